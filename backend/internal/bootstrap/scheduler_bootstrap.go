@@ -31,6 +31,10 @@ func registerScheduledJobs(ctx context.Context, db *gorm.DB, svc *services, http
 	if err != nil {
 		return fmt.Errorf("failed to register API key expiration jobs in scheduler: %w", err)
 	}
+	err = scheduler.RegisterKeyRotationJob(ctx, db, svc.jwtService, svc.auditLogService, svc.appConfigService)
+	if err != nil {
+		return fmt.Errorf("failed to register key rotation job in scheduler: %w", err)
+	}
 	err = scheduler.RegisterAnalyticsJob(ctx, svc.appConfigService, httpClient)
 	if err != nil {
 		return fmt.Errorf("failed to register analytics job in scheduler: %w", err)
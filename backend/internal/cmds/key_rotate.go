@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/spf13/cobra"
@@ -29,6 +30,7 @@ func init() {
 	keyRotateCmd := &cobra.Command{
 		Use:   "key-rotate",
 		Short: "Generates a new token signing key and replaces the current one",
+		Long:  "Generates a new token signing key and replaces the current one.\nThe previous key is kept in the keyring and published in the JWKS until all tokens it signed have expired.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			db, err := bootstrap.NewDatabase()
 			if err != nil {
@@ -71,7 +73,7 @@ func keyRotate(ctx context.Context, flags keyRotateFlags, db *gorm.DB, envConfig
 	}
 
 	if !flags.Yes {
-		fmt.Println("WARNING: Rotating the private key will invalidate all existing tokens. Both pocket-id and all client applications will likely need to be restarted.")
+		fmt.Println("Rotating the private key will make pocket-id sign new tokens with a new key. The previous key remains valid for verifying existing tokens until they expire.")
 		ok, err := utils.PromptForConfirmation("Confirm")
 		if err != nil {
 			return err
@@ -100,14 +102,41 @@ func keyRotate(ctx context.Context, flags keyRotateFlags, db *gorm.DB, envConfig
 		return fmt.Errorf("failed to generate key: %w", err)
 	}
 
-	// Save the key
-	err = keyProvider.SaveKey(key)
+	// Keep the previous key until all tokens it signed have expired
+	retainFor, err := service.MaxTokenLifetime(ctx, db, appConfigService.GetDbConfig())
+	if err != nil {
+		return fmt.Errorf("failed to compute token lifetime: %w", err)
+	}
+
+	// Save the key, moving the previous one to the keyring
+	err = jwkutils.RotateKey(keyProvider, key, retainFor)
 	if err != nil {
 		return fmt.Errorf("failed to store new key: %w", err)
 	}
 
+	// Replace the additional keys used for clients that require other algorithms as well
+	keyRing, err := keyProvider.LoadKeyRing()
+	if err != nil {
+		return fmt.Errorf("failed to load keyring: %w", err)
+	}
+	now := time.Now()
+	added, err := jwkutils.RotateAdditionalKeys(keyRing, now)
+	if err != nil {
+		return fmt.Errorf("failed to rotate additional keys: %w", err)
+	}
+	if len(added) > 0 {
+		_, err = jwkutils.RetireReplacedAdditionalKeys(keyRing, now, retainFor)
+		if err != nil {
+			return fmt.Errorf("failed to retire additional keys: %w", err)
+		}
+		err = keyProvider.SaveKeyRing(keyRing)
+		if err != nil {
+			return fmt.Errorf("failed to save keyring: %w", err)
+		}
+	}
+
 	fmt.Println("Key rotated successfully")
-	fmt.Println("Note: if pocket-id is running, you will need to restart it for the new key to be loaded")
+	fmt.Println("Note: if pocket-id is running, it will load the new key within 5 minutes")
 
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -214,3 +215,37 @@ func TestKeyRotateMultipleAlgorithms(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyRotateKeepsPreviousKey(t *testing.T) {
+	envConfig := &common.EnvConfigSchema{
+		KeysStorage: "file",
+		KeysPath:    t.TempDir(),
+	}
+	db := testingutils.NewDatabaseForTest(t)
+
+	appConfigService, err := service.NewAppConfigService(t.Context(), db)
+	require.NoError(t, err)
+	keyProvider, err := jwkutils.GetKeyProvider(db, envConfig, appConfigService.GetDbConfig().InstanceID.Value)
+	require.NoError(t, err)
+
+	flags := keyRotateFlags{Alg: "ES256", Yes: true}
+	err = keyRotate(t.Context(), flags, db, envConfig)
+	require.NoError(t, err)
+	firstKey, err := keyProvider.LoadKey()
+	require.NoError(t, err)
+
+	err = keyRotate(t.Context(), flags, db, envConfig)
+	require.NoError(t, err)
+
+	// The first key is moved to the keyring until all tokens it may have signed have expired
+	keyRing, err := keyProvider.LoadKeyRing()
+	require.NoError(t, err)
+	require.Equal(t, 1, keyRing.Len())
+
+	retiredKey, _ := keyRing.Key(0)
+	retiredKeyID, _ := retiredKey.KeyID()
+	firstKeyID, _ := firstKey.KeyID()
+	assert.Equal(t, firstKeyID, retiredKeyID)
+	assert.Equal(t, jwkutils.KeyStatusRetired, jwkutils.GetKeyStatus(retiredKey))
+	assert.WithinDuration(t, time.Now().Add(service.RefreshTokenDuration), jwkutils.GetKeyExpiration(retiredKey), time.Minute)
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -8,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/caarlos0/env/v11"
@@ -32,6 +34,9 @@ const (
 	AppUrl                  string     = "http://localhost:1411"
 )
 
+// SigningKeyAlgs contains the algorithms of the keys that Pocket ID can generate to sign tokens
+var SigningKeyAlgs = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
 type EnvConfigSchema struct {
 	AppEnv             string     `env:"APP_ENV" options:"toLower"`
 	LogLevel           string     `env:"LOG_LEVEL" options:"toLower"`
@@ -42,6 +47,8 @@ type EnvConfigSchema struct {
 	KeysPath           string     `env:"KEYS_PATH"`
 	KeysStorage        string     `env:"KEYS_STORAGE"`
 	EncryptionKey      []byte     `env:"ENCRYPTION_KEY" options:"file"`
+	KeyRotationDays    int        `env:"KEY_ROTATION_DAYS"`
+	KeyRotationAlg     string     `env:"KEY_ROTATION_ALG"`
 	Port               string     `env:"PORT"`
 	Host               string     `env:"HOST" options:"toLower"`
 	UnixSocket         string     `env:"UNIX_SOCKET"`
@@ -55,6 +62,8 @@ type EnvConfigSchema struct {
 	TracingEnabled     bool       `env:"TRACING_ENABLED"`
 	LogJSON            bool       `env:"LOG_JSON"`
 	TrustProxy         bool       `env:"TRUST_PROXY"`
+	ClientCertHeader   string     `env:"CLIENT_CERT_HEADER"`
+	ClientCACerts      string     `env:"CLIENT_CA_CERTS" options:"file"`
 	AnalyticsDisabled  bool       `env:"ANALYTICS_DISABLED"`
 	AllowDowngrade     bool       `env:"ALLOW_DOWNGRADE"`
 	InternalAppURL     string     `env:"INTERNAL_APP_URL"`
@@ -80,6 +89,8 @@ func defaultConfig() EnvConfigSchema {
 		KeysPath:           "data/keys",
 		KeysStorage:        "", // "database" or "file"
 		EncryptionKey:      nil,
+		KeyRotationDays:    0,  // Automatic key rotation is disabled by default
+		KeyRotationAlg:     "", // Defaults to the algorithm of the current key
 		AppURL:             AppUrl,
 		Port:               "1411",
 		Host:               "0.0.0.0",
@@ -93,6 +104,8 @@ func defaultConfig() EnvConfigSchema {
 		MetricsEnabled:     false,
 		TracingEnabled:     false,
 		TrustProxy:         false,
+		ClientCertHeader:   "",
+		ClientCACerts:      "",
 		AnalyticsDisabled:  false,
 		AllowDowngrade:     false,
 		InternalAppURL:     "",
@@ -181,6 +194,17 @@ func validateEnvConfig(config *EnvConfigSchema) error {
 		return fmt.Errorf("invalid value for KEYS_STORAGE: %s", config.KeysStorage)
 	}
 
+	if config.KeyRotationDays < 0 {
+		return errors.New("KEY_ROTATION_DAYS must not be negative")
+	}
+	if config.KeyRotationAlg != "" && !slices.Contains(SigningKeyAlgs, config.KeyRotationAlg) {
+		return fmt.Errorf("invalid value for KEY_ROTATION_ALG: %s", config.KeyRotationAlg)
+	}
+
+	if config.ClientCACerts != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(config.ClientCACerts)) {
+		return errors.New("CLIENT_CA_CERTS must contain PEM-encoded certificates")
+	}
+
 	// Validate LOCAL_IPV6_RANGES
 	ranges := strings.Split(config.LocalIPv6Ranges, ",")
 	for _, rangeStr := range ranges {
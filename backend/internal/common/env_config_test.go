@@ -167,6 +167,33 @@ func TestParseEnvConfig(t *testing.T) {
 		assert.ErrorContains(t, err, "invalid value for KEYS_STORAGE")
 	})
 
+	t.Run("should parse key rotation settings", func(t *testing.T) {
+		EnvConfig = defaultConfig()
+		t.Setenv("DB_PROVIDER", "sqlite")
+		t.Setenv("DB_CONNECTION_STRING", "file:test.db")
+		t.Setenv("APP_URL", "http://localhost:3000")
+		t.Setenv("KEY_ROTATION_DAYS", "90")
+		t.Setenv("KEY_ROTATION_ALG", "ES256")
+
+		err := parseEnvConfig()
+		require.NoError(t, err)
+		assert.Equal(t, 90, EnvConfig.KeyRotationDays)
+		assert.Equal(t, "ES256", EnvConfig.KeyRotationAlg)
+	})
+
+	t.Run("should fail with invalid KEY_ROTATION_ALG value", func(t *testing.T) {
+		EnvConfig = defaultConfig()
+		t.Setenv("DB_PROVIDER", "sqlite")
+		t.Setenv("DB_CONNECTION_STRING", "file:test.db")
+		t.Setenv("APP_URL", "http://localhost:3000")
+		t.Setenv("KEY_ROTATION_DAYS", "90")
+		t.Setenv("KEY_ROTATION_ALG", "HS256")
+
+		err := parseEnvConfig()
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "invalid value for KEY_ROTATION_ALG")
+	})
+
 	t.Run("should parse boolean environment variables correctly", func(t *testing.T) {
 		EnvConfig = defaultConfig()
 		t.Setenv("DB_PROVIDER", "sqlite")
@@ -70,6 +70,11 @@ type OidcClientAssertionInvalidError struct{}
 func (e *OidcClientAssertionInvalidError) Error() string       { return "invalid client assertion" }
 func (e *OidcClientAssertionInvalidError) HttpStatusCode() int { return 400 }
 
+type OidcClientCertificateInvalidError struct{}
+
+func (e *OidcClientCertificateInvalidError) Error() string       { return "invalid client certificate" }
+func (e *OidcClientCertificateInvalidError) HttpStatusCode() int { return 400 }
+
 type OidcInvalidAuthorizationCodeError struct{}
 
 func (e *OidcInvalidAuthorizationCodeError) Error() string       { return "invalid authorization code" }
@@ -284,6 +289,159 @@ func (e *OidcMissingRefreshTokenError) HttpStatusCode() int {
 	return http.StatusBadRequest
 }
 
+type OidcUnsupportedTokenTypeError struct{}
+
+func (e *OidcUnsupportedTokenTypeError) Error() string {
+	return "revocation of this token type is not supported"
+}
+func (e *OidcUnsupportedTokenTypeError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcInvalidRequestURIError struct{}
+
+func (e *OidcInvalidRequestURIError) Error() string {
+	return "request URI is invalid or expired"
+}
+func (e *OidcInvalidRequestURIError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcUnsupportedResponseModeError struct{}
+
+func (e *OidcUnsupportedResponseModeError) Error() string {
+	return "response mode is not supported"
+}
+func (e *OidcUnsupportedResponseModeError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcPushedAuthorizationRequestRequiredError struct{}
+
+func (e *OidcPushedAuthorizationRequestRequiredError) Error() string {
+	return "this client requires pushed authorization requests"
+}
+func (e *OidcPushedAuthorizationRequestRequiredError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcInvalidRequestObjectError struct{}
+
+func (e *OidcInvalidRequestObjectError) Error() string {
+	return "request object is invalid"
+}
+func (e *OidcInvalidRequestObjectError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcRequestObjectRequiredError struct{}
+
+func (e *OidcRequestObjectRequiredError) Error() string {
+	return "this client requires signed request objects"
+}
+func (e *OidcRequestObjectRequiredError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcInvalidInitialAccessTokenError struct{}
+
+func (e *OidcInvalidInitialAccessTokenError) Error() string {
+	return "initial access token is invalid or expired"
+}
+func (e *OidcInvalidInitialAccessTokenError) HttpStatusCode() int {
+	return http.StatusUnauthorized
+}
+
+type OidcInvalidRegistrationAccessTokenError struct{}
+
+func (e *OidcInvalidRegistrationAccessTokenError) Error() string {
+	return "registration access token is invalid"
+}
+func (e *OidcInvalidRegistrationAccessTokenError) HttpStatusCode() int {
+	return http.StatusUnauthorized
+}
+
+type OidcInvalidDpopProofError struct{}
+
+func (e *OidcInvalidDpopProofError) Error() string {
+	return "DPoP proof is invalid"
+}
+func (e *OidcInvalidDpopProofError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcDpopRequiredError struct{}
+
+func (e *OidcDpopRequiredError) Error() string {
+	return "this client requires DPoP-bound tokens"
+}
+func (e *OidcDpopRequiredError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcAcrNotSatisfiedError struct{}
+
+func (e *OidcAcrNotSatisfiedError) Error() string {
+	return "this application requires signing in with a stronger authentication method"
+}
+func (e *OidcAcrNotSatisfiedError) HttpStatusCode() int {
+	return http.StatusForbidden
+}
+
+type OidcInvalidSubjectTokenError struct{}
+
+func (e *OidcInvalidSubjectTokenError) Error() string {
+	return "invalid subject token"
+}
+func (e *OidcInvalidSubjectTokenError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcInvalidActorTokenError struct{}
+
+func (e *OidcInvalidActorTokenError) Error() string {
+	return "invalid actor token"
+}
+func (e *OidcInvalidActorTokenError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcUnsupportedRequestedTokenTypeError struct{}
+
+func (e *OidcUnsupportedRequestedTokenTypeError) Error() string {
+	return "the requested token type is not supported"
+}
+func (e *OidcUnsupportedRequestedTokenTypeError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcTokenExchangeAudienceNotAllowedError struct{}
+
+func (e *OidcTokenExchangeAudienceNotAllowedError) Error() string {
+	return "the client is not allowed to exchange tokens for this audience"
+}
+func (e *OidcTokenExchangeAudienceNotAllowedError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcInvalidAssertionError struct{}
+
+func (e *OidcInvalidAssertionError) Error() string {
+	return "invalid assertion"
+}
+func (e *OidcInvalidAssertionError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcInvalidScopeError struct{}
+
+func (e *OidcInvalidScopeError) Error() string {
+	return "the requested scope exceeds the scope of the subject token"
+}
+func (e *OidcInvalidScopeError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
 type OidcMissingAuthorizationCodeError struct{}
 
 func (e *OidcMissingAuthorizationCodeError) Error() string {
@@ -350,6 +508,51 @@ func (e *OidcAuthorizationPendingError) HttpStatusCode() int {
 	return http.StatusBadRequest
 }
 
+type OidcInvalidAuthReqIDError struct{}
+
+func (e *OidcInvalidAuthReqIDError) Error() string {
+	return "invalid auth_req_id"
+}
+func (e *OidcInvalidAuthReqIDError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcAuthReqIDExpiredError struct{}
+
+func (e *OidcAuthReqIDExpiredError) Error() string {
+	return "authentication request has expired"
+}
+func (e *OidcAuthReqIDExpiredError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcBackchannelAuthenticationDeniedError struct{}
+
+func (e *OidcBackchannelAuthenticationDeniedError) Error() string {
+	return "the user denied the authentication request"
+}
+func (e *OidcBackchannelAuthenticationDeniedError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcUnknownUserError struct{}
+
+func (e *OidcUnknownUserError) Error() string {
+	return "the user identified by the login hint is unknown"
+}
+func (e *OidcUnknownUserError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type OidcBackchannelAuthenticationNotAllowedError struct{}
+
+func (e *OidcBackchannelAuthenticationNotAllowedError) Error() string {
+	return "the client is not allowed to use backchannel authentication"
+}
+func (e *OidcBackchannelAuthenticationNotAllowedError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
 type ReauthenticationRequiredError struct{}
 
 func (e *ReauthenticationRequiredError) Error() string {
@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"crypto/x509"
 	"errors"
+	"html/template"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -25,15 +28,18 @@ import (
 func NewOidcController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, fileSizeLimitMiddleware *middleware.FileSizeLimitMiddleware, oidcService *service.OidcService, jwtService *service.JwtService) {
 	oc := &OidcController{oidcService: oidcService, jwtService: jwtService}
 
-	group.POST("/oidc/authorize", authMiddleware.WithAdminNotRequired().Add(), oc.authorizeHandler)
+	group.POST("/oidc/authorize", authMiddleware.WithAdminNotRequired().WithSuccessOptional().Add(), oc.authorizeHandler)
 	group.POST("/oidc/authorization-required", authMiddleware.WithAdminNotRequired().Add(), oc.authorizationConfirmationRequiredHandler)
+	group.POST("/oidc/authorization-response", oc.authorizationResponseHandler)
 
+	group.POST("/oidc/par", oc.pushedAuthorizationRequestHandler)
 	group.POST("/oidc/token", oc.createTokensHandler)
 	group.GET("/oidc/userinfo", oc.userInfoHandler)
 	group.POST("/oidc/userinfo", oc.userInfoHandler)
 	group.POST("/oidc/end-session", authMiddleware.WithAdminNotRequired().WithSuccessOptional().Add(), oc.EndSessionHandler)
 	group.GET("/oidc/end-session", authMiddleware.WithAdminNotRequired().WithSuccessOptional().Add(), oc.EndSessionHandler)
 	group.POST("/oidc/introspect", oc.introspectTokenHandler)
+	group.POST("/oidc/revoke", oc.revokeTokenHandler)
 
 	group.GET("/oidc/clients", authMiddleware.Add(), oc.listClientsHandler)
 	group.POST("/oidc/clients", authMiddleware.Add(), oc.createClientHandler)
@@ -45,6 +51,15 @@ func NewOidcController(group *gin.RouterGroup, authMiddleware *middleware.AuthMi
 	group.PUT("/oidc/clients/:id/allowed-user-groups", authMiddleware.Add(), oc.updateAllowedUserGroupsHandler)
 	group.POST("/oidc/clients/:id/secret", authMiddleware.Add(), oc.createClientSecretHandler)
 
+	group.POST("/oidc/register", oc.registerClientHandler)
+	group.GET("/oidc/register/:id", oc.getRegisteredClientHandler)
+	group.PUT("/oidc/register/:id", oc.updateRegisteredClientHandler)
+	group.DELETE("/oidc/register/:id", oc.deleteRegisteredClientHandler)
+
+	group.POST("/oidc/initial-access-tokens", authMiddleware.Add(), oc.createInitialAccessTokenHandler)
+	group.GET("/oidc/initial-access-tokens", authMiddleware.Add(), oc.listInitialAccessTokensHandler)
+	group.DELETE("/oidc/initial-access-tokens/:id", authMiddleware.Add(), oc.deleteInitialAccessTokenHandler)
+
 	group.GET("/oidc/clients/:id/logo", oc.getClientLogoHandler)
 	group.DELETE("/oidc/clients/:id/logo", oc.deleteClientLogoHandler)
 	group.POST("/oidc/clients/:id/logo", authMiddleware.Add(), fileSizeLimitMiddleware.Add(2<<20), oc.updateClientLogoHandler)
@@ -55,6 +70,11 @@ func NewOidcController(group *gin.RouterGroup, authMiddleware *middleware.AuthMi
 	group.POST("/oidc/device/verify", authMiddleware.WithAdminNotRequired().Add(), oc.verifyDeviceCodeHandler)
 	group.GET("/oidc/device/info", authMiddleware.WithAdminNotRequired().Add(), oc.getDeviceCodeInfoHandler)
 
+	group.POST("/oidc/bc-authorize", oc.backchannelAuthenticationHandler)
+	group.GET("/oidc/backchannel-authentications", authMiddleware.WithAdminNotRequired().Add(), oc.listPendingBackchannelAuthenticationsHandler)
+	group.POST("/oidc/backchannel-authentications/:id/approve", authMiddleware.WithAdminNotRequired().Add(), oc.approveBackchannelAuthenticationHandler)
+	group.POST("/oidc/backchannel-authentications/:id/deny", authMiddleware.WithAdminNotRequired().Add(), oc.denyBackchannelAuthenticationHandler)
+
 	group.GET("/oidc/users/me/authorized-clients", authMiddleware.WithAdminNotRequired().Add(), oc.listOwnAuthorizedClientsHandler)
 	group.GET("/oidc/users/:id/authorized-clients", authMiddleware.Add(), oc.listAuthorizedClientsHandler)
 
@@ -64,6 +84,8 @@ func NewOidcController(group *gin.RouterGroup, authMiddleware *middleware.AuthMi
 
 }
 
+const defaultInitialAccessTokenDuration = 24 * time.Hour
+
 type OidcController struct {
 	oidcService *service.OidcService
 	jwtService  *service.JwtService
@@ -76,7 +98,7 @@ type OidcController struct {
 // @Accept json
 // @Produce json
 // @Param request body dto.AuthorizeOidcClientRequestDto true "Authorization request parameters"
-// @Success 200 {object} dto.AuthorizeOidcClientResponseDto "Authorization code and callback URL"
+// @Success 200 {object} dto.AuthorizeOidcClientResponseDto "Authorization code and callback URL, or the error to return to the callback URL with prompt=none"
 // @Router /api/oidc/authorize [post]
 func (oc *OidcController) authorizeHandler(c *gin.Context) {
 	var input dto.AuthorizeOidcClientRequestDto
@@ -85,19 +107,107 @@ func (oc *OidcController) authorizeHandler(c *gin.Context) {
 		return
 	}
 
-	code, callbackURL, err := oc.oidcService.Authorize(c.Request.Context(), input, c.GetString("userID"), c.ClientIP(), c.Request.UserAgent())
+	// The user might not be signed in, which is handled by the service depending on the "prompt" parameter
+	input.AuthTime = c.GetTime("authTime")
+	input.AuthenticationMethods = c.GetStringSlice("authMethods")
+
+	response, err := oc.oidcService.Authorize(c.Request.Context(), input, c.GetString("userID"), c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	response := dto.AuthorizeOidcClientResponseDto{
-		Code:        code,
-		CallbackURL: callbackURL,
-		Issuer:      common.EnvConfig.AppURL,
+	c.JSON(http.StatusOK, response)
+}
+
+// authorizationResponseHandler godoc
+// @Summary Return an authorization response with the form_post response mode
+// @Description Render a page that posts the authorization response to the callback URL of the client, as described in OAuth 2.0 Form Post Response Mode
+// @Tags OIDC
+// @Accept application/x-www-form-urlencoded
+// @Produce html
+// @Param client_id formData string true "Client ID"
+// @Param redirect_uri formData string true "Callback URL"
+// @Param code formData string false "Authorization code"
+// @Param state formData string false "State"
+// @Param error formData string false "Error"
+// @Param iss formData string false "Issuer"
+// @Param response formData string false "Signed authorization response, with the JWT response modes"
+// @Success 200 {string} string "HTML page that posts the authorization response"
+// @Router /api/oidc/authorization-response [post]
+func (oc *OidcController) authorizationResponseHandler(c *gin.Context) {
+	var input dto.OidcAuthorizationResponseDto
+	if err := c.ShouldBind(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err := oc.oidcService.ValidateCallbackURL(c.Request.Context(), input.ClientID, input.CallbackURL)
+	if err != nil {
+		_ = c.Error(err)
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	// With the JWT response modes, all parameters are contained in the signed response
+	params := map[string]string{}
+	if input.Response != "" {
+		params["response"] = input.Response
+	} else {
+		for name, value := range map[string]string{"code": input.Code, "state": input.State, "error": input.Error, "iss": input.Issuer} {
+			if value != "" {
+				params[name] = value
+			}
+		}
+	}
+
+	oc.renderFormPostResponse(c, input.CallbackURL, params)
+}
+
+// pushedAuthorizationRequestHandler godoc
+// @Summary Push an authorization request
+// @Description Store the parameters of an authorization request and return a request URI to use in the authorization endpoint, as described in RFC 9126
+// @Tags OIDC
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string false "Client ID (if not using Basic Auth)"
+// @Param client_secret formData string false "Client secret (if not using Basic Auth or client assertions)"
+// @Param client_assertion formData string false "Client assertion (when using client assertions)"
+// @Param client_assertion_type formData string false "Client assertion type (when using client assertions)"
+// @Param scope formData string true "Requested scopes"
+// @Param redirect_uri formData string false "Callback URL"
+// @Param nonce formData string false "Nonce"
+// @Param state formData string false "State"
+// @Param code_challenge formData string false "PKCE code challenge"
+// @Param code_challenge_method formData string false "PKCE code challenge method"
+// @Param request formData string false "Signed request object containing the authorization request parameters"
+// @Success 201 {object} dto.OidcPushedAuthorizationResponseDto "Request URI and its lifetime"
+// @Router /api/oidc/par [post]
+func (oc *OidcController) pushedAuthorizationRequestHandler(c *gin.Context) {
+	var input dto.OidcPushedAuthorizationRequestDto
+	if err := c.ShouldBind(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// Client id and secret can also be passed over the Authorization header
+	if input.ClientID == "" && input.ClientSecret == "" {
+		input.ClientID, input.ClientSecret, _ = c.Request.BasicAuth()
+	}
+
+	var err error
+	input.ClientCertificate, input.ClientCertificateChainVerified, err = clientCertificate(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response, err := oc.oidcService.PushAuthorizationRequest(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // authorizationConfirmationRequiredHandler godoc
@@ -107,7 +217,7 @@ func (oc *OidcController) authorizeHandler(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body dto.AuthorizationRequiredDto true "Authorization check parameters"
-// @Success 200 {object} object "{ \"authorizationRequired\": true/false }"
+// @Success 200 {object} dto.AuthorizationRequiredResponseDto
 // @Router /api/oidc/authorization-required [post]
 func (oc *OidcController) authorizationConfirmationRequiredHandler(c *gin.Context) {
 	var input dto.AuthorizationRequiredDto
@@ -116,13 +226,13 @@ func (oc *OidcController) authorizationConfirmationRequiredHandler(c *gin.Contex
 		return
 	}
 
-	hasAuthorizedClient, err := oc.oidcService.HasAuthorizedClient(c.Request.Context(), input.ClientID, c.GetString("userID"), input.Scope)
+	response, err := oc.oidcService.IsAuthorizationRequired(c.Request.Context(), input, c.GetString("userID"))
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"authorizationRequired": !hasAuthorizedClient})
+	c.JSON(http.StatusOK, response)
 }
 
 // createTokensHandler godoc
@@ -159,11 +269,28 @@ func (oc *OidcController) createTokensHandler(c *gin.Context) {
 		return
 	}
 
+	// Validate that auth_req_id is provided for the CIBA grant type
+	if input.GrantType == service.GrantTypeCIBA && input.AuthReqID == "" {
+		_ = c.Error(&common.ValidationError{Message: "auth_req_id is required"})
+		return
+	}
+
 	// Client id and secret can also be passed over the Authorization header
 	if input.ClientID == "" && input.ClientSecret == "" {
 		input.ClientID, input.ClientSecret, _ = c.Request.BasicAuth()
 	}
 
+	input.DpopProof = c.GetHeader("DPoP")
+	input.IPAddress = c.ClientIP()
+	input.UserAgent = c.Request.UserAgent()
+
+	var err error
+	input.ClientCertificate, input.ClientCertificateChainVerified, err = clientCertificate(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	tokens, err := oc.oidcService.CreateTokens(c.Request.Context(), input)
 
 	switch {
@@ -177,17 +304,28 @@ func (oc *OidcController) createTokensHandler(c *gin.Context) {
 			"error": "slow_down",
 		})
 		return
+	case errors.Is(err, &common.OidcAuthReqIDExpiredError{}):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "expired_token",
+		})
+		return
+	case errors.Is(err, &common.OidcBackchannelAuthenticationDeniedError{}):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "access_denied",
+		})
+		return
 	case err != nil:
 		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, dto.OidcTokenResponseDto{
-		AccessToken:  tokens.AccessToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
-		IdToken:      tokens.IdToken,      // May be empty
-		RefreshToken: tokens.RefreshToken, // May be empty
+		AccessToken:     tokens.AccessToken,
+		TokenType:       tokens.TokenType,
+		ExpiresIn:       int(tokens.ExpiresIn.Seconds()),
+		IdToken:         tokens.IdToken,         // May be empty
+		RefreshToken:    tokens.RefreshToken,    // May be empty
+		IssuedTokenType: tokens.IssuedTokenType, // May be empty
 	})
 }
 
@@ -197,11 +335,12 @@ func (oc *OidcController) createTokensHandler(c *gin.Context) {
 // @Tags OIDC
 // @Accept json
 // @Produce json
+// @Produce application/jwt
 // @Success 200 {object} object "User claims based on requested scopes"
 // @Security OAuth2AccessToken
 // @Router /api/oidc/userinfo [get]
 func (oc *OidcController) userInfoHandler(c *gin.Context) {
-	_, authToken, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	scheme, authToken, ok := strings.Cut(c.GetHeader("Authorization"), " ")
 	if !ok || authToken == "" {
 		_ = c.Error(&common.MissingAccessToken{})
 		return
@@ -212,7 +351,25 @@ func (oc *OidcController) userInfoHandler(c *gin.Context) {
 		_ = c.Error(err)
 		return
 	}
-	userID, ok := token.Subject()
+
+	err = oc.oidcService.VerifyAccessTokenDpopBinding(c.Request.Context(), token, scheme, authToken, c.GetHeader("DPoP"), c.Request.Method, c.Request.URL.Path)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	cert, _, err := clientCertificate(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	err = service.VerifyAccessTokenCertificateBinding(token, cert)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	subject, ok := token.Subject()
 	if !ok {
 		_ = c.Error(&common.TokenInvalidError{})
 		return
@@ -222,12 +379,18 @@ func (oc *OidcController) userInfoHandler(c *gin.Context) {
 		_ = c.Error(&common.TokenInvalidError{})
 		return
 	}
-	claims, err := oc.oidcService.GetUserClaimsForClient(c.Request.Context(), userID, clientID[0])
+	claims, userInfoToken, err := oc.oidcService.GetUserInfo(c.Request.Context(), subject, clientID[0])
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
+	// Clients that registered signing or encryption of userinfo responses receive a JWT
+	if userInfoToken != "" {
+		c.Data(http.StatusOK, "application/jwt", []byte(userInfoToken))
+		return
+	}
+
 	c.JSON(http.StatusOK, claims)
 }
 
@@ -270,6 +433,11 @@ func (oc *OidcController) EndSessionHandler(c *gin.Context) {
 	// The validation was successful, so we can log out and redirect the user to the callback URL without confirmation
 	cookie.AddAccessTokenCookie(c, 0, "")
 
+	err = oc.oidcService.SendBackchannelLogout(c.Request.Context(), c.GetString("userID"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Failed to send back-channel logout", "error", err)
+	}
+
 	logoutCallbackURL, _ := url.Parse(callbackURL)
 	if input.State != "" {
 		q := logoutCallbackURL.Query()
@@ -277,9 +445,121 @@ func (oc *OidcController) EndSessionHandler(c *gin.Context) {
 		logoutCallbackURL.RawQuery = q.Encode()
 	}
 
+	// Clients that support front-channel logout need to be loaded in iframes before redirecting the user
+	frontchannelLogoutURLs, err := oc.oidcService.GetFrontchannelLogoutURLs(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Failed to get front-channel logout URLs", "error", err)
+	}
+	if len(frontchannelLogoutURLs) > 0 {
+		oc.renderFrontchannelLogout(c, frontchannelLogoutURLs, logoutCallbackURL.String())
+		return
+	}
+
 	c.Redirect(http.StatusFound, logoutCallbackURL.String())
 }
 
+// formPostResponseTemplate posts the authorization response to the callback URL with an auto-submitted form
+var formPostResponseTemplate = template.Must(template.New("form-post-response").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Signing in</title>
+</head>
+<body>
+<form method="post" action="{{.CallbackURL}}">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<noscript><button type="submit">Continue</button></noscript>
+</form>
+<script nonce="{{.Nonce}}">document.forms[0].submit();</script>
+</body>
+</html>
+`))
+
+func (oc *OidcController) renderFormPostResponse(c *gin.Context, callbackURL string, params map[string]string) {
+	// Allow the form to be submitted to the origin of the callback URL only
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		_ = c.Error(&common.OidcInvalidCallbackURLError{})
+		return
+	}
+	formAction := u.Scheme + "://" + u.Host
+	if u.Host == "" {
+		// Callback URLs of native apps may use a custom scheme without a host
+		formAction = u.Scheme + ":"
+	}
+	csp := c.Writer.Header().Get("Content-Security-Policy")
+	c.Writer.Header().Set("Content-Security-Policy", strings.Replace(csp, "form-action 'self'", "form-action "+formAction, 1))
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	err = formPostResponseTemplate.Execute(c.Writer, map[string]any{
+		"CallbackURL": callbackURL,
+		"Params":      params,
+		"Nonce":       middleware.GetCSPNonce(c),
+	})
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to render form_post response page", "error", err)
+	}
+}
+
+// frontchannelLogoutTemplate loads the front-channel logout URLs in hidden iframes and redirects
+// to the post-logout redirect URL once all of them have loaded, or after a timeout
+var frontchannelLogoutTemplate = template.Must(template.New("frontchannel-logout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Signing out</title>
+</head>
+<body>
+<p>Signing out&hellip; <a href="{{.RedirectURL}}">Continue</a></p>
+<script nonce="{{.Nonce}}">
+(function () {
+	var logoutURLs = {{.LogoutURLs}};
+	var redirectURL = {{.RedirectURL}};
+	var pending = logoutURLs.length;
+	var redirect = function () { window.location.replace(redirectURL); };
+	logoutURLs.forEach(function (logoutURL) {
+		var iframe = document.createElement("iframe");
+		iframe.style.display = "none";
+		iframe.addEventListener("load", function () {
+			pending--;
+			if (pending === 0) redirect();
+		});
+		iframe.src = logoutURL;
+		document.body.appendChild(iframe);
+	});
+	setTimeout(redirect, 5000);
+})();
+</script>
+</body>
+</html>
+`))
+
+func (oc *OidcController) renderFrontchannelLogout(c *gin.Context, logoutURLs []string, redirectURL string) {
+	// Allow the front-channel logout URLs to be loaded in frames
+	frameSources := make([]string, 0, len(logoutURLs))
+	for _, logoutURL := range logoutURLs {
+		u, err := url.Parse(logoutURL)
+		if err != nil {
+			continue
+		}
+		frameSources = append(frameSources, u.Scheme+"://"+u.Host)
+	}
+	csp := c.Writer.Header().Get("Content-Security-Policy")
+	c.Writer.Header().Set("Content-Security-Policy", csp+"; frame-src "+strings.Join(frameSources, " "))
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	err := frontchannelLogoutTemplate.Execute(c.Writer, map[string]any{
+		"LogoutURLs":  logoutURLs,
+		"RedirectURL": redirectURL,
+		"Nonce":       middleware.GetCSPNonce(c),
+	})
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to render front-channel logout page", "error", err)
+	}
+}
+
 // EndSessionHandler godoc (POST method)
 // @Summary End OIDC session (POST method)
 // @Description End user session and handle OIDC logout using POST
@@ -329,6 +609,13 @@ func (oc *OidcController) introspectTokenHandler(c *gin.Context) {
 		}
 	}
 
+	var err error
+	creds.ClientCertificate, creds.ClientCertificateChainVerified, err = clientCertificate(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	response, err := oc.oidcService.IntrospectToken(c.Request.Context(), creds, input.Token)
 	if err != nil {
 		_ = c.Error(err)
@@ -338,6 +625,54 @@ func (oc *OidcController) introspectTokenHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// revokeTokenHandler godoc
+// @Summary Revoke OIDC tokens
+// @Description Revoke a refresh token that was issued to the client, as described in RFC 7009
+// @Tags OIDC
+// @Accept application/x-www-form-urlencoded
+// @Param token formData string true "The token to be revoked"
+// @Param token_type_hint formData string false "Hint about the type of the token"
+// @Param client_id formData string false "Client ID (if not using Basic Auth)"
+// @Param client_secret formData string false "Client secret (if not using Basic Auth or client assertions)"
+// @Param client_assertion formData string false "Client assertion (when using client assertions)"
+// @Param client_assertion_type formData string false "Client assertion type (when using client assertions)"
+// @Success 200 "OK"
+// @Router /api/oidc/revoke [post]
+func (oc *OidcController) revokeTokenHandler(c *gin.Context) {
+	var input dto.OidcRevokeTokenDto
+	if err := c.ShouldBind(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// Client id and secret can also be passed over the Authorization header
+	if input.ClientID == "" && input.ClientSecret == "" {
+		input.ClientID, input.ClientSecret, _ = c.Request.BasicAuth()
+	}
+
+	cert, chainVerified, err := clientCertificate(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// The token type hint is not needed, because the type of the token is stored in the token itself
+	err = oc.oidcService.RevokeToken(c.Request.Context(), service.ClientAuthCredentials{
+		ClientID:                       input.ClientID,
+		ClientSecret:                   input.ClientSecret,
+		ClientAssertion:                input.ClientAssertion,
+		ClientAssertionType:            input.ClientAssertionType,
+		ClientCertificate:              cert,
+		ClientCertificateChainVerified: chainVerified,
+	}, input.Token)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
 // getClientMetaDataHandler godoc
 // @Summary Get client metadata
 // @Description Get OIDC client metadata for discovery and configuration
@@ -535,6 +870,200 @@ func (oc *OidcController) createClientSecretHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"secret": secret})
 }
 
+// registerClientHandler godoc
+// @Summary Register a client
+// @Description Register a new OIDC client using dynamic client registration, as described in RFC 7591
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Param metadata body dto.OidcClientRegistrationDto true "Client metadata"
+// @Success 201 {object} dto.OidcClientRegistrationResponseDto "Registered client"
+// @Security BearerAuth
+// @Router /api/oidc/register [post]
+func (oc *OidcController) registerClientHandler(c *gin.Context) {
+	initialAccessToken, ok := utils.BearerAuth(c.Request)
+	if !ok {
+		_ = c.Error(&common.OidcInvalidInitialAccessTokenError{})
+		return
+	}
+
+	var input dto.OidcClientRegistrationDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response, err := oc.oidcService.RegisterClient(c.Request.Context(), initialAccessToken, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// getRegisteredClientHandler godoc
+// @Summary Get a registered client
+// @Description Read the metadata of a dynamically registered client, as described in RFC 7592
+// @Tags OIDC
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {object} dto.OidcClientRegistrationResponseDto "Registered client"
+// @Security BearerAuth
+// @Router /api/oidc/register/{id} [get]
+func (oc *OidcController) getRegisteredClientHandler(c *gin.Context) {
+	registrationAccessToken, _ := utils.BearerAuth(c.Request)
+
+	response, err := oc.oidcService.GetRegisteredClient(c.Request.Context(), c.Param("id"), registrationAccessToken)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// updateRegisteredClientHandler godoc
+// @Summary Update a registered client
+// @Description Replace the metadata of a dynamically registered client, as described in RFC 7592
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Param id path string true "Client ID"
+// @Param metadata body dto.OidcClientRegistrationDto true "Client metadata"
+// @Success 200 {object} dto.OidcClientRegistrationResponseDto "Registered client"
+// @Security BearerAuth
+// @Router /api/oidc/register/{id} [put]
+func (oc *OidcController) updateRegisteredClientHandler(c *gin.Context) {
+	registrationAccessToken, _ := utils.BearerAuth(c.Request)
+
+	var input dto.OidcClientRegistrationDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response, err := oc.oidcService.UpdateRegisteredClient(c.Request.Context(), c.Param("id"), registrationAccessToken, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// deleteRegisteredClientHandler godoc
+// @Summary Delete a registered client
+// @Description Delete a dynamically registered client, as described in RFC 7592
+// @Tags OIDC
+// @Param id path string true "Client ID"
+// @Success 204 "No Content"
+// @Security BearerAuth
+// @Router /api/oidc/register/{id} [delete]
+func (oc *OidcController) deleteRegisteredClientHandler(c *gin.Context) {
+	registrationAccessToken, _ := utils.BearerAuth(c.Request)
+
+	err := oc.oidcService.DeleteRegisteredClient(c.Request.Context(), c.Param("id"), registrationAccessToken)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// createInitialAccessTokenHandler godoc
+// @Summary Create initial access token
+// @Description Create a new initial access token that allows registering clients dynamically
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Param token body dto.OidcInitialAccessTokenCreateDto true "Initial access token information"
+// @Success 201 {object} dto.OidcInitialAccessTokenResponseDto "Created initial access token with token"
+// @Router /api/oidc/initial-access-tokens [post]
+func (oc *OidcController) createInitialAccessTokenHandler(c *gin.Context) {
+	var input dto.OidcInitialAccessTokenCreateDto
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ttl := input.TTL.Duration
+	if ttl <= 0 {
+		ttl = defaultInitialAccessTokenDuration
+	}
+
+	initialAccessToken, token, err := oc.oidcService.CreateInitialAccessToken(c.Request.Context(), ttl, input.UsageLimit, c.GetString("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var initialAccessTokenDto dto.OidcInitialAccessTokenDto
+	err = dto.MapStruct(initialAccessToken, &initialAccessTokenDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.OidcInitialAccessTokenResponseDto{
+		InitialAccessToken: initialAccessTokenDto,
+		Token:              token,
+	})
+}
+
+// listInitialAccessTokensHandler godoc
+// @Summary List initial access tokens
+// @Description Get a paginated list of initial access tokens
+// @Tags OIDC
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Success 200 {object} dto.Paginated[dto.OidcInitialAccessTokenDto]
+// @Router /api/oidc/initial-access-tokens [get]
+func (oc *OidcController) listInitialAccessTokensHandler(c *gin.Context) {
+	var sortedPaginationRequest utils.SortedPaginationRequest
+	if err := c.ShouldBindQuery(&sortedPaginationRequest); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tokens, pagination, err := oc.oidcService.ListInitialAccessTokens(c.Request.Context(), sortedPaginationRequest)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var tokensDto []dto.OidcInitialAccessTokenDto
+	if err := dto.MapStructList(tokens, &tokensDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Paginated[dto.OidcInitialAccessTokenDto]{
+		Data:       tokensDto,
+		Pagination: pagination,
+	})
+}
+
+// deleteInitialAccessTokenHandler godoc
+// @Summary Delete initial access token
+// @Description Delete an initial access token by ID
+// @Tags OIDC
+// @Param id path string true "Token ID"
+// @Success 204 "No Content"
+// @Router /api/oidc/initial-access-tokens/{id} [delete]
+func (oc *OidcController) deleteInitialAccessTokenHandler(c *gin.Context) {
+	err := oc.oidcService.DeleteInitialAccessToken(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // getClientLogoHandler godoc
 // @Summary Get client logo
 // @Description Get the logo image for an OIDC client
@@ -644,6 +1173,13 @@ func (oc *OidcController) deviceAuthorizationHandler(c *gin.Context) {
 		input.ClientID, input.ClientSecret, _ = c.Request.BasicAuth()
 	}
 
+	var err error
+	input.ClientCertificate, input.ClientCertificateChainVerified, err = clientCertificate(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	response, err := oc.oidcService.CreateDeviceAuthorization(c.Request.Context(), input)
 	if err != nil {
 		_ = c.Error(err)
@@ -653,6 +1189,132 @@ func (oc *OidcController) deviceAuthorizationHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// backchannelAuthenticationHandler godoc
+// @Summary Start a backchannel authentication request
+// @Description Start a Client-Initiated Backchannel Authentication (CIBA) request, which the user approves from their Pocket ID session. The client then polls the token endpoint with the returned auth_req_id
+// @Tags OIDC
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string false "Client ID (if not using Basic Auth)"
+// @Param client_secret formData string false "Client secret (if not using Basic Auth or client assertions)"
+// @Param client_assertion formData string false "Client assertion (when using client assertions)"
+// @Param client_assertion_type formData string false "Client assertion type (when using client assertions)"
+// @Param scope formData string true "Requested scopes, which must contain openid"
+// @Param login_hint formData string true "Username or email address of the user"
+// @Param binding_message formData string false "Message shown to the user, to link the request to the client's device"
+// @Param requested_expiry formData int false "Requested lifetime of the request, in seconds"
+// @Success 200 {object} dto.OidcBackchannelAuthenticationResponseDto "Identifier of the request, its lifetime and the polling interval"
+// @Router /api/oidc/bc-authorize [post]
+func (oc *OidcController) backchannelAuthenticationHandler(c *gin.Context) {
+	var input dto.OidcBackchannelAuthenticationRequestDto
+	if err := c.ShouldBind(&input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// Client id and secret can also be passed over the Authorization header
+	if input.ClientID == "" && input.ClientSecret == "" {
+		input.ClientID, input.ClientSecret, _ = c.Request.BasicAuth()
+	}
+
+	var err error
+	input.ClientCertificate, input.ClientCertificateChainVerified, err = clientCertificate(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response, err := oc.oidcService.CreateBackchannelAuthentication(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// listPendingBackchannelAuthenticationsHandler godoc
+// @Summary List pending backchannel authentication requests
+// @Description Get the CIBA requests of the current user that are waiting to be approved or denied
+// @Tags OIDC
+// @Produce json
+// @Success 200 {array} dto.BackchannelAuthenticationRequestDto
+// @Router /api/oidc/backchannel-authentications [get]
+func (oc *OidcController) listPendingBackchannelAuthenticationsHandler(c *gin.Context) {
+	authRequests, err := oc.oidcService.ListPendingBackchannelAuthentications(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	authRequestsDto := make([]dto.BackchannelAuthenticationRequestDto, len(authRequests))
+	for i, authRequest := range authRequests {
+		authRequestsDto[i] = dto.BackchannelAuthenticationRequestDto{
+			ID: authRequest.ID,
+			Client: dto.OidcClientMetaDataDto{
+				ID:                       authRequest.Client.ID,
+				Name:                     authRequest.Client.Name,
+				HasLogo:                  authRequest.Client.HasLogo(),
+				LaunchURL:                authRequest.Client.LaunchURL,
+				RequiresReauthentication: authRequest.Client.RequiresReauthentication,
+			},
+			Scope:          authRequest.Scope,
+			BindingMessage: authRequest.BindingMessage,
+			ExpiresAt:      authRequest.ExpiresAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, authRequestsDto)
+}
+
+// approveBackchannelAuthenticationHandler godoc
+// @Summary Approve a backchannel authentication request
+// @Description Approve a CIBA request of the current user, so the client can retrieve the tokens
+// @Tags OIDC
+// @Accept json
+// @Param id path string true "ID of the request"
+// @Param request body dto.BackchannelAuthenticationApproveDto false "Reauthentication token, if the client requires it"
+// @Success 204 "No Content"
+// @Router /api/oidc/backchannel-authentications/{id}/approve [post]
+func (oc *OidcController) approveBackchannelAuthenticationHandler(c *gin.Context) {
+	// The body is optional, as it's only needed for clients that require reauthentication
+	var input dto.BackchannelAuthenticationApproveDto
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		_ = c.Error(err)
+		return
+	}
+
+	auth := service.AuthenticationInfo{
+		Time:    c.GetTime("authTime"),
+		Methods: c.GetStringSlice("authMethods"),
+	}
+
+	err := oc.oidcService.ApproveBackchannelAuthentication(c.Request.Context(), c.Param("id"), c.GetString("userID"), input.ReauthenticationToken, auth, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// denyBackchannelAuthenticationHandler godoc
+// @Summary Deny a backchannel authentication request
+// @Description Deny a CIBA request of the current user, so the client receives an access_denied error
+// @Tags OIDC
+// @Param id path string true "ID of the request"
+// @Success 204 "No Content"
+// @Router /api/oidc/backchannel-authentications/{id}/deny [post]
+func (oc *OidcController) denyBackchannelAuthenticationHandler(c *gin.Context) {
+	err := oc.oidcService.DenyBackchannelAuthentication(c.Request.Context(), c.Param("id"), c.GetString("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // listOwnAuthorizedClientsHandler godoc
 // @Summary List authorized clients for current user
 // @Description Get a paginated list of OIDC clients that the current user has authorized
@@ -721,7 +1383,7 @@ func (oc *OidcController) revokeOwnClientAuthorizationHandler(c *gin.Context) {
 
 	userID := c.GetString("userID")
 
-	err := oc.oidcService.RevokeAuthorizedClient(c.Request.Context(), userID, clientID)
+	err := oc.oidcService.RevokeAuthorizedClient(c.Request.Context(), userID, clientID, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		_ = c.Error(err)
 		return
@@ -772,7 +1434,12 @@ func (oc *OidcController) verifyDeviceCodeHandler(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.Request.UserAgent()
 
-	err := oc.oidcService.VerifyDeviceCode(c.Request.Context(), userCode, c.GetString("userID"), ipAddress, userAgent)
+	auth := service.AuthenticationInfo{
+		Time:    c.GetTime("authTime"),
+		Methods: c.GetStringSlice("authMethods"),
+	}
+
+	err := oc.oidcService.VerifyDeviceCode(c.Request.Context(), userCode, c.GetString("userID"), auth, ipAddress, userAgent)
 	if err != nil {
 		_ = c.Error(err)
 		return
@@ -836,3 +1503,13 @@ func (oc *OidcController) getClientPreviewHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, preview)
 }
+
+// clientCertificate returns the TLS client certificate of the request, used for mutual-TLS client authentication and certificate-bound tokens
+func clientCertificate(c *gin.Context) (*x509.Certificate, bool, error) {
+	cert, chainVerified, err := utils.ClientCertificate(c.Request)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Invalid client certificate", slog.Any("error", err))
+		return nil, false, &common.OidcClientCertificateInvalidError{}
+	}
+	return cert, chainVerified, nil
+}
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/middleware"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// NewOidcScopeController creates a new controller for custom scope management
+// @Summary Custom scope management controller
+// @Description Initializes all custom scope-related API endpoints
+// @Tags OIDC Scopes
+func NewOidcScopeController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, oidcScopeService *service.OidcScopeService) {
+	osc := OidcScopeController{
+		oidcScopeService: oidcScopeService,
+	}
+
+	scopesGroup := group.Group("/oidc/scopes")
+	scopesGroup.Use(authMiddleware.Add())
+	{
+		scopesGroup.GET("", osc.list)
+		scopesGroup.GET("/:id", osc.get)
+		scopesGroup.POST("", osc.create)
+		scopesGroup.PUT("/:id", osc.update)
+		scopesGroup.DELETE("/:id", osc.delete)
+	}
+}
+
+type OidcScopeController struct {
+	oidcScopeService *service.OidcScopeService
+}
+
+// list godoc
+// @Summary List custom scopes
+// @Description Get a paginated list of custom scopes with optional search and sorting
+// @Tags OIDC Scopes
+// @Param search query string false "Search term to filter scopes by name"
+// @Param pagination[page] query int false "Page number for pagination" default(1)
+// @Param pagination[limit] query int false "Number of items per page" default(20)
+// @Param sort[column] query string false "Column to sort by"
+// @Param sort[direction] query string false "Sort direction (asc or desc)" default("asc")
+// @Success 200 {object} dto.Paginated[dto.OidcScopeDto]
+// @Router /api/oidc/scopes [get]
+func (osc *OidcScopeController) list(c *gin.Context) {
+	searchTerm := c.Query("search")
+	var sortedPaginationRequest utils.SortedPaginationRequest
+	if err := c.ShouldBindQuery(&sortedPaginationRequest); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scopes, pagination, err := osc.oidcScopeService.List(c.Request.Context(), searchTerm, sortedPaginationRequest)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var scopesDto []dto.OidcScopeDto
+	if err := dto.MapStructList(scopes, &scopesDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Paginated[dto.OidcScopeDto]{
+		Data:       scopesDto,
+		Pagination: pagination,
+	})
+}
+
+// get godoc
+// @Summary Get custom scope by ID
+// @Description Retrieve detailed information about a specific custom scope
+// @Tags OIDC Scopes
+// @Produce json
+// @Param id path string true "Scope ID"
+// @Success 200 {object} dto.OidcScopeDto
+// @Router /api/oidc/scopes/{id} [get]
+func (osc *OidcScopeController) get(c *gin.Context) {
+	scope, err := osc.oidcScopeService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var scopeDto dto.OidcScopeDto
+	if err := dto.MapStruct(scope, &scopeDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scopeDto)
+}
+
+// create godoc
+// @Summary Create custom scope
+// @Description Create a new custom scope that releases the given claims
+// @Tags OIDC Scopes
+// @Accept json
+// @Produce json
+// @Param scope body dto.OidcScopeCreateDto true "Scope information"
+// @Success 201 {object} dto.OidcScopeDto "Created scope"
+// @Router /api/oidc/scopes [post]
+func (osc *OidcScopeController) create(c *gin.Context) {
+	var input dto.OidcScopeCreateDto
+	if err := dto.ShouldBindWithNormalizedJSON(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scope, err := osc.oidcScopeService.Create(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var scopeDto dto.OidcScopeDto
+	if err := dto.MapStruct(scope, &scopeDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, scopeDto)
+}
+
+// update godoc
+// @Summary Update custom scope
+// @Description Update an existing custom scope by ID
+// @Tags OIDC Scopes
+// @Accept json
+// @Produce json
+// @Param id path string true "Scope ID"
+// @Param scope body dto.OidcScopeCreateDto true "Scope information"
+// @Success 200 {object} dto.OidcScopeDto "Updated scope"
+// @Router /api/oidc/scopes/{id} [put]
+func (osc *OidcScopeController) update(c *gin.Context) {
+	var input dto.OidcScopeCreateDto
+	if err := dto.ShouldBindWithNormalizedJSON(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scope, err := osc.oidcScopeService.Update(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var scopeDto dto.OidcScopeDto
+	if err := dto.MapStruct(scope, &scopeDto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scopeDto)
+}
+
+// delete godoc
+// @Summary Delete custom scope
+// @Description Delete a specific custom scope by ID
+// @Tags OIDC Scopes
+// @Param id path string true "Scope ID"
+// @Success 204 "No Content"
+// @Router /api/oidc/scopes/{id} [delete]
+func (osc *OidcScopeController) delete(c *gin.Context) {
+	if err := osc.oidcScopeService.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -15,8 +16,8 @@ import (
 	"golang.org/x/time/rate"
 )
 
-func NewWebauthnController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, rateLimitMiddleware *middleware.RateLimitMiddleware, webauthnService *service.WebAuthnService, appConfigService *service.AppConfigService) {
-	wc := &WebauthnController{webAuthnService: webauthnService, appConfigService: appConfigService}
+func NewWebauthnController(group *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware, rateLimitMiddleware *middleware.RateLimitMiddleware, webauthnService *service.WebAuthnService, appConfigService *service.AppConfigService, oidcService *service.OidcService) {
+	wc := &WebauthnController{webAuthnService: webauthnService, appConfigService: appConfigService, oidcService: oidcService}
 	group.GET("/webauthn/register/start", authMiddleware.WithAdminNotRequired().Add(), wc.beginRegistrationHandler)
 	group.POST("/webauthn/register/finish", authMiddleware.WithAdminNotRequired().Add(), wc.verifyRegistrationHandler)
 
@@ -35,6 +36,7 @@ func NewWebauthnController(group *gin.RouterGroup, authMiddleware *middleware.Au
 type WebauthnController struct {
 	webAuthnService  *service.WebAuthnService
 	appConfigService *service.AppConfigService
+	oidcService      *service.OidcService
 }
 
 func (wc *WebauthnController) beginRegistrationHandler(c *gin.Context) {
@@ -171,6 +173,13 @@ func (wc *WebauthnController) updateCredentialHandler(c *gin.Context) {
 
 func (wc *WebauthnController) logoutHandler(c *gin.Context) {
 	cookie.AddAccessTokenCookie(c, 0, "")
+
+	// Signing out of Pocket ID ends the sessions at the clients as well
+	err := wc.oidcService.SendBackchannelLogout(c.Request.Context(), c.GetString("userID"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Failed to send back-channel logout", "error", err)
+	}
+
 	c.Status(http.StatusNoContent)
 }
 
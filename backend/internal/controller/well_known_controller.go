@@ -1,15 +1,16 @@
 package controller
 
 import (
-	"encoding/json"
-	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
 	"github.com/pocket-id/pocket-id/backend/internal/service"
 )
 
@@ -17,10 +18,11 @@ import (
 // @Summary OIDC Discovery controller
 // @Description Initializes OIDC discovery and JWKS endpoints
 // @Tags Well Known
-func NewWellKnownController(group *gin.RouterGroup, jwtService *service.JwtService) {
-	wkc := &WellKnownController{jwtService: jwtService}
+func NewWellKnownController(group *gin.RouterGroup, jwtService *service.JwtService, oidcScopeService *service.OidcScopeService) {
+	wkc := &WellKnownController{jwtService: jwtService, oidcScopeService: oidcScopeService}
 
-	// Pre-compute the OIDC configuration document, which is static
+	// Pre-compute the OIDC configuration document
+	// Only the custom scopes and their claims are added when the document is requested
 	var err error
 	wkc.oidcConfig, err = wkc.computeOIDCConfiguration()
 	if err != nil {
@@ -34,8 +36,9 @@ func NewWellKnownController(group *gin.RouterGroup, jwtService *service.JwtServi
 }
 
 type WellKnownController struct {
-	jwtService *service.JwtService
-	oidcConfig []byte
+	jwtService       *service.JwtService
+	oidcScopeService *service.OidcScopeService
+	oidcConfig       map[string]any
 }
 
 // jwksHandler godoc
@@ -62,18 +65,34 @@ func (wkc *WellKnownController) jwksHandler(c *gin.Context) {
 // @Success 200 {object} object "OpenID Connect configuration"
 // @Router /.well-known/openid-configuration [get]
 func (wkc *WellKnownController) openIDConfigurationHandler(c *gin.Context) {
-	c.Data(http.StatusOK, "application/json; charset=utf-8", wkc.oidcConfig)
+	scopes, err := wkc.oidcScopeService.ListAll(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	config := maps.Clone(wkc.oidcConfig)
+	scopesSupported := slices.Clone(service.BuiltInScopes)
+	claimsSupported := slices.Clone(service.BuiltInClaims)
+	for _, scope := range scopes {
+		scopesSupported = append(scopesSupported, scope.Name)
+		for _, claim := range scope.Claims {
+			if !slices.Contains(claimsSupported, claim) {
+				claimsSupported = append(claimsSupported, claim)
+			}
+		}
+	}
+	config["scopes_supported"] = scopesSupported
+	config["claims_supported"] = claimsSupported
+
+	c.JSON(http.StatusOK, config)
 }
 
-func (wkc *WellKnownController) computeOIDCConfiguration() ([]byte, error) {
+func (wkc *WellKnownController) computeOIDCConfiguration() (map[string]any, error) {
 	appUrl := common.EnvConfig.AppURL
 
 	internalAppUrl := common.EnvConfig.InternalAppURL
 
-	alg, err := wkc.jwtService.GetKeyAlg()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get key algorithm: %w", err)
-	}
 	config := map[string]any{
 		"issuer":                                         appUrl,
 		"authorization_endpoint":                         appUrl + "/authorize",
@@ -81,16 +100,40 @@ func (wkc *WellKnownController) computeOIDCConfiguration() ([]byte, error) {
 		"userinfo_endpoint":                              internalAppUrl + "/api/oidc/userinfo",
 		"end_session_endpoint":                           appUrl + "/api/oidc/end-session",
 		"introspection_endpoint":                         internalAppUrl + "/api/oidc/introspect",
+		"revocation_endpoint":                            internalAppUrl + "/api/oidc/revoke",
 		"device_authorization_endpoint":                  appUrl + "/api/oidc/device/authorize",
+		"backchannel_authentication_endpoint":            internalAppUrl + "/api/oidc/bc-authorize",
+		"pushed_authorization_request_endpoint":          internalAppUrl + "/api/oidc/par",
+		"registration_endpoint":                          internalAppUrl + "/api/oidc/register",
 		"jwks_uri":                                       internalAppUrl + "/.well-known/jwks.json",
-		"grant_types_supported":                          []string{service.GrantTypeAuthorizationCode, service.GrantTypeRefreshToken, service.GrantTypeDeviceCode, service.GrantTypeClientCredentials},
-		"scopes_supported":                               []string{"openid", "profile", "email", "groups"},
-		"claims_supported":                               []string{"sub", "given_name", "family_name", "name", "email", "email_verified", "preferred_username", "picture", "groups"},
+		"grant_types_supported":                          []string{service.GrantTypeAuthorizationCode, service.GrantTypeRefreshToken, service.GrantTypeDeviceCode, service.GrantTypeClientCredentials, service.GrantTypeTokenExchange, service.GrantTypeJWTBearer, service.GrantTypeCIBA},
 		"response_types_supported":                       []string{"code", "id_token"},
-		"subject_types_supported":                        []string{"public"},
-		"id_token_signing_alg_values_supported":          []string{alg.String()},
+		"response_modes_supported":                       service.SupportedResponseModes,
+		"subject_types_supported":                        []string{model.OidcSubjectTypePublic, model.OidcSubjectTypePairwise},
+		"id_token_signing_alg_values_supported":          common.SigningKeyAlgs,
+		"id_token_encryption_alg_values_supported":       service.SupportedEncryptionAlgs,
+		"id_token_encryption_enc_values_supported":       service.SupportedEncryptionEncs,
+		"userinfo_signing_alg_values_supported":          common.SigningKeyAlgs,
+		"userinfo_encryption_alg_values_supported":       service.SupportedEncryptionAlgs,
+		"userinfo_encryption_enc_values_supported":       service.SupportedEncryptionEncs,
 		"authorization_response_iss_parameter_supported": true,
+		"authorization_signing_alg_values_supported":     common.SigningKeyAlgs,
 		"code_challenge_methods_supported":               []string{"plain", "S256"},
+		"prompt_values_supported":                        []string{"none", "login", "consent"},
+		"acr_values_supported":                           service.SupportedAcrValues,
+		"request_parameter_supported":                    true,
+		"claims_parameter_supported":                     true,
+		"request_uri_parameter_supported":                false,
+		"request_object_signing_alg_values_supported":    service.SupportedRequestObjectSigningAlgs,
+		"backchannel_token_delivery_modes_supported":     []string{"poll"},
+		"backchannel_user_code_parameter_supported":      false,
+		"backchannel_logout_supported":                   true,
+		"backchannel_logout_session_supported":           false,
+		"frontchannel_logout_supported":                  true,
+		"frontchannel_logout_session_supported":          false,
+		"dpop_signing_alg_values_supported":              service.SupportedDpopSigningAlgs,
+		"token_endpoint_auth_methods_supported":          []string{"none", "client_secret_basic", "client_secret_post", "private_key_jwt", "tls_client_auth", "self_signed_tls_client_auth"},
+		"tls_client_certificate_bound_access_tokens":     true,
 	}
-	return json.Marshal(config)
+	return config, nil
 }
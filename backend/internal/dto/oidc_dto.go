@@ -1,6 +1,13 @@
 package dto
 
-import datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+import (
+	"crypto/x509"
+	"encoding/json"
+	"time"
+
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
 
 type OidcClientMetaDataDto struct {
 	ID                       string  `json:"id"`
@@ -17,6 +24,32 @@ type OidcClientDto struct {
 	IsPublic           bool                     `json:"isPublic"`
 	PkceEnabled        bool                     `json:"pkceEnabled"`
 	Credentials        OidcClientCredentialsDto `json:"credentials"`
+
+	RequiresPushedAuthorizationRequests bool     `json:"requiresPushedAuthorizationRequests"`
+	RequiresSignedRequestObject         bool     `json:"requiresSignedRequestObject"`
+	JwksURL                             *string  `json:"jwksURL"`
+	Jwks                                *string  `json:"jwks"`
+	BackchannelLogoutURL                *string  `json:"backchannelLogoutURL"`
+	FrontchannelLogoutURL               *string  `json:"frontchannelLogoutURL"`
+	FrontchannelLogoutSessionRequired   bool     `json:"frontchannelLogoutSessionRequired"`
+	RequiresDpop                        bool     `json:"requiresDpop"`
+	MinimumAcr                          *string  `json:"minimumAcr"`
+	TokenExchangeAudiences              []string `json:"tokenExchangeAudiences"`
+	RefreshTokensDisabled               bool     `json:"refreshTokensDisabled"`
+	RefreshTokensWithoutOfflineAccess   bool     `json:"refreshTokensWithoutOfflineAccess"`
+	BackchannelAuthenticationEnabled    bool     `json:"backchannelAuthenticationEnabled"`
+	SubjectType                         string   `json:"subjectType"`
+	SectorIdentifierURI                 *string  `json:"sectorIdentifierURI"`
+	AccessTokenLifetime                 *int     `json:"accessTokenLifetime"`
+	IdTokenLifetime                     *int     `json:"idTokenLifetime"`
+	RefreshTokenIdleLifetime            *int     `json:"refreshTokenIdleLifetime"`
+	RefreshTokenAbsoluteLifetime        *int     `json:"refreshTokenAbsoluteLifetime"`
+	IdTokenEncryptedResponseAlg         *string  `json:"idTokenEncryptedResponseAlg"`
+	IdTokenEncryptedResponseEnc         *string  `json:"idTokenEncryptedResponseEnc"`
+	UserinfoEncryptedResponseAlg        *string  `json:"userinfoEncryptedResponseAlg"`
+	UserinfoEncryptedResponseEnc        *string  `json:"userinfoEncryptedResponseEnc"`
+	UserinfoSignedResponseAlg           *string  `json:"userinfoSignedResponseAlg"`
+	IdTokenSignedResponseAlg            *string  `json:"idTokenSignedResponseAlg"`
 }
 
 type OidcClientWithAllowedUserGroupsDto struct {
@@ -30,16 +63,41 @@ type OidcClientWithAllowedGroupsCountDto struct {
 }
 
 type OidcClientUpdateDto struct {
-	Name                     string                   `json:"name" binding:"required,max=50" unorm:"nfc"`
-	CallbackURLs             []string                 `json:"callbackURLs" binding:"omitempty,dive,callback_url"`
-	LogoutCallbackURLs       []string                 `json:"logoutCallbackURLs" binding:"omitempty,dive,callback_url"`
-	IsPublic                 bool                     `json:"isPublic"`
-	PkceEnabled              bool                     `json:"pkceEnabled"`
-	RequiresReauthentication bool                     `json:"requiresReauthentication"`
-	Credentials              OidcClientCredentialsDto `json:"credentials"`
-	LaunchURL                *string                  `json:"launchURL" binding:"omitempty,url"`
-	HasLogo                  bool                     `json:"hasLogo"`
-	LogoURL                  *string                  `json:"logoUrl"`
+	Name                                string                   `json:"name" binding:"required,max=50" unorm:"nfc"`
+	CallbackURLs                        []string                 `json:"callbackURLs" binding:"omitempty,dive,callback_url"`
+	LogoutCallbackURLs                  []string                 `json:"logoutCallbackURLs" binding:"omitempty,dive,callback_url"`
+	IsPublic                            bool                     `json:"isPublic"`
+	PkceEnabled                         bool                     `json:"pkceEnabled"`
+	RequiresReauthentication            bool                     `json:"requiresReauthentication"`
+	RequiresPushedAuthorizationRequests bool                     `json:"requiresPushedAuthorizationRequests"`
+	RequiresSignedRequestObject         bool                     `json:"requiresSignedRequestObject"`
+	JwksURL                             *string                  `json:"jwksURL" binding:"omitempty,url"`
+	Jwks                                *string                  `json:"jwks" binding:"omitempty,json"`
+	BackchannelLogoutURL                *string                  `json:"backchannelLogoutURL" binding:"omitempty,url"`
+	FrontchannelLogoutURL               *string                  `json:"frontchannelLogoutURL" binding:"omitempty,url"`
+	FrontchannelLogoutSessionRequired   bool                     `json:"frontchannelLogoutSessionRequired"`
+	RequiresDpop                        bool                     `json:"requiresDpop"`
+	MinimumAcr                          *string                  `json:"minimumAcr" binding:"omitempty,oneof=urn:pocket-id:acr:one-time-code urn:pocket-id:acr:passkey"`
+	TokenExchangeAudiences              []string                 `json:"tokenExchangeAudiences" binding:"omitempty,dive,min=1,max=1024"`
+	RefreshTokensDisabled               bool                     `json:"refreshTokensDisabled"`
+	RefreshTokensWithoutOfflineAccess   bool                     `json:"refreshTokensWithoutOfflineAccess"`
+	BackchannelAuthenticationEnabled    bool                     `json:"backchannelAuthenticationEnabled"`
+	SubjectType                         string                   `json:"subjectType" binding:"omitempty,oneof=public pairwise"`
+	SectorIdentifierURI                 *string                  `json:"sectorIdentifierURI" binding:"omitempty,url"`
+	AccessTokenLifetime                 *int                     `json:"accessTokenLifetime" binding:"omitempty,min=60,max=86400"`
+	IdTokenLifetime                     *int                     `json:"idTokenLifetime" binding:"omitempty,min=60,max=86400"`
+	RefreshTokenIdleLifetime            *int                     `json:"refreshTokenIdleLifetime" binding:"omitempty,min=60,max=31536000"`
+	RefreshTokenAbsoluteLifetime        *int                     `json:"refreshTokenAbsoluteLifetime" binding:"omitempty,min=60,max=31536000"`
+	IdTokenEncryptedResponseAlg         *string                  `json:"idTokenEncryptedResponseAlg" binding:"omitempty,oneof=RSA-OAEP RSA-OAEP-256 ECDH-ES ECDH-ES+A128KW ECDH-ES+A256KW"`
+	IdTokenEncryptedResponseEnc         *string                  `json:"idTokenEncryptedResponseEnc" binding:"omitempty,oneof=A128CBC-HS256 A256CBC-HS512 A128GCM A256GCM"`
+	UserinfoEncryptedResponseAlg        *string                  `json:"userinfoEncryptedResponseAlg" binding:"omitempty,oneof=RSA-OAEP RSA-OAEP-256 ECDH-ES ECDH-ES+A128KW ECDH-ES+A256KW"`
+	UserinfoEncryptedResponseEnc        *string                  `json:"userinfoEncryptedResponseEnc" binding:"omitempty,oneof=A128CBC-HS256 A256CBC-HS512 A128GCM A256GCM"`
+	UserinfoSignedResponseAlg           *string                  `json:"userinfoSignedResponseAlg" binding:"omitempty,oneof=RS256 RS384 RS512 ES256 ES384 ES512 EdDSA"`
+	IdTokenSignedResponseAlg            *string                  `json:"idTokenSignedResponseAlg" binding:"omitempty,oneof=RS256 RS384 RS512 ES256 ES384 ES512 EdDSA"`
+	Credentials                         OidcClientCredentialsDto `json:"credentials"`
+	LaunchURL                           *string                  `json:"launchURL" binding:"omitempty,url"`
+	HasLogo                             bool                     `json:"hasLogo"`
+	LogoURL                             *string                  `json:"logoUrl"`
 }
 
 type OidcClientCreateDto struct {
@@ -48,41 +106,168 @@ type OidcClientCreateDto struct {
 }
 
 type OidcClientCredentialsDto struct {
-	FederatedIdentities []OidcClientFederatedIdentityDto `json:"federatedIdentities,omitempty"`
+	FederatedIdentities      []OidcClientFederatedIdentityDto `json:"federatedIdentities,omitempty"`
+	TLSClientAuthSubjectDN   string                           `json:"tlsClientAuthSubjectDn,omitempty" binding:"max=1024"`
+	TLSClientAuthThumbprints []string                         `json:"tlsClientAuthThumbprints,omitempty" binding:"omitempty,dive,base64rawurl"`
 }
 
 type OidcClientFederatedIdentityDto struct {
-	Issuer   string `json:"issuer"`
-	Subject  string `json:"subject,omitempty"`
-	Audience string `json:"audience,omitempty"`
-	JWKS     string `json:"jwks,omitempty"`
+	Issuer   string            `json:"issuer"`
+	Subject  string            `json:"subject,omitempty"`
+	Audience string            `json:"audience,omitempty"`
+	JWKS     string            `json:"jwks,omitempty"`
+	Claims   map[string]string `json:"claims,omitempty" binding:"omitempty,max=20,dive,keys,required,max=128,endkeys,max=1024"`
+	UserID   string            `json:"userId,omitempty"`
 }
 
 type AuthorizeOidcClientRequestDto struct {
 	ClientID              string `json:"clientID" binding:"required"`
-	Scope                 string `json:"scope" binding:"required"`
+	Scope                 string `json:"scope" binding:"required_without_all=RequestURI Request"`
 	CallbackURL           string `json:"callbackURL"`
 	Nonce                 string `json:"nonce"`
+	State                 string `json:"state"`
 	CodeChallenge         string `json:"codeChallenge"`
 	CodeChallengeMethod   string `json:"codeChallengeMethod"`
 	ReauthenticationToken string `json:"reauthenticationToken"`
+	RequestURI            string `json:"requestUri"`
+	Request               string `json:"request"`
+	Prompt                string `json:"prompt"`
+	MaxAge                *int   `json:"maxAge" binding:"omitempty,min=0"`
+	AcrValues             string `json:"acrValues"`
+	Claims                string `json:"claims"`
+	ResponseMode          string `json:"responseMode"`
+
+	// AuthTime is the time at which the user signed in
+	AuthTime time.Time `json:"-"`
+	// AuthenticationMethods are the methods the user signed in with
+	AuthenticationMethods []string `json:"-"`
 }
 
 type AuthorizeOidcClientResponseDto struct {
-	Code        string `json:"code"`
+	Code        string `json:"code,omitempty"`
 	CallbackURL string `json:"callbackURL"`
 	Issuer      string `json:"issuer"`
+	State       string `json:"state,omitempty"`
+	// Error is set instead of the code if the authorization request can't be completed without user interaction
+	Error string `json:"error,omitempty"`
+	// ResponseMode is how the response must be returned to the callback URL
+	ResponseMode string `json:"responseMode"`
+	// Response is set instead of the code, state and error with the JWT response modes, and contains them in a signed JWT
+	Response string `json:"response,omitempty"`
+}
+
+// OidcAuthorizationResponseDto contains the authorization response that the authorize page posts back to render the form_post response
+type OidcAuthorizationResponseDto struct {
+	ClientID    string `form:"client_id" binding:"required"`
+	CallbackURL string `form:"redirect_uri" binding:"required"`
+	Code        string `form:"code"`
+	State       string `form:"state"`
+	Error       string `form:"error"`
+	Issuer      string `form:"iss"`
+	Response    string `form:"response"`
+}
+
+type OidcPushedAuthorizationRequestDto struct {
+	ClientID            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertion     string `form:"client_assertion"`
+	ClientAssertionType string `form:"client_assertion_type"`
+	Scope               string `form:"scope" binding:"required_without=Request"`
+	CallbackURL         string `form:"redirect_uri"`
+	Nonce               string `form:"nonce"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Prompt              string `form:"prompt"`
+	MaxAge              *int   `form:"max_age" binding:"omitempty,min=0"`
+	AcrValues           string `form:"acr_values"`
+	Claims              string `form:"claims"`
+	ResponseMode        string `form:"response_mode"`
+	Request             string `form:"request"`
+
+	// ClientCertificate is the TLS client certificate, used for mutual-TLS client authentication
+	ClientCertificate *x509.Certificate `form:"-"`
+	// ClientCertificateChainVerified is true if the chain of the client certificate was verified against the client CAs or by the trusted proxy
+	ClientCertificateChainVerified bool `form:"-"`
+}
+
+type OidcPushedAuthorizationResponseDto struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+type OidcInitialAccessTokenCreateDto struct {
+	TTL        utils.JSONDuration `json:"ttl" binding:"required,ttl"`
+	UsageLimit int                `json:"usageLimit" binding:"required,min=1,max=100"`
+}
+
+type OidcInitialAccessTokenDto struct {
+	ID         string            `json:"id"`
+	ExpiresAt  datatype.DateTime `json:"expiresAt"`
+	UsageLimit int               `json:"usageLimit"`
+	UsageCount int               `json:"usageCount"`
+	CreatedAt  datatype.DateTime `json:"createdAt"`
+}
+
+type OidcInitialAccessTokenResponseDto struct {
+	InitialAccessToken OidcInitialAccessTokenDto `json:"initialAccessToken"`
+	Token              string                    `json:"token"`
+}
+
+type OidcClientRegistrationDto struct {
+	ClientName                        string          `json:"client_name" binding:"required,max=50" unorm:"nfc"`
+	RedirectURIs                      []string        `json:"redirect_uris" binding:"omitempty,dive,callback_url"`
+	PostLogoutRedirectURIs            []string        `json:"post_logout_redirect_uris,omitempty" binding:"omitempty,dive,callback_url"`
+	TokenEndpointAuthMethod           string          `json:"token_endpoint_auth_method" binding:"omitempty,oneof=none client_secret_basic client_secret_post"`
+	JwksURI                           *string         `json:"jwks_uri,omitempty" binding:"omitempty,url"`
+	Jwks                              json.RawMessage `json:"jwks,omitempty"`
+	ClientURI                         *string         `json:"client_uri,omitempty" binding:"omitempty,url"`
+	LogoURI                           *string         `json:"logo_uri,omitempty" binding:"omitempty,url"`
+	BackchannelLogoutURI              *string         `json:"backchannel_logout_uri,omitempty" binding:"omitempty,url"`
+	FrontchannelLogoutURI             *string         `json:"frontchannel_logout_uri,omitempty" binding:"omitempty,url"`
+	FrontchannelLogoutSessionRequired bool            `json:"frontchannel_logout_session_required,omitempty"`
+	SubjectType                       string          `json:"subject_type,omitempty" binding:"omitempty,oneof=public pairwise"`
+	SectorIdentifierURI               *string         `json:"sector_identifier_uri,omitempty" binding:"omitempty,url"`
+	IdTokenEncryptedResponseAlg       *string         `json:"id_token_encrypted_response_alg,omitempty" binding:"omitempty,oneof=RSA-OAEP RSA-OAEP-256 ECDH-ES ECDH-ES+A128KW ECDH-ES+A256KW"`
+	IdTokenEncryptedResponseEnc       *string         `json:"id_token_encrypted_response_enc,omitempty" binding:"omitempty,oneof=A128CBC-HS256 A256CBC-HS512 A128GCM A256GCM"`
+	UserinfoEncryptedResponseAlg      *string         `json:"userinfo_encrypted_response_alg,omitempty" binding:"omitempty,oneof=RSA-OAEP RSA-OAEP-256 ECDH-ES ECDH-ES+A128KW ECDH-ES+A256KW"`
+	UserinfoEncryptedResponseEnc      *string         `json:"userinfo_encrypted_response_enc,omitempty" binding:"omitempty,oneof=A128CBC-HS256 A256CBC-HS512 A128GCM A256GCM"`
+	UserinfoSignedResponseAlg         *string         `json:"userinfo_signed_response_alg,omitempty" binding:"omitempty,oneof=RS256 RS384 RS512 ES256 ES384 ES512 EdDSA"`
+	IdTokenSignedResponseAlg          *string         `json:"id_token_signed_response_alg,omitempty" binding:"omitempty,oneof=RS256 RS384 RS512 ES256 ES384 ES512 EdDSA"`
+}
+
+type OidcClientRegistrationResponseDto struct {
+	OidcClientRegistrationDto
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
 }
 
 type AuthorizationRequiredDto struct {
-	ClientID string `json:"clientID" binding:"required"`
-	Scope    string `json:"scope" binding:"required"`
+	ClientID   string `json:"clientID" binding:"required"`
+	Scope      string `json:"scope" binding:"required_without_all=RequestURI Request"`
+	Prompt     string `json:"prompt"`
+	Claims     string `json:"claims"`
+	RequestURI string `json:"requestUri"`
+	Request    string `json:"request"`
+}
+
+type AuthorizationRequiredResponseDto struct {
+	AuthorizationRequired bool `json:"authorizationRequired"`
+	// Scope, Claims and Prompt are the ones of the resolved request, as they aren't known to the consent screen if the client pushed the request
+	Scope  string `json:"scope"`
+	Claims string `json:"claims,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
 }
 
 type OidcCreateTokensDto struct {
 	GrantType           string `form:"grant_type" binding:"required"`
 	Code                string `form:"code"`
 	DeviceCode          string `form:"device_code"`
+	AuthReqID           string `form:"auth_req_id"`
 	ClientID            string `form:"client_id"`
 	ClientSecret        string `form:"client_secret"`
 	CodeVerifier        string `form:"code_verifier"`
@@ -90,12 +275,39 @@ type OidcCreateTokensDto struct {
 	ClientAssertion     string `form:"client_assertion"`
 	ClientAssertionType string `form:"client_assertion_type"`
 	Resource            string `form:"resource"`
+	Audience            string `form:"audience"`
+	Scope               string `form:"scope"`
+	SubjectToken        string `form:"subject_token"`
+	SubjectTokenType    string `form:"subject_token_type"`
+	ActorToken          string `form:"actor_token"`
+	ActorTokenType      string `form:"actor_token_type"`
+	RequestedTokenType  string `form:"requested_token_type"`
+	Assertion           string `form:"assertion"`
+
+	// DpopProof is the DPoP proof sent in the DPoP header
+	DpopProof string `form:"-"`
+	// ClientCertificate is the TLS client certificate, used for mutual-TLS client authentication
+	ClientCertificate *x509.Certificate `form:"-"`
+	// ClientCertificateChainVerified is true if the chain of the client certificate was verified against the client CAs or by the trusted proxy
+	ClientCertificateChainVerified bool `form:"-"`
+	// IPAddress and UserAgent identify the caller in the audit log
+	IPAddress string `form:"-"`
+	UserAgent string `form:"-"`
 }
 
 type OidcIntrospectDto struct {
 	Token string `form:"token" binding:"required"`
 }
 
+type OidcRevokeTokenDto struct {
+	Token               string `form:"token" binding:"required"`
+	TokenTypeHint       string `form:"token_type_hint"`
+	ClientID            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertion     string `form:"client_assertion"`
+	ClientAssertionType string `form:"client_assertion_type"`
+}
+
 type OidcUpdateAllowedUserGroupsDto struct {
 	UserGroupIDs []string `json:"userGroupIds" binding:"required"`
 }
@@ -113,19 +325,22 @@ type OidcTokenResponseDto struct {
 	IdToken      string `json:"id_token,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	ExpiresIn    int    `json:"expires_in"`
+	// IssuedTokenType is set for the token exchange grant, as described in RFC 8693
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
 }
 
 type OidcIntrospectionResponseDto struct {
-	Active     bool     `json:"active"`
-	TokenType  string   `json:"token_type,omitempty"`
-	Scope      string   `json:"scope,omitempty"`
-	Expiration int64    `json:"exp,omitempty"`
-	IssuedAt   int64    `json:"iat,omitempty"`
-	NotBefore  int64    `json:"nbf,omitempty"`
-	Subject    string   `json:"sub,omitempty"`
-	Audience   []string `json:"aud,omitempty"`
-	Issuer     string   `json:"iss,omitempty"`
-	Identifier string   `json:"jti,omitempty"`
+	Active       bool              `json:"active"`
+	TokenType    string            `json:"token_type,omitempty"`
+	Scope        string            `json:"scope,omitempty"`
+	Expiration   int64             `json:"exp,omitempty"`
+	IssuedAt     int64             `json:"iat,omitempty"`
+	NotBefore    int64             `json:"nbf,omitempty"`
+	Subject      string            `json:"sub,omitempty"`
+	Audience     []string          `json:"aud,omitempty"`
+	Issuer       string            `json:"iss,omitempty"`
+	Identifier   string            `json:"jti,omitempty"`
+	Confirmation map[string]string `json:"cnf,omitempty"`
 }
 
 type OidcDeviceAuthorizationRequestDto struct {
@@ -134,6 +349,11 @@ type OidcDeviceAuthorizationRequestDto struct {
 	ClientSecret        string `form:"client_secret"`
 	ClientAssertion     string `form:"client_assertion"`
 	ClientAssertionType string `form:"client_assertion_type"`
+
+	// ClientCertificate is the TLS client certificate, used for mutual-TLS client authentication
+	ClientCertificate *x509.Certificate `form:"-"`
+	// ClientCertificateChainVerified is true if the chain of the client certificate was verified against the client CAs or by the trusted proxy
+	ClientCertificateChainVerified bool `form:"-"`
 }
 
 type OidcDeviceAuthorizationResponseDto struct {
@@ -146,6 +366,41 @@ type OidcDeviceAuthorizationResponseDto struct {
 	RequiresAuthorization   bool   `json:"requires_authorization"`
 }
 
+type OidcBackchannelAuthenticationRequestDto struct {
+	ClientID            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertion     string `form:"client_assertion"`
+	ClientAssertionType string `form:"client_assertion_type"`
+	Scope               string `form:"scope" binding:"required"`
+	LoginHint           string `form:"login_hint" binding:"required"`
+	BindingMessage      string `form:"binding_message" binding:"max=100"`
+	RequestedExpiry     *int   `form:"requested_expiry" binding:"omitempty,min=1"`
+
+	// ClientCertificate is the TLS client certificate, used for mutual-TLS client authentication
+	ClientCertificate *x509.Certificate `form:"-"`
+	// ClientCertificateChainVerified is true if the chain of the client certificate was verified against the client CAs or by the trusted proxy
+	ClientCertificateChainVerified bool `form:"-"`
+}
+
+type OidcBackchannelAuthenticationResponseDto struct {
+	AuthReqID string `json:"auth_req_id"`
+	ExpiresIn int    `json:"expires_in"`
+	Interval  int    `json:"interval"`
+}
+
+// BackchannelAuthenticationRequestDto is a pending CIBA request that the user can approve or deny
+type BackchannelAuthenticationRequestDto struct {
+	ID             string                `json:"id"`
+	Client         OidcClientMetaDataDto `json:"client"`
+	Scope          string                `json:"scope"`
+	BindingMessage string                `json:"bindingMessage"`
+	ExpiresAt      datatype.DateTime     `json:"expiresAt"`
+}
+
+type BackchannelAuthenticationApproveDto struct {
+	ReauthenticationToken string `json:"reauthenticationToken"`
+}
+
 type OidcDeviceTokenRequestDto struct {
 	GrantType    string `form:"grant_type" binding:"required,eq=urn:ietf:params:oauth:grant-type:device_code"`
 	DeviceCode   string `form:"device_code" binding:"required"`
@@ -169,6 +424,8 @@ type OidcClientPreviewDto struct {
 	IdToken     map[string]any `json:"idToken"`
 	AccessToken map[string]any `json:"accessToken"`
 	UserInfo    map[string]any `json:"userInfo"`
+	// OfflineAccess is true if the client would receive a refresh token to access the user's data while they're not signed in
+	OfflineAccess bool `json:"offlineAccess"`
 }
 
 type AccessibleOidcClientDto struct {
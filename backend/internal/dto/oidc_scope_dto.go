@@ -0,0 +1,21 @@
+package dto
+
+import (
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+)
+
+type OidcScopeDto struct {
+	ID             string                  `json:"id"`
+	Name           string                  `json:"name"`
+	Description    string                  `json:"description"`
+	Claims         []string                `json:"claims"`
+	AllowedClients []OidcClientMetaDataDto `json:"allowedClients"`
+	CreatedAt      datatype.DateTime       `json:"createdAt"`
+}
+
+type OidcScopeCreateDto struct {
+	Name             string   `json:"name" binding:"required,max=128,scope_name"`
+	Description      string   `json:"description" binding:"max=255" unorm:"nfc"`
+	Claims           []string `json:"claims" binding:"required,max=50,dive,min=1,max=128"`
+	AllowedClientIDs []string `json:"allowedClientIds" binding:"omitempty,dive,min=1"`
+}
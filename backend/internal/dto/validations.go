@@ -19,6 +19,9 @@ var validateUsernameRegex = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_.@-]*[a-z
 
 var validateClientIDRegex = regexp.MustCompile("^[a-zA-Z0-9._-]+$")
 
+// Scope names can contain any printable ASCII character, except spaces, double quotes and backslashes, as described in RFC 6749
+var validateScopeNameRegex = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+
 func init() {
 	v := binding.Validator.Engine().(*validator.Validate)
 
@@ -37,6 +40,12 @@ func init() {
 		panic("Failed to register custom validation for client_id: " + err.Error())
 	}
 
+	if err := v.RegisterValidation("scope_name", func(fl validator.FieldLevel) bool {
+		return ValidateScopeName(fl.Field().String())
+	}); err != nil {
+		panic("Failed to register custom validation for scope_name: " + err.Error())
+	}
+
 	if err := v.RegisterValidation("ttl", func(fl validator.FieldLevel) bool {
 		ttl, ok := fl.Field().Interface().(utils.JSONDuration)
 		if !ok {
@@ -65,6 +74,11 @@ func ValidateClientID(clientID string) bool {
 	return validateClientIDRegex.MatchString(clientID)
 }
 
+// ValidateScopeName validates the names of custom scopes
+func ValidateScopeName(name string) bool {
+	return validateScopeNameRegex.MatchString(name)
+}
+
 // ValidateCallbackURL validates callback URLs with support for wildcards
 func ValidateCallbackURL(raw string) bool {
 	// Don't validate if it contains a wildcard
@@ -32,6 +32,29 @@ func TestValidateUsername(t *testing.T) {
 	}
 }
 
+func TestValidateScopeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"valid simple", "nextcloud", true},
+		{"valid with symbols", "api:read.write_all", true},
+		{"valid url", "https://example.com/scope", true},
+		{"contains space", "read write", false},
+		{"contains double quote", "read\"write", false},
+		{"contains backslash", "read\\write", false},
+		{"non-ascii", "réad", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ValidateScopeName(tt.input))
+		})
+	}
+}
+
 func TestValidateClientID(t *testing.T) {
 	tests := []struct {
 		name     string
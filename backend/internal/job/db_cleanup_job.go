@@ -25,6 +25,11 @@ func (s *Scheduler) RegisterDbCleanupJobs(ctx context.Context, db *gorm.DB) erro
 		s.registerJob(ctx, "ClearSignupTokens", def, jobs.clearSignupTokens, true),
 		s.registerJob(ctx, "ClearOidcAuthorizationCodes", def, jobs.clearOidcAuthorizationCodes, true),
 		s.registerJob(ctx, "ClearOidcRefreshTokens", def, jobs.clearOidcRefreshTokens, true),
+		s.registerJob(ctx, "ClearOidcPushedAuthorizationRequests", def, jobs.clearOidcPushedAuthorizationRequests, true),
+		s.registerJob(ctx, "ClearOidcBackchannelAuthenticationRequests", def, jobs.clearOidcBackchannelAuthenticationRequests, true),
+		s.registerJob(ctx, "ClearOidcInitialAccessTokens", def, jobs.clearOidcInitialAccessTokens, true),
+		s.registerJob(ctx, "ClearOidcDpopProofs", def, jobs.clearOidcDpopProofs, true),
+		s.registerJob(ctx, "ClearOidcUsedAssertions", def, jobs.clearOidcUsedAssertions, true),
 		s.registerJob(ctx, "ClearReauthenticationTokens", def, jobs.clearReauthenticationTokens, true),
 		s.registerJob(ctx, "ClearAuditLogs", def, jobs.clearAuditLogs, true),
 	)
@@ -105,6 +110,76 @@ func (j *DbCleanupJobs) clearOidcRefreshTokens(ctx context.Context) error {
 	return nil
 }
 
+// ClearOidcPushedAuthorizationRequests deletes OIDC pushed authorization requests that have expired
+func (j *DbCleanupJobs) clearOidcPushedAuthorizationRequests(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.OidcPushedAuthorizationRequest{}, "expires_at < ?", datatype.DateTime(time.Now()))
+	if st.Error != nil {
+		return fmt.Errorf("failed to clean expired OIDC pushed authorization requests: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired OIDC pushed authorization requests", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
+// ClearOidcBackchannelAuthenticationRequests deletes OIDC backchannel authentication requests that have expired
+func (j *DbCleanupJobs) clearOidcBackchannelAuthenticationRequests(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.OidcBackchannelAuthenticationRequest{}, "expires_at < ?", datatype.DateTime(time.Now()))
+	if st.Error != nil {
+		return fmt.Errorf("failed to clean expired OIDC backchannel authentication requests: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired OIDC backchannel authentication requests", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
+// ClearOidcInitialAccessTokens deletes OIDC initial access tokens that have expired
+func (j *DbCleanupJobs) clearOidcInitialAccessTokens(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.OidcInitialAccessToken{}, "expires_at < ?", datatype.DateTime(time.Now()))
+	if st.Error != nil {
+		return fmt.Errorf("failed to clean expired OIDC initial access tokens: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired OIDC initial access tokens", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
+// ClearOidcDpopProofs deletes the identifiers of DPoP proofs that have expired
+func (j *DbCleanupJobs) clearOidcDpopProofs(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.OidcDpopProof{}, "expires_at < ?", datatype.DateTime(time.Now()))
+	if st.Error != nil {
+		return fmt.Errorf("failed to clean expired OIDC DPoP proofs: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired OIDC DPoP proofs", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
+// ClearOidcUsedAssertions deletes the identifiers of federated assertions that have expired
+func (j *DbCleanupJobs) clearOidcUsedAssertions(ctx context.Context) error {
+	st := j.db.
+		WithContext(ctx).
+		Delete(&model.OidcUsedAssertion{}, "expires_at < ?", datatype.DateTime(time.Now()))
+	if st.Error != nil {
+		return fmt.Errorf("failed to clean expired OIDC used assertions: %w", st.Error)
+	}
+
+	slog.InfoContext(ctx, "Cleaned expired OIDC used assertions", slog.Int64("count", st.RowsAffected))
+
+	return nil
+}
+
 // ClearReauthenticationTokens deletes reauthentication tokens that have expired
 func (j *DbCleanupJobs) clearReauthenticationTokens(ctx context.Context) error {
 	st := j.db.
@@ -0,0 +1,311 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	jwkutils "github.com/pocket-id/pocket-id/backend/internal/utils/jwk"
+)
+
+// keyPrePublishDuration is how long a new signing key is published in the JWKS before it becomes active
+// This gives clients that cache the JWKS time to learn about the new key
+const keyPrePublishDuration = 7 * 24 * time.Hour
+
+// keyReloadInterval is how often the keys are reloaded from the key provider
+// This picks up the keys rotated with the CLI or by other instances without a restart
+const keyReloadInterval = 5 * time.Minute
+
+func (s *Scheduler) RegisterKeyRotationJob(ctx context.Context, db *gorm.DB, jwtService *service.JwtService, auditLogService *service.AuditLogService, appConfigService *service.AppConfigService) error {
+	jobs := &KeyRotationJob{
+		db:               db,
+		envConfig:        &common.EnvConfig,
+		jwtService:       jwtService,
+		auditLogService:  auditLogService,
+		appConfigService: appConfigService,
+	}
+
+	// The keys are reloaded regardless of whether automatic key rotation is enabled
+	err := s.registerJob(ctx, "ReloadSigningKeys", gocron.DurationJob(keyReloadInterval), jobs.reloadSigningKeys, false)
+	if err != nil {
+		return err
+	}
+
+	// Skip if automatic key rotation is disabled
+	if common.EnvConfig.KeyRotationDays <= 0 {
+		return nil
+	}
+
+	// Run every hour, and now
+	return s.registerJob(ctx, "RotateSigningKey", gocron.DurationJob(time.Hour), jobs.rotateSigningKey, true)
+}
+
+type KeyRotationJob struct {
+	db               *gorm.DB
+	envConfig        *common.EnvConfigSchema
+	jwtService       *service.JwtService
+	auditLogService  *service.AuditLogService
+	appConfigService *service.AppConfigService
+}
+
+// reloadSigningKeys reloads the active key and the keyring from the key provider
+func (j *KeyRotationJob) reloadSigningKeys(ctx context.Context) error {
+	return j.jwtService.ReloadKeys()
+}
+
+// rotateSigningKey performs the next step of the automatic key rotation, if it's due:
+// - The next key is generated and published in the JWKS some time before the rotation interval ends
+// - At the end of the interval, the next key becomes active and the previous one is retired
+// - Retired keys are removed once all tokens they signed have expired
+// The additional keys used for clients that require other algorithms are rotated on the same schedule
+func (j *KeyRotationJob) rotateSigningKey(ctx context.Context) error {
+	interval := time.Duration(j.envConfig.KeyRotationDays) * 24 * time.Hour
+	prePublish := min(keyPrePublishDuration, interval/2)
+
+	keyProvider, err := jwkutils.GetKeyProvider(j.db, j.envConfig, j.appConfigService.GetDbConfig().InstanceID.Value)
+	if err != nil {
+		return fmt.Errorf("failed to get key provider: %w", err)
+	}
+
+	activeKey, err := keyProvider.LoadKey()
+	if err != nil {
+		return fmt.Errorf("failed to load key: %w", err)
+	}
+	if activeKey == nil {
+		return errors.New("no signing key found")
+	}
+	keyRing, err := keyProvider.LoadKeyRing()
+	if err != nil {
+		return fmt.Errorf("failed to load keyring: %w", err)
+	}
+
+	now := time.Now()
+
+	// Remove the retired keys whose tokens have all expired
+	prunedKeyRing, err := jwkutils.RemoveExpiredKeys(keyRing, now)
+	if err != nil {
+		return err
+	}
+	if prunedKeyRing.Len() != keyRing.Len() {
+		err = keyProvider.SaveKeyRing(prunedKeyRing)
+		if err != nil {
+			return fmt.Errorf("failed to save keyring: %w", err)
+		}
+		for i := range keyRing.Len() {
+			key, _ := keyRing.Key(i)
+			keyID, _ := key.KeyID()
+			if _, ok := prunedKeyRing.LookupKeyID(keyID); !ok {
+				j.createAuditLog(ctx, model.AuditLogEventSigningKeyRemoved, model.AuditLogData{"keyId": keyID})
+			}
+		}
+		keyRing = prunedKeyRing
+	}
+
+	err = j.rotateAdditionalKeys(ctx, keyProvider, keyRing, interval, prePublish, now)
+	if err != nil {
+		return err
+	}
+
+	// Keys that were created without automatic rotation don't have an activation time, so the rotation interval starts now
+	activeFrom := jwkutils.GetKeyActiveFrom(activeKey)
+	if activeFrom.IsZero() {
+		activeFrom = now
+		err = jwkutils.SetKeyActiveFrom(activeKey, activeFrom)
+		if err != nil {
+			return err
+		}
+		err = keyProvider.SaveKey(activeKey)
+		if err != nil {
+			return fmt.Errorf("failed to save key: %w", err)
+		}
+	}
+
+	nextKey := jwkutils.GetNextKey(keyRing)
+	rotateAt := activeFrom.Add(interval)
+	switch {
+	case nextKey == nil && !now.Before(rotateAt.Add(-prePublish)):
+		// The next key must always be published for the whole pre-publish period, even if we're late
+		nextActiveFrom := rotateAt
+		if nextActiveFrom.Before(now.Add(prePublish)) {
+			nextActiveFrom = now.Add(prePublish)
+		}
+		err = j.publishNextKey(ctx, keyProvider, keyRing, activeKey, nextActiveFrom)
+	case nextKey != nil && !now.Before(jwkutils.GetKeyActiveFrom(nextKey)):
+		err = j.activateNextKey(ctx, keyProvider, activeKey, nextKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Reload the keys in the service, which also picks up the changes made by other instances
+	return j.jwtService.ReloadKeys()
+}
+
+// rotateAdditionalKeys performs the next step of the rotation of the additional keys, which works like the one of the active key
+// For each algorithm, the replacement is published before it becomes active, and the replaced key is retired once it's not used anymore
+func (j *KeyRotationJob) rotateAdditionalKeys(ctx context.Context, keyProvider jwkutils.KeyProvider, keyRing jwk.Set, interval time.Duration, prePublish time.Duration, now time.Time) error {
+	changed := false
+	for _, alg := range common.SigningKeyAlgs {
+		keys := jwkutils.GetAdditionalKeys(keyRing, alg)
+		current := jwkutils.CurrentAdditionalKey(keys, now)
+		if current == nil {
+			continue
+		}
+
+		// Keys that were created before additional keys were rotated don't have an activation time
+		activeFrom := jwkutils.GetKeyActiveFrom(current)
+		if activeFrom.IsZero() {
+			activeFrom = now
+			err := jwkutils.SetKeyActiveFrom(current, activeFrom)
+			if err != nil {
+				return err
+			}
+			changed = true
+		}
+
+		pending := slices.ContainsFunc(keys, func(key jwk.Key) bool {
+			return jwkutils.GetKeyActiveFrom(key).After(now)
+		})
+		rotateAt := activeFrom.Add(interval)
+		if pending || now.Before(rotateAt.Add(-prePublish)) {
+			continue
+		}
+
+		nextActiveFrom := rotateAt
+		if nextActiveFrom.Before(now.Add(prePublish)) {
+			nextActiveFrom = now.Add(prePublish)
+		}
+		key, err := jwkutils.GenerateAdditionalKey(alg, nextActiveFrom)
+		if err != nil {
+			return err
+		}
+		err = keyRing.AddKey(key)
+		if err != nil {
+			return fmt.Errorf("failed to add key to the keyring: %w", err)
+		}
+		changed = true
+
+		keyID, _ := key.KeyID()
+		slog.InfoContext(ctx, "Published the next additional signing key", slog.String("kid", keyID), slog.String("alg", alg), slog.Time("activeFrom", nextActiveFrom))
+		j.createAuditLog(ctx, model.AuditLogEventSigningKeyPublished, model.AuditLogData{
+			"keyId":      keyID,
+			"alg":        alg,
+			"activeFrom": nextActiveFrom.UTC().Format(time.RFC3339),
+		})
+	}
+
+	// Additional keys that were replaced are kept until all tokens they signed have expired
+	retainFor, err := service.MaxTokenLifetime(ctx, j.db, j.appConfigService.GetDbConfig())
+	if err != nil {
+		return fmt.Errorf("failed to compute token lifetime: %w", err)
+	}
+	retired, err := jwkutils.RetireReplacedAdditionalKeys(keyRing, now, retainFor)
+	if err != nil {
+		return err
+	}
+	for _, previousKey := range retired {
+		alg, _ := previousKey.Algorithm()
+		key := jwkutils.CurrentAdditionalKey(jwkutils.GetAdditionalKeys(keyRing, alg.String()), now)
+		keyID, _ := key.KeyID()
+		previousKeyID, _ := previousKey.KeyID()
+		slog.InfoContext(ctx, "Activated the next additional signing key", slog.String("kid", keyID), slog.String("previousKid", previousKeyID))
+		j.createAuditLog(ctx, model.AuditLogEventSigningKeyActivated, model.AuditLogData{
+			"keyId":                keyID,
+			"previousKeyId":        previousKeyID,
+			"previousKeyExpiresAt": now.Add(retainFor).UTC().Format(time.RFC3339),
+		})
+	}
+
+	if !changed && len(retired) == 0 {
+		return nil
+	}
+	err = keyProvider.SaveKeyRing(keyRing)
+	if err != nil {
+		return fmt.Errorf("failed to save keyring: %w", err)
+	}
+	return nil
+}
+
+// publishNextKey generates the key that replaces the active key at activeFrom, and publishes it in the JWKS
+func (j *KeyRotationJob) publishNextKey(ctx context.Context, keyProvider jwkutils.KeyProvider, keyRing jwk.Set, activeKey jwk.Key, activeFrom time.Time) error {
+	// Use the configured algorithm, or keep the one of the active key
+	alg := j.envConfig.KeyRotationAlg
+	if alg == "" {
+		activeAlg, ok := activeKey.Algorithm()
+		if !ok {
+			return errors.New("failed to retrieve algorithm of the active key")
+		}
+		alg = activeAlg.String()
+	}
+	var crv string
+	if alg == jwa.EdDSA().String() {
+		crv = jwa.Ed25519().String()
+	}
+
+	key, err := jwkutils.GenerateKey(alg, crv)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	err = jwkutils.SetNextKey(key, activeFrom)
+	if err != nil {
+		return err
+	}
+	err = keyRing.AddKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to add key to the keyring: %w", err)
+	}
+	err = keyProvider.SaveKeyRing(keyRing)
+	if err != nil {
+		return fmt.Errorf("failed to save keyring: %w", err)
+	}
+
+	keyID, _ := key.KeyID()
+	slog.InfoContext(ctx, "Published the next signing key", slog.String("kid", keyID), slog.Time("activeFrom", activeFrom))
+	j.createAuditLog(ctx, model.AuditLogEventSigningKeyPublished, model.AuditLogData{
+		"keyId":      keyID,
+		"alg":        alg,
+		"activeFrom": activeFrom.UTC().Format(time.RFC3339),
+	})
+
+	return nil
+}
+
+// activateNextKey makes the next key the active one, and retires the previous key until all tokens it signed have expired
+func (j *KeyRotationJob) activateNextKey(ctx context.Context, keyProvider jwkutils.KeyProvider, activeKey jwk.Key, nextKey jwk.Key) error {
+	retainFor, err := service.MaxTokenLifetime(ctx, j.db, j.appConfigService.GetDbConfig())
+	if err != nil {
+		return fmt.Errorf("failed to compute token lifetime: %w", err)
+	}
+
+	err = jwkutils.RotateKey(keyProvider, nextKey, retainFor)
+	if err != nil {
+		return fmt.Errorf("failed to rotate key: %w", err)
+	}
+
+	keyID, _ := nextKey.KeyID()
+	previousKeyID, _ := activeKey.KeyID()
+	slog.InfoContext(ctx, "Activated the next signing key", slog.String("kid", keyID), slog.String("previousKid", previousKeyID))
+	j.createAuditLog(ctx, model.AuditLogEventSigningKeyActivated, model.AuditLogData{
+		"keyId":                keyID,
+		"previousKeyId":        previousKeyID,
+		"previousKeyExpiresAt": time.Now().Add(retainFor).UTC().Format(time.RFC3339),
+	})
+
+	return nil
+}
+
+func (j *KeyRotationJob) createAuditLog(ctx context.Context, event model.AuditLogEvent, data model.AuditLogData) {
+	// Key rotations are not caused by a user or a request
+	j.auditLogService.Create(ctx, event, "", "", "", data, j.db)
+}
@@ -0,0 +1,231 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/service"
+	jwkutils "github.com/pocket-id/pocket-id/backend/internal/utils/jwk"
+	testutils "github.com/pocket-id/pocket-id/backend/internal/utils/testing"
+)
+
+func TestKeyRotationJob(t *testing.T) {
+	// The JWT service reads the global configuration
+	originalEnvConfig := common.EnvConfig
+	t.Cleanup(func() { common.EnvConfig = originalEnvConfig })
+	common.EnvConfig.KeysStorage = "file"
+	common.EnvConfig.KeysPath = t.TempDir()
+	common.EnvConfig.EncryptionKey = nil
+	common.EnvConfig.KeyRotationDays = 90
+	common.EnvConfig.KeyRotationAlg = "ES256"
+
+	db := testutils.NewDatabaseForTest(t)
+	appConfigService, err := service.NewAppConfigService(t.Context(), db)
+	require.NoError(t, err)
+	jwtService, err := service.NewJwtService(db, appConfigService)
+	require.NoError(t, err)
+
+	job := &KeyRotationJob{
+		db:               db,
+		envConfig:        &common.EnvConfig,
+		jwtService:       jwtService,
+		auditLogService:  &service.AuditLogService{},
+		appConfigService: appConfigService,
+	}
+
+	keyProvider, err := jwkutils.GetKeyProvider(db, &common.EnvConfig, appConfigService.GetDbConfig().InstanceID.Value)
+	require.NoError(t, err)
+	firstKey, err := keyProvider.LoadKey()
+	require.NoError(t, err)
+	firstKeyID, _ := firstKey.KeyID()
+
+	countAuditLogs := func(event model.AuditLogEvent) int64 {
+		var count int64
+		err := db.Model(&model.AuditLog{}).Where("event = ?", event).Count(&count).Error
+		require.NoError(t, err)
+		return count
+	}
+
+	// updateKeyRing changes the keys in the keyring, to simulate the passing of time
+	updateKeyRing := func(update func(key jwk.Key)) {
+		keyRing, err := keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		for i := range keyRing.Len() {
+			key, _ := keyRing.Key(i)
+			update(key)
+		}
+		require.NoError(t, keyProvider.SaveKeyRing(keyRing))
+	}
+
+	t.Run("starts the rotation interval for keys without activation time", func(t *testing.T) {
+		err := job.rotateSigningKey(t.Context())
+		require.NoError(t, err)
+
+		activeKey, err := keyProvider.LoadKey()
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), jwkutils.GetKeyActiveFrom(activeKey), time.Minute)
+
+		keyRing, err := keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		assert.Equal(t, 0, keyRing.Len(), "No key should be published before the end of the interval")
+	})
+
+	t.Run("publishes the next key before the end of the interval", func(t *testing.T) {
+		activeKey, err := keyProvider.LoadKey()
+		require.NoError(t, err)
+		require.NoError(t, jwkutils.SetKeyActiveFrom(activeKey, time.Now().Add(-89*24*time.Hour)))
+		require.NoError(t, keyProvider.SaveKey(activeKey))
+
+		err = job.rotateSigningKey(t.Context())
+		require.NoError(t, err)
+
+		keyRing, err := keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		nextKey := jwkutils.GetNextKey(keyRing)
+		require.NotNil(t, nextKey)
+		alg, _ := nextKey.Algorithm()
+		assert.Equal(t, "ES256", alg.String())
+		assert.WithinDuration(t, time.Now().Add(keyPrePublishDuration), jwkutils.GetKeyActiveFrom(nextKey), time.Minute)
+		assert.Equal(t, int64(1), countAuditLogs(model.AuditLogEventSigningKeyPublished))
+
+		// The next key is published, but the active key is still used
+		nextKeyID, _ := nextKey.KeyID()
+		jwks, err := jwtService.GetPublicJWKSAsJSON()
+		require.NoError(t, err)
+		assert.Contains(t, string(jwks), nextKeyID)
+		keyAlg, err := jwtService.GetKeyAlg()
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", keyAlg.String())
+
+		// Running the job again doesn't publish another key
+		err = job.rotateSigningKey(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), countAuditLogs(model.AuditLogEventSigningKeyPublished))
+	})
+
+	t.Run("activates the next key and retires the previous one", func(t *testing.T) {
+		updateKeyRing(func(key jwk.Key) {
+			require.NoError(t, jwkutils.SetNextKey(key, time.Now().Add(-time.Minute)))
+		})
+
+		err := job.rotateSigningKey(t.Context())
+		require.NoError(t, err)
+
+		keyAlg, err := jwtService.GetKeyAlg()
+		require.NoError(t, err)
+		assert.Equal(t, "ES256", keyAlg.String())
+		assert.Equal(t, int64(1), countAuditLogs(model.AuditLogEventSigningKeyActivated))
+
+		keyRing, err := keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		require.Equal(t, 1, keyRing.Len())
+		retiredKey, ok := keyRing.LookupKeyID(firstKeyID)
+		require.True(t, ok)
+		assert.Equal(t, jwkutils.KeyStatusRetired, jwkutils.GetKeyStatus(retiredKey))
+		assert.WithinDuration(t, time.Now().Add(service.RefreshTokenDuration), jwkutils.GetKeyExpiration(retiredKey), time.Minute)
+
+		jwks, err := jwtService.GetPublicJWKSAsJSON()
+		require.NoError(t, err)
+		assert.Contains(t, string(jwks), firstKeyID)
+	})
+
+	t.Run("removes the retired key after the grace period", func(t *testing.T) {
+		updateKeyRing(func(key jwk.Key) {
+			require.NoError(t, jwkutils.RetireKey(key, time.Now().Add(-time.Minute)))
+		})
+
+		err := job.rotateSigningKey(t.Context())
+		require.NoError(t, err)
+
+		keyRing, err := keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		assert.Equal(t, 0, keyRing.Len())
+		assert.Equal(t, int64(1), countAuditLogs(model.AuditLogEventSigningKeyRemoved))
+
+		jwks, err := jwtService.GetPublicJWKSAsJSON()
+		require.NoError(t, err)
+		assert.NotContains(t, string(jwks), firstKeyID)
+	})
+
+	t.Run("rotates the additional keys on the same schedule", func(t *testing.T) {
+		// The active key now uses ES256, so RS256 requires an additional key
+		require.NoError(t, jwtService.EnsureSigningKey("RS256"))
+		keyRing, err := keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		keys := jwkutils.GetAdditionalKeys(keyRing, "RS256")
+		require.Len(t, keys, 1)
+		firstAdditionalKeyID, _ := keys[0].KeyID()
+
+		signingKeyID := func() string {
+			tokenString, err := jwtService.GenerateOAuthAccessToken("user-id", "client-id", "", service.TokenConfirmation{}, time.Minute, "RS256")
+			require.NoError(t, err)
+			msg, err := jws.Parse([]byte(tokenString))
+			require.NoError(t, err)
+			keyID, _ := msg.Signatures()[0].ProtectedHeaders().KeyID()
+			return keyID
+		}
+		published := countAuditLogs(model.AuditLogEventSigningKeyPublished)
+		activated := countAuditLogs(model.AuditLogEventSigningKeyActivated)
+
+		// Before the end of the interval, the replacement is published but not used yet
+		updateKeyRing(func(key jwk.Key) {
+			if jwkutils.GetKeyStatus(key) == jwkutils.KeyStatusAdditional {
+				require.NoError(t, jwkutils.SetKeyActiveFrom(key, time.Now().Add(-89*24*time.Hour)))
+			}
+		})
+		err = job.rotateSigningKey(t.Context())
+		require.NoError(t, err)
+
+		keyRing, err = keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		keys = jwkutils.GetAdditionalKeys(keyRing, "RS256")
+		require.Len(t, keys, 2)
+		assert.Equal(t, published+1, countAuditLogs(model.AuditLogEventSigningKeyPublished))
+		assert.Equal(t, firstAdditionalKeyID, signingKeyID())
+
+		// Once the replacement is active, the previous key is retired
+		updateKeyRing(func(key jwk.Key) {
+			keyID, _ := key.KeyID()
+			if jwkutils.GetKeyStatus(key) == jwkutils.KeyStatusAdditional && keyID != firstAdditionalKeyID {
+				require.NoError(t, jwkutils.SetKeyActiveFrom(key, time.Now().Add(-time.Minute)))
+			}
+		})
+		err = job.rotateSigningKey(t.Context())
+		require.NoError(t, err)
+
+		keyRing, err = keyProvider.LoadKeyRing()
+		require.NoError(t, err)
+		previousKey, ok := keyRing.LookupKeyID(firstAdditionalKeyID)
+		require.True(t, ok)
+		assert.Equal(t, jwkutils.KeyStatusRetired, jwkutils.GetKeyStatus(previousKey))
+		assert.Equal(t, activated+1, countAuditLogs(model.AuditLogEventSigningKeyActivated))
+		assert.NotEqual(t, firstAdditionalKeyID, signingKeyID())
+	})
+	t.Run("reloads keys rotated outside of the service", func(t *testing.T) {
+		// Simulates a rotation with the CLI while the server is running
+		newKey, err := jwkutils.GenerateKey("ES256", "")
+		require.NoError(t, err)
+		newKeyID, _ := newKey.KeyID()
+		require.NoError(t, jwkutils.RotateKey(keyProvider, newKey, time.Hour))
+
+		publicKey, err := jwtService.GetPublicJWK()
+		require.NoError(t, err)
+		activeKeyID, _ := publicKey.KeyID()
+		assert.NotEqual(t, newKeyID, activeKeyID)
+
+		err = job.reloadSigningKeys(t.Context())
+		require.NoError(t, err)
+
+		publicKey, err = jwtService.GetPublicJWK()
+		require.NoError(t, err)
+		activeKeyID, _ = publicKey.KeyID()
+		assert.Equal(t, newKeyID, activeKeyID)
+	})
+}
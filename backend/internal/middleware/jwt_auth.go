@@ -69,5 +69,11 @@ func (m *JwtAuthMiddleware) Verify(c *gin.Context, adminRequired bool) (subject
 		return "", false, &common.MissingPermissionError{}
 	}
 
+	// The access token is issued when the user signs in, so its issue time is the authentication time
+	if issuedAt, ok := token.IssuedAt(); ok {
+		c.Set("authTime", issuedAt)
+	}
+	c.Set("authMethods", service.GetAuthenticationMethods(token))
+
 	return subject, isAdmin, nil
 }
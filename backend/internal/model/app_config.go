@@ -32,17 +32,27 @@ func (a *AppConfigVariable) AsDurationMinutes() time.Duration {
 	return time.Duration(val) * time.Minute
 }
 
+// AsDurationSeconds returns the value as a time.Duration, interpreting the string as a whole number of seconds.
+func (a *AppConfigVariable) AsDurationSeconds() time.Duration {
+	val, err := strconv.Atoi(a.Value)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(val) * time.Second
+}
+
 type AppConfig struct {
 	// General
-	AppName                   AppConfigVariable `key:"appName,public"` // Public
-	SessionDuration           AppConfigVariable `key:"sessionDuration"`
-	EmailsVerified            AppConfigVariable `key:"emailsVerified"`
-	AccentColor               AppConfigVariable `key:"accentColor,public"`         // Public
-	DisableAnimations         AppConfigVariable `key:"disableAnimations,public"`   // Public
-	AllowOwnAccountEdit       AppConfigVariable `key:"allowOwnAccountEdit,public"` // Public
-	AllowUserSignups          AppConfigVariable `key:"allowUserSignups,public"`    // Public
-	SignupDefaultUserGroupIDs AppConfigVariable `key:"signupDefaultUserGroupIDs"`
-	SignupDefaultCustomClaims AppConfigVariable `key:"signupDefaultCustomClaims"`
+	AppName                      AppConfigVariable `key:"appName,public"` // Public
+	SessionDuration              AppConfigVariable `key:"sessionDuration"`
+	EmailsVerified               AppConfigVariable `key:"emailsVerified"`
+	AccentColor                  AppConfigVariable `key:"accentColor,public"`         // Public
+	DisableAnimations            AppConfigVariable `key:"disableAnimations,public"`   // Public
+	AllowOwnAccountEdit          AppConfigVariable `key:"allowOwnAccountEdit,public"` // Public
+	AllowUserSignups             AppConfigVariable `key:"allowUserSignups,public"`    // Public
+	SignupDefaultUserGroupIDs    AppConfigVariable `key:"signupDefaultUserGroupIDs"`
+	SignupDefaultCustomClaims    AppConfigVariable `key:"signupDefaultCustomClaims"`
+	RefreshTokenReuseGracePeriod AppConfigVariable `key:"refreshTokenReuseGracePeriod"`
 	// Internal
 	InstanceID AppConfigVariable `key:"instanceId,internal"` // Internal
 	// Email
@@ -58,6 +68,7 @@ type AppConfig struct {
 	EmailOneTimeAccessAsUnauthenticatedEnabled AppConfigVariable `key:"emailOneTimeAccessAsUnauthenticatedEnabled,public"` // Public
 	EmailOneTimeAccessAsAdminEnabled           AppConfigVariable `key:"emailOneTimeAccessAsAdminEnabled,public"`           // Public
 	EmailApiKeyExpirationEnabled               AppConfigVariable `key:"emailApiKeyExpirationEnabled"`
+	EmailRefreshTokenReuseNotificationEnabled  AppConfigVariable `key:"emailRefreshTokenReuseNotificationEnabled"`
 	// LDAP
 	LdapEnabled                        AppConfigVariable `key:"ldapEnabled,public"` // Public
 	LdapUrl                            AppConfigVariable `key:"ldapUrl"`
@@ -26,13 +26,20 @@ type AuditLogData map[string]string //nolint:recvcheck
 type AuditLogEvent string //nolint:recvcheck
 
 const (
-	AuditLogEventSignIn                     AuditLogEvent = "SIGN_IN"
-	AuditLogEventOneTimeAccessTokenSignIn   AuditLogEvent = "TOKEN_SIGN_IN"
-	AuditLogEventAccountCreated             AuditLogEvent = "ACCOUNT_CREATED"
-	AuditLogEventClientAuthorization        AuditLogEvent = "CLIENT_AUTHORIZATION"
-	AuditLogEventNewClientAuthorization     AuditLogEvent = "NEW_CLIENT_AUTHORIZATION"
-	AuditLogEventDeviceCodeAuthorization    AuditLogEvent = "DEVICE_CODE_AUTHORIZATION"
-	AuditLogEventNewDeviceCodeAuthorization AuditLogEvent = "NEW_DEVICE_CODE_AUTHORIZATION"
+	AuditLogEventSignIn                      AuditLogEvent = "SIGN_IN"
+	AuditLogEventOneTimeAccessTokenSignIn    AuditLogEvent = "TOKEN_SIGN_IN"
+	AuditLogEventAccountCreated              AuditLogEvent = "ACCOUNT_CREATED"
+	AuditLogEventClientAuthorization         AuditLogEvent = "CLIENT_AUTHORIZATION"
+	AuditLogEventNewClientAuthorization      AuditLogEvent = "NEW_CLIENT_AUTHORIZATION"
+	AuditLogEventDeviceCodeAuthorization     AuditLogEvent = "DEVICE_CODE_AUTHORIZATION"
+	AuditLogEventNewDeviceCodeAuthorization  AuditLogEvent = "NEW_DEVICE_CODE_AUTHORIZATION"
+	AuditLogEventBackchannelLogout           AuditLogEvent = "BACKCHANNEL_LOGOUT"
+	AuditLogEventBackchannelAuthorization    AuditLogEvent = "BACKCHANNEL_AUTHORIZATION"
+	AuditLogEventNewBackchannelAuthorization AuditLogEvent = "NEW_BACKCHANNEL_AUTHORIZATION"
+	AuditLogEventRefreshTokenReuse           AuditLogEvent = "REFRESH_TOKEN_REUSE"
+	AuditLogEventSigningKeyPublished         AuditLogEvent = "SIGNING_KEY_PUBLISHED"
+	AuditLogEventSigningKeyActivated         AuditLogEvent = "SIGNING_KEY_ACTIVATED"
+	AuditLogEventSigningKeyRemoved           AuditLogEvent = "SIGNING_KEY_REMOVED"
 )
 
 // Scan and Value methods for GORM to handle the custom type
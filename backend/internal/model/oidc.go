@@ -4,13 +4,19 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
 	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
 )
 
 type UserAuthorizedOidcClient struct {
-	Scope      string
+	Scope string
+	// Claims contains the individual claims the user consented to, across all authorizations
+	// The ones requested for the userinfo endpoint are also released there
+	Claims     *OidcClaimsRequest
 	LastUsedAt datatype.DateTime `sortable:"true"`
 
 	UserID string `gorm:"primary_key;"`
@@ -33,9 +39,12 @@ type OidcAuthorizationCode struct {
 
 	Code                      string
 	Scope                     string
+	Claims                    *OidcClaimsRequest
 	Nonce                     string
 	CodeChallenge             *string
 	CodeChallengeMethodSha256 *bool
+	AuthTime                  *datatype.DateTime
+	AuthenticationMethods     string
 	ExpiresAt                 datatype.DateTime
 
 	UserID string
@@ -47,16 +56,50 @@ type OidcAuthorizationCode struct {
 type OidcClient struct {
 	Base
 
-	Name                     string `sortable:"true"`
-	Secret                   string
-	CallbackURLs             UrlList
-	LogoutCallbackURLs       UrlList
-	ImageType                *string
-	IsPublic                 bool
-	PkceEnabled              bool
-	RequiresReauthentication bool
-	Credentials              OidcClientCredentials
-	LaunchURL                *string
+	Name                                string `sortable:"true"`
+	Secret                              string
+	CallbackURLs                        UrlList
+	LogoutCallbackURLs                  UrlList
+	ImageType                           *string
+	IsPublic                            bool
+	PkceEnabled                         bool
+	RequiresReauthentication            bool
+	RequiresPushedAuthorizationRequests bool
+	RequiresSignedRequestObject         bool
+	JwksURL                             *string
+	Jwks                                *string
+	RegistrationAccessToken             *string
+	BackchannelLogoutURL                *string
+	FrontchannelLogoutURL               *string
+	FrontchannelLogoutSessionRequired   bool
+	RequiresDpop                        bool
+	MinimumAcr                          *string
+	TokenExchangeAudiences              UrlList
+	RefreshTokensDisabled               bool
+	RefreshTokensWithoutOfflineAccess   bool
+	BackchannelAuthenticationEnabled    bool
+	SubjectType                         string
+	SectorIdentifierURI                 *string
+	Credentials                         OidcClientCredentials
+	LaunchURL                           *string
+
+	// Lifetimes of the tokens issued to the client, in seconds; if nil, the defaults are used
+	AccessTokenLifetime          *int
+	IdTokenLifetime              *int
+	RefreshTokenIdleLifetime     *int
+	RefreshTokenAbsoluteLifetime *int
+
+	// Algorithms used to encrypt the ID tokens and userinfo responses with a key of the client; if nil, they aren't encrypted
+	IdTokenEncryptedResponseAlg  *string
+	IdTokenEncryptedResponseEnc  *string
+	UserinfoEncryptedResponseAlg *string
+	UserinfoEncryptedResponseEnc *string
+
+	// Algorithm used to sign userinfo responses; if nil, userinfo responses are plain JSON unless they're encrypted
+	UserinfoSignedResponseAlg *string
+
+	// Algorithm used to sign ID tokens, access tokens, and logout tokens; if nil, the algorithm of the active key is used
+	IdTokenSignedResponseAlg *string
 
 	AllowedUserGroups         []UserGroup `gorm:"many2many:oidc_clients_allowed_user_groups;"`
 	CreatedByID               *string
@@ -64,6 +107,13 @@ type OidcClient struct {
 	UserAuthorizedOidcClients []UserAuthorizedOidcClient `gorm:"foreignKey:ClientID;references:ID"`
 }
 
+const (
+	// OidcSubjectTypePublic means the client receives the user ID as the subject identifier
+	OidcSubjectTypePublic = "public"
+	// OidcSubjectTypePairwise means the client receives a subject identifier that is unique to its sector
+	OidcSubjectTypePairwise = "pairwise"
+)
+
 func (c OidcClient) HasLogo() bool {
 	return c.ImageType != nil && *c.ImageType != ""
 }
@@ -71,9 +121,19 @@ func (c OidcClient) HasLogo() bool {
 type OidcRefreshToken struct {
 	Base
 
-	Token     string
-	ExpiresAt datatype.DateTime
-	Scope     string
+	Token                 string
+	ExpiresAt             datatype.DateTime
+	AbsoluteExpiresAt     *datatype.DateTime
+	Scope                 string
+	Claims                *OidcClaimsRequest
+	AuthTime              *datatype.DateTime
+	AuthenticationMethods string
+	DpopJkt               *string
+
+	// All refresh tokens obtained by rotating the same original token share a family ID
+	// Rotated tokens are kept until they expire, so their reuse can be detected
+	FamilyID  string
+	RotatedAt *datatype.DateTime
 
 	UserID string
 	User   User
@@ -92,6 +152,10 @@ func (c OidcRefreshToken) Scopes() []string {
 
 type OidcClientCredentials struct { //nolint:recvcheck
 	FederatedIdentities []OidcClientFederatedIdentity `json:"federatedIdentities,omitempty"`
+	// Subject DN of the client certificate, for the "tls_client_auth" method
+	TLSClientAuthSubjectDN string `json:"tlsClientAuthSubjectDn,omitempty"`
+	// JWK thumbprints of the self-signed client certificates, for the "self_signed_tls_client_auth" method
+	TLSClientAuthThumbprints []string `json:"tlsClientAuthThumbprints,omitempty"`
 }
 
 type OidcClientFederatedIdentity struct {
@@ -99,20 +163,32 @@ type OidcClientFederatedIdentity struct {
 	Subject  string `json:"subject,omitempty"`
 	Audience string `json:"audience,omitempty"`
 	JWKS     string `json:"jwks,omitempty"` // URL of the JWKS
+	// Claims that the tokens must contain, with their exact values
+	Claims map[string]string `json:"claims,omitempty"`
+	// ID of the user that tokens used as authorization grants are mapped to
+	// If empty, they are mapped to the client's service account
+	UserID string `json:"userId,omitempty"`
 }
 
-func (occ OidcClientCredentials) FederatedIdentityForIssuer(issuer string) (OidcClientFederatedIdentity, bool) {
+// FederatedIdentitiesForIssuer returns the federated identities that accept tokens from the issuer
+func (occ OidcClientCredentials) FederatedIdentitiesForIssuer(issuer string) []OidcClientFederatedIdentity {
 	if issuer == "" {
-		return OidcClientFederatedIdentity{}, false
+		return nil
 	}
 
+	var res []OidcClientFederatedIdentity
 	for _, fi := range occ.FederatedIdentities {
 		if fi.Issuer == issuer {
-			return fi, true
+			res = append(res, fi)
 		}
 	}
 
-	return OidcClientFederatedIdentity{}, false
+	return res
+}
+
+// HasTLSClientAuth returns true if the client can authenticate with a TLS client certificate
+func (occ OidcClientCredentials) HasTLSClientAuth() bool {
+	return occ.TLSClientAuthSubjectDN != "" || len(occ.TLSClientAuthThumbprints) > 0
 }
 
 func (occ *OidcClientCredentials) Scan(value any) error {
@@ -155,8 +231,194 @@ type OidcDeviceCode struct {
 	ExpiresAt    datatype.DateTime
 	IsAuthorized bool
 
+	// How the user signed in when approving the device
+	AuthTime              *datatype.DateTime
+	AuthenticationMethods string
+
 	UserID   *string
 	User     User
 	ClientID string
 	Client   OidcClient
 }
+
+// OidcBackchannelAuthenticationRequest is an authentication request started by a client with CIBA
+// The user approves it from their Pocket ID session, while the client polls the token endpoint
+type OidcBackchannelAuthenticationRequest struct {
+	Base
+	AuthReqID             string
+	Scope                 string
+	BindingMessage        string
+	ExpiresAt             datatype.DateTime
+	IsAuthorized          bool
+	IsDenied              bool
+	AuthTime              *datatype.DateTime
+	AuthenticationMethods string
+
+	UserID   string
+	User     User
+	ClientID string
+	Client   OidcClient
+}
+
+type OidcPushedAuthorizationRequest struct {
+	Base
+	RequestURI string
+	Parameters OidcAuthorizationRequestParameters
+	ExpiresAt  datatype.DateTime
+
+	ClientID string
+}
+
+type OidcDpopProof struct {
+	Base
+
+	JwtIDHash string
+	ExpiresAt datatype.DateTime
+}
+
+type OidcUsedAssertion struct {
+	Base
+
+	JwtIDHash string
+	ExpiresAt datatype.DateTime
+}
+
+// OidcPairwiseSubject maps a pairwise subject identifier back to the user it was derived for
+type OidcPairwiseSubject struct {
+	Base
+
+	Subject string
+	UserID  string
+}
+
+type OidcInitialAccessToken struct {
+	Base
+
+	Token      string            `json:"-"` // Hashed token
+	ExpiresAt  datatype.DateTime `json:"expiresAt" sortable:"true"`
+	UsageLimit int               `json:"usageLimit" sortable:"true"`
+	UsageCount int               `json:"usageCount" sortable:"true"`
+
+	CreatedByID *string
+}
+
+func (t *OidcInitialAccessToken) IsExpired() bool {
+	return time.Time(t.ExpiresAt).Before(time.Now())
+}
+
+func (t *OidcInitialAccessToken) IsUsageLimitReached() bool {
+	return t.UsageCount >= t.UsageLimit
+}
+
+func (t *OidcInitialAccessToken) IsValid() bool {
+	return !t.IsExpired() && !t.IsUsageLimitReached()
+}
+
+type OidcAuthorizationRequestParameters struct { //nolint:recvcheck
+	Scope               string `json:"scope"`
+	CallbackURL         string `json:"redirect_uri,omitempty"`
+	Nonce               string `json:"nonce,omitempty"`
+	State               string `json:"state,omitempty"`
+	CodeChallenge       string `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
+	Prompt              string `json:"prompt,omitempty"`
+	MaxAge              *int   `json:"max_age,omitempty"`
+	AcrValues           string `json:"acr_values,omitempty"`
+	Claims              string `json:"claims,omitempty"`
+	ResponseMode        string `json:"response_mode,omitempty"`
+}
+
+func (p *OidcAuthorizationRequestParameters) Scan(value any) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+}
+
+func (p OidcAuthorizationRequestParameters) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// OidcClaimsRequest contains the individual claims requested with the "claims" authorization request parameter
+// The values of the requested claims aren't enforced, so only the names of the claims are used
+type OidcClaimsRequest struct { //nolint:recvcheck
+	Userinfo map[string]*OidcClaimRequest `json:"userinfo,omitempty"`
+	IDToken  map[string]*OidcClaimRequest `json:"id_token,omitempty"`
+}
+
+type OidcClaimRequest struct {
+	Essential bool  `json:"essential,omitempty"`
+	Value     any   `json:"value,omitempty"`
+	Values    []any `json:"values,omitempty"`
+}
+
+// UserinfoClaimNames returns the names of the claims requested for the userinfo endpoint
+func (r *OidcClaimsRequest) UserinfoClaimNames() []string {
+	if r == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(r.Userinfo))
+}
+
+// IDTokenClaimNames returns the names of the claims requested for the ID token
+func (r *OidcClaimsRequest) IDTokenClaimNames() []string {
+	if r == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(r.IDToken))
+}
+
+// ClaimNames returns the names of all requested claims, for the ID token or the userinfo endpoint
+func (r *OidcClaimsRequest) ClaimNames() []string {
+	if r == nil {
+		return nil
+	}
+	names := slices.Concat(r.IDTokenClaimNames(), r.UserinfoClaimNames())
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Merge returns the claims requested in both requests, with the ones in other taking precedence
+func (r *OidcClaimsRequest) Merge(other *OidcClaimsRequest) *OidcClaimsRequest {
+	if r == nil {
+		return other
+	}
+	if other == nil {
+		return r
+	}
+	return &OidcClaimsRequest{
+		Userinfo: mergeClaimRequests(r.Userinfo, other.Userinfo),
+		IDToken:  mergeClaimRequests(r.IDToken, other.IDToken),
+	}
+}
+
+func mergeClaimRequests(a, b map[string]*OidcClaimRequest) map[string]*OidcClaimRequest {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	merged := make(map[string]*OidcClaimRequest, len(a)+len(b))
+	maps.Copy(merged, a)
+	maps.Copy(merged, b)
+	return merged
+}
+
+func (r *OidcClaimsRequest) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+}
+
+func (r OidcClaimsRequest) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// OidcScope is a scope defined by an admin, which releases a set of claims
+type OidcScope struct {
+	Base
+
+	Name        string `sortable:"true"`
+	Description string
+	// Claims contains the names of the built-in claims and the keys of the custom claims that the scope releases
+	Claims StringList
+
+	// If there are no allowed clients, all clients can request the scope
+	AllowedClients []OidcClient `gorm:"many2many:oidc_scopes_allowed_clients;"`
+}
+
+// IsAllowedForClient returns true if the client can request the scope
+func (s OidcScope) IsAllowedForClient(clientID string) bool {
+	if len(s.AllowedClients) == 0 {
+		return true
+	}
+
+	for _, client := range s.AllowedClients {
+		if client.ID == clientID {
+			return true
+		}
+	}
+
+	return false
+}
+
+type StringList []string //nolint:recvcheck
+
+func (sl *StringList) Scan(value any) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, sl)
+	case string:
+		return json.Unmarshal([]byte(v), sl)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+}
+
+func (sl StringList) Value() (driver.Value, error) {
+	return json.Marshal(sl)
+}
@@ -50,6 +50,9 @@ type ReauthenticationToken struct {
 	Token     string
 	ExpiresAt datatype.DateTime
 
+	// The methods the user reauthenticated with
+	AuthenticationMethods string
+
 	UserID string
 	User   User
 }
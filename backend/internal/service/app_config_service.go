@@ -59,15 +59,16 @@ func (s *AppConfigService) getDefaultDbConfig() *model.AppConfig {
 	// Values are the default ones
 	return &model.AppConfig{
 		// General
-		AppName:                   model.AppConfigVariable{Value: "Pocket ID"},
-		SessionDuration:           model.AppConfigVariable{Value: "60"},
-		EmailsVerified:            model.AppConfigVariable{Value: "false"},
-		DisableAnimations:         model.AppConfigVariable{Value: "false"},
-		AllowOwnAccountEdit:       model.AppConfigVariable{Value: "true"},
-		AllowUserSignups:          model.AppConfigVariable{Value: "disabled"},
-		SignupDefaultUserGroupIDs: model.AppConfigVariable{Value: "[]"},
-		SignupDefaultCustomClaims: model.AppConfigVariable{Value: "[]"},
-		AccentColor:               model.AppConfigVariable{Value: "default"},
+		AppName:                      model.AppConfigVariable{Value: "Pocket ID"},
+		SessionDuration:              model.AppConfigVariable{Value: "60"},
+		EmailsVerified:               model.AppConfigVariable{Value: "false"},
+		DisableAnimations:            model.AppConfigVariable{Value: "false"},
+		AllowOwnAccountEdit:          model.AppConfigVariable{Value: "true"},
+		AllowUserSignups:             model.AppConfigVariable{Value: "disabled"},
+		SignupDefaultUserGroupIDs:    model.AppConfigVariable{Value: "[]"},
+		SignupDefaultCustomClaims:    model.AppConfigVariable{Value: "[]"},
+		RefreshTokenReuseGracePeriod: model.AppConfigVariable{Value: "10"},
+		AccentColor:                  model.AppConfigVariable{Value: "default"},
 		// Internal
 		InstanceID: model.AppConfigVariable{Value: ""},
 		// Email
@@ -83,6 +84,7 @@ func (s *AppConfigService) getDefaultDbConfig() *model.AppConfig {
 		EmailOneTimeAccessAsUnauthenticatedEnabled: model.AppConfigVariable{Value: "false"},
 		EmailOneTimeAccessAsAdminEnabled:           model.AppConfigVariable{Value: "false"},
 		EmailApiKeyExpirationEnabled:               model.AppConfigVariable{Value: "false"},
+		EmailRefreshTokenReuseNotificationEnabled:  model.AppConfigVariable{Value: "false"},
 		// LDAP
 		LdapEnabled:                        model.AppConfigVariable{Value: "false"},
 		LdapUrl:                            model.AppConfigVariable{},
@@ -53,8 +53,12 @@ func (s *AuditLogService) Create(ctx context.Context, event model.AuditLogEvent,
 	}
 
 	// Save the audit log in the database
-	err = tx.
-		WithContext(ctx).
+	// Events that are not caused by a user, such as automatic key rotations, are stored without one
+	query := tx.WithContext(ctx)
+	if userID == "" {
+		query = query.Omit("UserID")
+	}
+	err = query.
 		Create(&auditLog).
 		Error
 	if err != nil {
@@ -135,6 +139,54 @@ func (s *AuditLogService) CreateNewSignInWithEmail(ctx context.Context, ipAddres
 	return createdAuditLog
 }
 
+// CreateRefreshTokenReuseWithEmail records that a rotated refresh token was used again, and notifies the user if enabled
+func (s *AuditLogService) CreateRefreshTokenReuseWithEmail(ctx context.Context, ipAddress, userAgent, userID string, client model.OidcClient, tx *gorm.DB) model.AuditLog {
+	createdAuditLog, ok := s.Create(ctx, model.AuditLogEventRefreshTokenReuse, ipAddress, userAgent, userID, model.AuditLogData{"clientName": client.Name}, tx)
+	if !ok {
+		// At this point the transaction has been canceled already, and error has been logged
+		return createdAuditLog
+	}
+
+	if s.appConfigService.GetDbConfig().EmailRefreshTokenReuseNotificationEnabled.IsTrue() {
+		// We use a background context here as this is running in a goroutine
+		//nolint:contextcheck
+		go func() {
+			span := trace.SpanFromContext(ctx)
+			innerCtx := trace.ContextWithSpan(context.Background(), span)
+
+			// Note we don't use the transaction here because this is running in background
+			var user model.User
+			innerErr := s.db.
+				WithContext(innerCtx).
+				Where("id = ?", userID).
+				First(&user).
+				Error
+			if innerErr != nil {
+				slog.ErrorContext(innerCtx, "Failed to load user from database to send notification email", slog.Any("error", innerErr))
+				return
+			}
+
+			if user.Email == nil {
+				return
+			}
+
+			innerErr = SendEmail(innerCtx, s.emailService, email.Address{
+				Name:  user.FullName(),
+				Email: *user.Email,
+			}, RefreshTokenReuseTemplate, &RefreshTokenReuseTemplateData{
+				ClientName: client.Name,
+				DateTime:   createdAuditLog.CreatedAt.UTC(),
+			})
+			if innerErr != nil {
+				slog.ErrorContext(innerCtx, "Failed to send notification email", slog.Any("error", innerErr), slog.String("address", *user.Email))
+				return
+			}
+		}()
+	}
+
+	return createdAuditLog
+}
+
 // ListAuditLogsForUser retrieves all audit logs for a given user ID
 func (s *AuditLogService) ListAuditLogsForUser(ctx context.Context, userID string, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.AuditLog, utils.PaginationResponse, error) {
 	var logs []model.AuditLog
@@ -547,7 +547,7 @@ func (s *TestService) SetLdapTestConfig(ctx context.Context) error {
 }
 
 func (s *TestService) SignRefreshToken(userID, clientID, refreshToken string) (string, error) {
-	return s.jwtService.GenerateOAuthRefreshToken(userID, clientID, refreshToken)
+	return s.jwtService.GenerateOAuthRefreshToken(userID, clientID, refreshToken, RefreshTokenDuration)
 }
 
 // GetExternalIdPJWKS returns the JWKS for the "external IdP".
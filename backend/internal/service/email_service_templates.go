@@ -49,6 +49,13 @@ var ApiKeyExpiringSoonTemplate = email.Template[ApiKeyExpiringSoonTemplateData]{
 	},
 }
 
+var RefreshTokenReuseTemplate = email.Template[RefreshTokenReuseTemplateData]{
+	Path: "refresh-token-reuse",
+	Title: func(data *email.TemplateData[RefreshTokenReuseTemplateData]) string {
+		return fmt.Sprintf("Session with %s revoked", data.Data.ClientName)
+	},
+}
+
 type NewLoginTemplateData struct {
 	IPAddress string
 	Country   string
@@ -70,5 +77,10 @@ type ApiKeyExpiringSoonTemplateData struct {
 	ExpiresAt  time.Time
 }
 
+type RefreshTokenReuseTemplateData struct {
+	ClientName string
+	DateTime   time.Time
+}
+
 // this is list of all template paths used for preloading templates
-var emailTemplatesPaths = []string{NewLoginTemplate.Path, OneTimeAccessTemplate.Path, TestTemplate.Path, ApiKeyExpiringSoonTemplate.Path}
+var emailTemplatesPaths = []string{NewLoginTemplate.Path, OneTimeAccessTemplate.Path, TestTemplate.Path, ApiKeyExpiringSoonTemplate.Path, RefreshTokenReuseTemplate.Path}
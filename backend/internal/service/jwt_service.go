@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwe"
 	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
 	"gorm.io/gorm"
 
@@ -39,6 +44,30 @@ const (
 	// RefreshTokenClaim is the claim used for the refresh token's value
 	RefreshTokenClaim = "rt"
 
+	// AuthTimeClaim is the claim containing the time at which the user authenticated
+	AuthTimeClaim = "auth_time"
+
+	// AuthenticationMethodsClaim is the claim containing the methods used to authenticate the user, as described in RFC 8176
+	AuthenticationMethodsClaim = "amr"
+
+	// AuthenticationContextClassClaim is the claim containing the authentication context class the authentication satisfies
+	AuthenticationContextClassClaim = "acr"
+
+	// AcrOneTimeCode is the authentication context class of users who signed in with a one-time code
+	AcrOneTimeCode = "urn:pocket-id:acr:one-time-code"
+
+	// AcrPasskey is the authentication context class of users who signed in with a passkey
+	AcrPasskey = "urn:pocket-id:acr:passkey"
+
+	// ConfirmationClaim is the claim containing the key a token is bound to, as described in RFC 7800
+	ConfirmationClaim = "cnf"
+
+	// ActorClaim is the claim identifying the party acting on behalf of the subject, as described in RFC 8693
+	ActorClaim = "act"
+
+	// ScopeClaim is the claim containing the scopes an access token is restricted to
+	ScopeClaim = "scope"
+
 	// OAuthAccessTokenJWTType identifies a JWT as an OAuth access token
 	OAuthAccessTokenJWTType = "oauth-access-token" //nolint:gosec
 
@@ -51,16 +80,32 @@ const (
 	// IDTokenJWTType identifies a JWT as an ID token used by Pocket ID
 	IDTokenJWTType = "id-token"
 
+	// LogoutTokenJWTType identifies a JWT as an OIDC back-channel logout token
+	LogoutTokenJWTType = "logout-token" //nolint:gosec
+
+	// BackchannelLogoutEvent is the event member of the "events" claim in logout tokens
+	BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
 	// Acceptable clock skew for verifying tokens
 	clockSkew = time.Minute
 )
 
 type JwtService struct {
 	envConfig        *common.EnvConfigSchema
-	privateKey       jwk.Key
-	keyId            string
 	appConfigService *AppConfigService
-	jwksEncoded      []byte
+	keyProvider      jwkutils.KeyProvider
+
+	// keysLock protects the keys below, which can be replaced while the service is running
+	keysLock    sync.RWMutex
+	privateKey  jwk.Key
+	keyId       string
+	keyRing     jwk.Set
+	signingKeys map[string][]jwk.Key
+	publicKeys  jwk.Set
+	jwksEncoded []byte
+
+	// keyGenerationLock ensures that only one additional signing key is generated at a time
+	keyGenerationLock sync.Mutex
 }
 
 func NewJwtService(db *gorm.DB, appConfigService *AppConfigService) (*JwtService, error) {
@@ -89,19 +134,16 @@ func (s *JwtService) loadOrGenerateKey(db *gorm.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to get key provider: %w", err)
 	}
+	s.keyProvider = keyProvider
 
-	// Try loading a key
-	key, err := keyProvider.LoadKey()
+	// Try loading the key and the keyring
+	loaded, err := s.loadKeys()
 	if err != nil {
-		return fmt.Errorf("failed to load key (provider type '%s'): %w", s.envConfig.KeysStorage, err)
+		return err
 	}
 
-	// If we have a key, store it in the object and we're done
-	if key != nil {
-		err = s.SetKey(key)
-		if err != nil {
-			return fmt.Errorf("failed to set private key: %w", err)
-		}
+	// If we have a key, we're done
+	if loaded {
 		return nil
 	}
 
@@ -120,6 +162,52 @@ func (s *JwtService) loadOrGenerateKey(db *gorm.DB) error {
 	return nil
 }
 
+// ReloadKeys loads the active key and the keyring from the key provider again
+// This is used to pick up keys that were rotated while the service is running
+func (s *JwtService) ReloadKeys() error {
+	loaded, err := s.loadKeys()
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		return errors.New("no key found")
+	}
+	return nil
+}
+
+// loadKeys loads the active key and the keyring from the key provider, and stores them in the object
+// It returns false if there's no key stored yet
+func (s *JwtService) loadKeys() (bool, error) {
+	key, err := s.keyProvider.LoadKey()
+	if err != nil {
+		return false, fmt.Errorf("failed to load key (provider type '%s'): %w", s.envConfig.KeysStorage, err)
+	}
+
+	// Load the keyring, with the keys besides the active one that are still valid
+	keyRing, err := s.keyProvider.LoadKeyRing()
+	if err != nil {
+		return false, fmt.Errorf("failed to load keyring (provider type '%s'): %w", s.envConfig.KeysStorage, err)
+	}
+	keyRing, err = jwkutils.RemoveExpiredKeys(keyRing, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to remove expired keys from keyring: %w", err)
+	}
+
+	s.keysLock.Lock()
+	defer s.keysLock.Unlock()
+
+	s.keyRing = keyRing
+	if key == nil {
+		return false, nil
+	}
+
+	err = s.setKey(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to set private key: %w", err)
+	}
+	return true, nil
+}
+
 // generateKey generates a new key and stores it in the object
 func (s *JwtService) generateKey() error {
 	// Default is to generate RS256 (RSA-2048) keys
@@ -160,6 +248,13 @@ func ValidateKey(privateKey jwk.Key) error {
 }
 
 func (s *JwtService) SetKey(privateKey jwk.Key) error {
+	s.keysLock.Lock()
+	defer s.keysLock.Unlock()
+
+	return s.setKey(privateKey)
+}
+
+func (s *JwtService) setKey(privateKey jwk.Key) error {
 	// Validate the loaded key
 	err := ValidateKey(privateKey)
 	if err != nil {
@@ -175,8 +270,27 @@ func (s *JwtService) SetKey(privateKey jwk.Key) error {
 	}
 	s.keyId = keyId
 
-	// Create and encode a JWKS containing the public key
-	publicKey, err := s.GetPublicJWK()
+	return s.updatePublicKeys()
+}
+
+// SetKeyRing sets the keys that are kept besides the active key, such as retired keys
+// All keys in the keyring are published in the JWKS and can be used to verify tokens, but only additional keys are used for signing
+func (s *JwtService) SetKeyRing(keyRing jwk.Set) error {
+	s.keysLock.Lock()
+	defer s.keysLock.Unlock()
+
+	s.keyRing = keyRing
+	if s.privateKey == nil {
+		return nil
+	}
+	return s.updatePublicKeys()
+}
+
+// updatePublicKeys builds the set of public keys from the active key and the keyring, and caches the encoded JWKS
+// It also collects the additional keys that are used for signing with other algorithms than the one of the active key
+// The caller must hold the write lock
+func (s *JwtService) updatePublicKeys() error {
+	publicKey, err := s.getPublicJWK()
 	if err != nil {
 		return fmt.Errorf("failed to get public JWK: %w", err)
 	}
@@ -185,6 +299,36 @@ func (s *JwtService) SetKey(privateKey jwk.Key) error {
 	if err != nil {
 		return fmt.Errorf("failed to add public key to JWKS: %w", err)
 	}
+
+	signingKeys := make(map[string][]jwk.Key)
+	if s.keyRing != nil {
+		for i := range s.keyRing.Len() {
+			key, _ := s.keyRing.Key(i)
+			keyId, _ := key.KeyID()
+			if keyId == s.keyId {
+				continue
+			}
+
+			if jwkutils.GetKeyStatus(key) == jwkutils.KeyStatusAdditional {
+				alg, ok := key.Algorithm()
+				if ok {
+					signingKeys[alg.String()] = append(signingKeys[alg.String()], key)
+				}
+			}
+
+			publicKey, err = jwkutils.PublicKeyForJWKS(key)
+			if err != nil {
+				return fmt.Errorf("failed to get public JWK of key '%s' in keyring: %w", keyId, err)
+			}
+			err = jwks.AddKey(publicKey)
+			if err != nil {
+				return fmt.Errorf("failed to add public key '%s' to JWKS: %w", keyId, err)
+			}
+		}
+	}
+
+	s.signingKeys = signingKeys
+	s.publicKeys = jwks
 	s.jwksEncoded, err = json.Marshal(jwks)
 	if err != nil {
 		return fmt.Errorf("failed to encode JWKS to JSON: %w", err)
@@ -193,7 +337,102 @@ func (s *JwtService) SetKey(privateKey jwk.Key) error {
 	return nil
 }
 
-func (s *JwtService) GenerateAccessToken(user model.User) (string, error) {
+// verificationKeys returns the option to verify the signature of tokens with any of the published keys
+// The key is selected with the "kid" header, which is always set in tokens signed by Pocket ID
+func (s *JwtService) verificationKeys() jwt.ParseOption {
+	s.keysLock.RLock()
+	defer s.keysLock.RUnlock()
+
+	return jwt.WithKeySet(s.publicKeys, jws.WithInferAlgorithmFromKey(true))
+}
+
+// signToken signs the token with the key for the given algorithm
+// If alg is empty, the token is signed with the active key
+func (s *JwtService) signToken(token jwt.Token, alg string, suboptions ...jwt.Option) ([]byte, error) {
+	privateKey, err := s.signingKey(alg)
+	if err != nil && alg != "" {
+		// The key for the algorithm may have been generated by another instance
+		reloadErr := s.ReloadKeys()
+		if reloadErr != nil {
+			return nil, fmt.Errorf("failed to reload keys: %w", reloadErr)
+		}
+		privateKey, err = s.signingKey(alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyAlg, _ := privateKey.Algorithm()
+	return jwt.Sign(token, jwt.WithKey(keyAlg, privateKey, suboptions...))
+}
+
+// signingKey returns the key used to sign tokens with the given algorithm
+// If alg is empty, or it's the algorithm of the active key, the active key is returned
+func (s *JwtService) signingKey(alg string) (jwk.Key, error) {
+	s.keysLock.RLock()
+	defer s.keysLock.RUnlock()
+
+	if s.privateKey == nil {
+		return nil, errors.New("key is not initialized")
+	}
+
+	activeAlg, _ := s.privateKey.Algorithm()
+	if alg == "" || (activeAlg != nil && activeAlg.String() == alg) {
+		return s.privateKey, nil
+	}
+
+	// Additional keys that were rotated are published before they're used, so the current one depends on the time
+	key := jwkutils.CurrentAdditionalKey(s.signingKeys[alg], time.Now())
+	if key == nil {
+		return nil, fmt.Errorf("no signing key available for algorithm '%s'", alg)
+	}
+	return key, nil
+}
+
+// EnsureSigningKey ensures that there's a key to sign tokens with the given algorithm
+// If the active key uses a different algorithm, an additional key is generated and stored in the keyring
+// Additional keys are rotated together with the active key by the key rotation job
+func (s *JwtService) EnsureSigningKey(alg string) error {
+	if !slices.Contains(common.SigningKeyAlgs, alg) {
+		return fmt.Errorf("unsupported signing algorithm '%s'", alg)
+	}
+	if _, err := s.signingKey(alg); err == nil {
+		return nil
+	}
+
+	s.keyGenerationLock.Lock()
+	defer s.keyGenerationLock.Unlock()
+
+	if s.keyProvider == nil {
+		return errors.New("key provider is not initialized")
+	}
+
+	// Another instance may have generated the key already
+	keyRing, err := s.keyProvider.LoadKeyRing()
+	if err != nil {
+		return fmt.Errorf("failed to load keyring: %w", err)
+	}
+	if len(jwkutils.GetAdditionalKeys(keyRing, alg)) == 0 {
+		key, err := jwkutils.GenerateAdditionalKey(alg, time.Now())
+		if err != nil {
+			return err
+		}
+		err = keyRing.AddKey(key)
+		if err != nil {
+			return fmt.Errorf("failed to add key to the keyring: %w", err)
+		}
+		err = s.keyProvider.SaveKeyRing(keyRing)
+		if err != nil {
+			return fmt.Errorf("failed to save keyring: %w", err)
+		}
+	}
+
+	return s.ReloadKeys()
+}
+
+// GenerateAccessToken creates and signs the access token of a user's session
+// The methods the user signed in with are recorded in the token, so they can be propagated to ID tokens
+func (s *JwtService) GenerateAccessToken(user model.User, authenticationMethods []string) (string, error) {
 	now := time.Now()
 	token, err := jwt.NewBuilder().
 		Subject(user.ID).
@@ -220,8 +459,14 @@ func (s *JwtService) GenerateAccessToken(user model.User) (string, error) {
 		return "", fmt.Errorf("failed to set 'isAdmin' claim in token: %w", err)
 	}
 
-	alg, _ := s.privateKey.Algorithm()
-	signed, err := jwt.Sign(token, jwt.WithKey(alg, s.privateKey))
+	if len(authenticationMethods) > 0 {
+		err = token.Set(AuthenticationMethodsClaim, authenticationMethods)
+		if err != nil {
+			return "", fmt.Errorf("failed to set 'amr' claim in token: %w", err)
+		}
+	}
+
+	signed, err := s.signToken(token, "")
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -230,11 +475,10 @@ func (s *JwtService) GenerateAccessToken(user model.User) (string, error) {
 }
 
 func (s *JwtService) VerifyAccessToken(tokenString string) (jwt.Token, error) {
-	alg, _ := s.privateKey.Algorithm()
 	token, err := jwt.ParseString(
 		tokenString,
 		jwt.WithValidate(true),
-		jwt.WithKey(alg, s.privateKey),
+		s.verificationKeys(),
 		jwt.WithAcceptableSkew(clockSkew),
 		jwt.WithAudience(s.envConfig.AppURL),
 		jwt.WithIssuer(s.envConfig.AppURL),
@@ -247,11 +491,12 @@ func (s *JwtService) VerifyAccessToken(tokenString string) (jwt.Token, error) {
 	return token, nil
 }
 
-// BuildIDToken creates an ID token with all claims
-func (s *JwtService) BuildIDToken(userClaims map[string]any, clientID string, nonce string) (jwt.Token, error) {
+// BuildIDToken creates an ID token with all claims, which expires after the given lifetime
+// The information about how the user authenticated is added if it's known
+func (s *JwtService) BuildIDToken(userClaims map[string]any, clientID string, nonce string, auth AuthenticationInfo, lifetime time.Duration) (jwt.Token, error) {
 	now := time.Now()
 	token, err := jwt.NewBuilder().
-		Expiration(now.Add(1 * time.Hour)).
+		Expiration(now.Add(lifetime)).
 		IssuedAt(now).
 		Issuer(s.envConfig.AppURL).
 		Build()
@@ -283,34 +528,97 @@ func (s *JwtService) BuildIDToken(userClaims map[string]any, clientID string, no
 		}
 	}
 
+	if !auth.Time.IsZero() {
+		err = token.Set(AuthTimeClaim, auth.Time.Unix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to set claim 'auth_time': %w", err)
+		}
+	}
+
+	if len(auth.Methods) > 0 {
+		err = token.Set(AuthenticationMethodsClaim, auth.Methods)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set claim 'amr': %w", err)
+		}
+	}
+
+	if acr := auth.Acr(); acr != "" {
+		err = token.Set(AuthenticationContextClassClaim, acr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set claim 'acr': %w", err)
+		}
+	}
+
 	return token, nil
 }
 
-// GenerateIDToken creates and signs an ID token
-func (s *JwtService) GenerateIDToken(userClaims map[string]any, clientID string, nonce string) (string, error) {
-	token, err := s.BuildIDToken(userClaims, clientID, nonce)
+// GenerateIDToken creates and signs an ID token with the key for signingAlg, or with the active key if it's empty
+// If encryption is set, the signed token is then encrypted for the client
+func (s *JwtService) GenerateIDToken(userClaims map[string]any, clientID string, nonce string, auth AuthenticationInfo, lifetime time.Duration, signingAlg string, encryption TokenEncryption) (string, error) {
+	token, err := s.BuildIDToken(userClaims, clientID, nonce, auth, lifetime)
 	if err != nil {
 		return "", err
 	}
 
-	alg, _ := s.privateKey.Algorithm()
-	signed, err := jwt.Sign(token, jwt.WithKey(alg, s.privateKey))
+	signed, err := s.signToken(token, signingAlg)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	return string(signed), nil
+	return encryptToken(signed, encryption)
+}
+
+// GenerateUserInfoToken creates and signs a JWT with the claims of a userinfo response, with the key for signingAlg
+// If encryption is set, the signed token is then encrypted for the client
+// If signingAlg is empty, the claims are encrypted as a JSON object without being signed, as described in section 5.3.2 of OpenID Connect Core
+func (s *JwtService) GenerateUserInfoToken(userClaims map[string]any, clientID string, signingAlg string, encryption TokenEncryption) (string, error) {
+	if signingAlg == "" {
+		if encryption.IsEmpty() {
+			return "", errors.New("userinfo responses must be signed or encrypted")
+		}
+		payload, err := json.Marshal(userClaims)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal claims: %w", err)
+		}
+		return encryptPayload(payload, "", encryption)
+	}
+
+	token, err := jwt.NewBuilder().
+		IssuedAt(time.Now()).
+		Issuer(s.envConfig.AppURL).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build token: %w", err)
+	}
+
+	err = SetAudienceString(token, clientID)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'aud' claim in token: %w", err)
+	}
+
+	for k, v := range userClaims {
+		err = token.Set(k, v)
+		if err != nil {
+			return "", fmt.Errorf("failed to set claim '%s': %w", k, err)
+		}
+	}
+
+	signed, err := s.signToken(token, signingAlg)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return encryptToken(signed, encryption)
 }
 
 func (s *JwtService) VerifyIdToken(tokenString string, acceptExpiredTokens bool) (jwt.Token, error) {
-	alg, _ := s.privateKey.Algorithm()
 
 	opts := make([]jwt.ParseOption, 0)
 
 	// These options are always present
 	opts = append(opts,
 		jwt.WithValidate(true),
-		jwt.WithKey(alg, s.privateKey),
+		s.verificationKeys(),
 		jwt.WithAcceptableSkew(clockSkew),
 		jwt.WithIssuer(s.envConfig.AppURL),
 		jwt.WithValidator(TokenTypeValidator(IDTokenJWTType)),
@@ -335,12 +643,14 @@ func (s *JwtService) VerifyIdToken(tokenString string, acceptExpiredTokens bool)
 	return token, nil
 }
 
-// BuildOAuthAccessToken creates an OAuth access token with all claims
-func (s *JwtService) BuildOAuthAccessToken(user model.User, clientID string) (jwt.Token, error) {
+// BuildOAuthAccessToken creates an OAuth access token for the subject with all claims, which expires after the given lifetime
+// The granted scopes are stored in the "scope" claim, if any
+// If cnf is not empty, the token is bound to the DPoP key and/or client certificate it contains
+func (s *JwtService) BuildOAuthAccessToken(subject string, clientID string, scope string, cnf TokenConfirmation, lifetime time.Duration) (jwt.Token, error) {
 	now := time.Now()
 	token, err := jwt.NewBuilder().
-		Subject(user.ID).
-		Expiration(now.Add(1 * time.Hour)).
+		Subject(subject).
+		Expiration(now.Add(lifetime)).
 		IssuedAt(now).
 		Issuer(s.envConfig.AppURL).
 		Build()
@@ -358,18 +668,41 @@ func (s *JwtService) BuildOAuthAccessToken(user model.User, clientID string) (jw
 		return nil, fmt.Errorf("failed to set 'type' claim in token: %w", err)
 	}
 
+	if scope != "" {
+		err = token.Set(ScopeClaim, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set 'scope' claim in token: %w", err)
+		}
+	}
+
+	err = SetTokenConfirmation(token, cnf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set 'cnf' claim in token: %w", err)
+	}
+
 	return token, nil
 }
 
-// GenerateOAuthAccessToken creates and signs an OAuth access token
-func (s *JwtService) GenerateOAuthAccessToken(user model.User, clientID string) (string, error) {
-	token, err := s.BuildOAuthAccessToken(user, clientID)
+// GenerateOAuthAccessToken creates and signs an OAuth access token with the key for signingAlg, or with the active key if it's empty
+func (s *JwtService) GenerateOAuthAccessToken(subject string, clientID string, scope string, cnf TokenConfirmation, lifetime time.Duration, signingAlg string) (string, error) {
+	return s.GenerateOAuthAccessTokenWithClaims(subject, clientID, scope, cnf, lifetime, signingAlg, nil)
+}
+
+// GenerateOAuthAccessTokenWithClaims creates and signs an OAuth access token that contains additional claims
+func (s *JwtService) GenerateOAuthAccessTokenWithClaims(subject string, clientID string, scope string, cnf TokenConfirmation, lifetime time.Duration, signingAlg string, claims map[string]any) (string, error) {
+	token, err := s.BuildOAuthAccessToken(subject, clientID, scope, cnf, lifetime)
 	if err != nil {
 		return "", err
 	}
 
-	alg, _ := s.privateKey.Algorithm()
-	signed, err := jwt.Sign(token, jwt.WithKey(alg, s.privateKey))
+	for k, v := range claims {
+		err = token.Set(k, v)
+		if err != nil {
+			return "", fmt.Errorf("failed to set '%s' claim in token: %w", k, err)
+		}
+	}
+
+	signed, err := s.signToken(token, signingAlg)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -378,11 +711,10 @@ func (s *JwtService) GenerateOAuthAccessToken(user model.User, clientID string)
 }
 
 func (s *JwtService) VerifyOAuthAccessToken(tokenString string) (jwt.Token, error) {
-	alg, _ := s.privateKey.Algorithm()
 	token, err := jwt.ParseString(
 		tokenString,
 		jwt.WithValidate(true),
-		jwt.WithKey(alg, s.privateKey),
+		s.verificationKeys(),
 		jwt.WithAcceptableSkew(clockSkew),
 		jwt.WithIssuer(s.envConfig.AppURL),
 		jwt.WithValidator(TokenTypeValidator(OAuthAccessTokenJWTType)),
@@ -394,11 +726,11 @@ func (s *JwtService) VerifyOAuthAccessToken(tokenString string) (jwt.Token, erro
 	return token, nil
 }
 
-func (s *JwtService) GenerateOAuthRefreshToken(userID string, clientID string, refreshToken string) (string, error) {
+func (s *JwtService) GenerateOAuthRefreshToken(subject string, clientID string, refreshToken string, lifetime time.Duration) (string, error) {
 	now := time.Now()
 	token, err := jwt.NewBuilder().
-		Subject(userID).
-		Expiration(now.Add(RefreshTokenDuration)).
+		Subject(subject).
+		Expiration(now.Add(lifetime)).
 		IssuedAt(now).
 		Issuer(s.envConfig.AppURL).
 		Build()
@@ -421,8 +753,7 @@ func (s *JwtService) GenerateOAuthRefreshToken(userID string, clientID string, r
 		return "", fmt.Errorf("failed to set 'type' claim in token: %w", err)
 	}
 
-	alg, _ := s.privateKey.Algorithm()
-	signed, err := jwt.Sign(token, jwt.WithKey(alg, s.privateKey))
+	signed, err := s.signToken(token, "")
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -430,12 +761,11 @@ func (s *JwtService) GenerateOAuthRefreshToken(userID string, clientID string, r
 	return string(signed), nil
 }
 
-func (s *JwtService) VerifyOAuthRefreshToken(tokenString string) (userID, clientID, rt string, err error) {
-	alg, _ := s.privateKey.Algorithm()
+func (s *JwtService) VerifyOAuthRefreshToken(tokenString string) (subject, clientID, rt string, err error) {
 	token, err := jwt.ParseString(
 		tokenString,
 		jwt.WithValidate(true),
-		jwt.WithKey(alg, s.privateKey),
+		s.verificationKeys(),
 		jwt.WithAcceptableSkew(clockSkew),
 		jwt.WithIssuer(s.envConfig.AppURL),
 		jwt.WithValidator(TokenTypeValidator(OAuthRefreshTokenJWTType)),
@@ -455,12 +785,89 @@ func (s *JwtService) VerifyOAuthRefreshToken(tokenString string) (userID, client
 	}
 	clientID = audiences[0]
 
-	userID, ok = token.Subject()
+	subject, ok = token.Subject()
 	if !ok {
 		return "", "", "", errors.New("failed to get 'sub' claim from token")
 	}
 
-	return userID, clientID, rt, nil
+	return subject, clientID, rt, nil
+}
+
+// GenerateLogoutToken creates and signs a logout token for OIDC Back-Channel Logout
+// The subject is the identifier of the user for the client, and the token is signed like the ID tokens of the client
+func (s *JwtService) GenerateLogoutToken(subject string, clientID string, signingAlg string) (string, error) {
+	now := time.Now()
+	token, err := jwt.NewBuilder().
+		Subject(subject).
+		Expiration(now.Add(2 * time.Minute)).
+		IssuedAt(now).
+		Issuer(s.envConfig.AppURL).
+		JwtID(uuid.New().String()).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build token: %w", err)
+	}
+
+	err = SetAudienceString(token, clientID)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'aud' claim in token: %w", err)
+	}
+
+	err = SetTokenType(token, LogoutTokenJWTType)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'type' claim in token: %w", err)
+	}
+
+	err = token.Set("events", map[string]any{BackchannelLogoutEvent: map[string]any{}})
+	if err != nil {
+		return "", fmt.Errorf("failed to set claim 'events': %w", err)
+	}
+
+	headers := jws.NewHeaders()
+	err = headers.Set(jws.TypeKey, "logout+jwt")
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'typ' header: %w", err)
+	}
+
+	signed, err := s.signToken(token, signingAlg, jws.WithProtectedHeaders(headers))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return string(signed), nil
+}
+
+// GenerateAuthorizationResponseToken creates and signs a JWT containing the parameters of an authorization response, as described in JARM
+func (s *JwtService) GenerateAuthorizationResponseToken(params map[string]string, clientID string, signingAlg string) (string, error) {
+	// The response is used right away by the client, so it's only valid for a short time
+	now := time.Now()
+	token, err := jwt.NewBuilder().
+		Expiration(now.Add(10 * time.Minute)).
+		IssuedAt(now).
+		Issuer(s.envConfig.AppURL).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build token: %w", err)
+	}
+
+	err = SetAudienceString(token, clientID)
+	if err != nil {
+		return "", fmt.Errorf("failed to set 'aud' claim in token: %w", err)
+	}
+
+	for k, v := range params {
+		err = token.Set(k, v)
+		if err != nil {
+			return "", fmt.Errorf("failed to set claim '%s': %w", k, err)
+		}
+	}
+
+	signed, err := s.signToken(token, signingAlg)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return string(signed), nil
 }
 
 // GetTokenType returns the type of the JWT token issued by Pocket ID, but **does not validate it**.
@@ -486,23 +893,26 @@ func (s *JwtService) GetTokenType(tokenString string) (string, jwt.Token, error)
 
 // GetPublicJWK returns the JSON Web Key (JWK) for the public key.
 func (s *JwtService) GetPublicJWK() (jwk.Key, error) {
+	s.keysLock.RLock()
+	defer s.keysLock.RUnlock()
+
+	return s.getPublicJWK()
+}
+
+func (s *JwtService) getPublicJWK() (jwk.Key, error) {
 	if s.privateKey == nil {
 		return nil, errors.New("key is not initialized")
 	}
 
-	pubKey, err := s.privateKey.PublicKey()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get public key: %w", err)
-	}
-
-	jwkutils.EnsureAlgInKey(pubKey, "", "")
-
-	return pubKey, nil
+	return jwkutils.PublicKeyForJWKS(s.privateKey)
 }
 
-// GetPublicJWKSAsJSON returns the JSON Web Key Set (JWKS) for the public key, encoded as JSON.
-// The value is cached since the key is static.
+// GetPublicJWKSAsJSON returns the JSON Web Key Set (JWKS) with the public keys of the active key and the keyring, encoded as JSON.
+// The value is cached until the keys change.
 func (s *JwtService) GetPublicJWKSAsJSON() ([]byte, error) {
+	s.keysLock.RLock()
+	defer s.keysLock.RUnlock()
+
 	if len(s.jwksEncoded) == 0 {
 		return nil, errors.New("key is not initialized")
 	}
@@ -512,6 +922,9 @@ func (s *JwtService) GetPublicJWKSAsJSON() ([]byte, error) {
 
 // GetKeyAlg returns the algorithm of the key
 func (s *JwtService) GetKeyAlg() (jwa.KeyAlgorithm, error) {
+	s.keysLock.RLock()
+	defer s.keysLock.RUnlock()
+
 	if len(s.jwksEncoded) == 0 {
 		return nil, errors.New("key is not initialized")
 	}
@@ -524,6 +937,35 @@ func (s *JwtService) GetKeyAlg() (jwa.KeyAlgorithm, error) {
 	return alg, nil
 }
 
+// MaxTokenLifetime returns the longest lifetime of the tokens that Pocket ID currently signs
+// After a key is replaced, it must be kept for verification for at least this long
+func MaxTokenLifetime(ctx context.Context, db *gorm.DB, appConfig *model.AppConfig) (time.Duration, error) {
+	var clientLifetimes struct {
+		AccessToken  *int
+		IdToken      *int
+		RefreshToken *int
+	}
+	err := db.
+		WithContext(ctx).
+		Model(&model.OidcClient{}).
+		Select("MAX(access_token_lifetime) AS access_token, MAX(id_token_lifetime) AS id_token, MAX(refresh_token_idle_lifetime) AS refresh_token").
+		Scan(&clientLifetimes).
+		Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to query token lifetimes of clients: %w", err)
+	}
+
+	return max(
+		appConfig.SessionDuration.AsDurationMinutes(),
+		clientTokenLifetime(clientLifetimes.AccessToken, AccessTokenDuration),
+		clientTokenLifetime(clientLifetimes.IdToken, IdTokenDuration),
+		clientTokenLifetime(clientLifetimes.RefreshToken, RefreshTokenDuration),
+		AccessTokenDuration,
+		IdTokenDuration,
+		RefreshTokenDuration,
+	), nil
+}
+
 // GetIsAdmin returns the value of the "isAdmin" claim in the token
 func GetIsAdmin(token jwt.Token) (bool, error) {
 	if !token.Has(IsAdminClaim) {
@@ -560,6 +1002,156 @@ func SetAudienceString(token jwt.Token, audience string) error {
 	return token.Set(jwt.AudienceKey, audience)
 }
 
+var (
+	// AuthenticationMethodsPasskey are the methods used when signing in with a passkey: a hardware-protected key and user verification
+	AuthenticationMethodsPasskey = []string{"hwk", "user"}
+
+	// AuthenticationMethodsOneTimeCode are the methods used when signing in with a one-time code
+	AuthenticationMethodsOneTimeCode = []string{"otp"}
+
+	// SupportedAcrValues are the supported authentication context classes, from the weakest to the strongest
+	SupportedAcrValues = []string{AcrOneTimeCode, AcrPasskey}
+)
+
+// AuthenticationInfo contains information about how a user authenticated, which is added to ID tokens
+type AuthenticationInfo struct {
+	// Time at which the user authenticated, which is zero if it's unknown
+	Time time.Time
+	// Methods used to authenticate, as described in RFC 8176
+	Methods []string
+}
+
+// Acr returns the authentication context class satisfied by the authentication, or an empty string if there's none
+func (a AuthenticationInfo) Acr() string {
+	switch {
+	case slices.Contains(a.Methods, "hwk"):
+		return AcrPasskey
+	case slices.Contains(a.Methods, "otp"):
+		return AcrOneTimeCode
+	default:
+		return ""
+	}
+}
+
+// SatisfiesAcr returns true if the authentication is at least as strong as the given authentication context class
+func (a AuthenticationInfo) SatisfiesAcr(acr string) bool {
+	required := slices.Index(SupportedAcrValues, acr)
+	return required >= 0 && slices.Index(SupportedAcrValues, a.Acr()) >= required
+}
+
+// GetAuthenticationMethods returns the methods the user signed in with, as recorded in the access token of the session
+func GetAuthenticationMethods(token jwt.Token) []string {
+	// When the token is parsed, the claim is decoded as a generic array
+	var values []any
+	if !token.Has(AuthenticationMethodsClaim) || token.Get(AuthenticationMethodsClaim, &values) != nil {
+		return nil
+	}
+
+	methods := make([]string, 0, len(values))
+	for _, v := range values {
+		if method, ok := v.(string); ok {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// TokenConfirmation contains the keys a token is bound to, which are set in the "cnf" claim
+type TokenConfirmation struct {
+	// JWK thumbprint of the DPoP key, as described in RFC 9449
+	DpopJkt string
+	// SHA-256 thumbprint of the client certificate, as described in RFC 8705
+	CertificateThumbprint string
+}
+
+// IsEmpty returns true if the token is not bound to any key
+func (c TokenConfirmation) IsEmpty() bool {
+	return c.DpopJkt == "" && c.CertificateThumbprint == ""
+}
+
+// Claim returns the value of the "cnf" claim
+func (c TokenConfirmation) Claim() map[string]string {
+	claim := make(map[string]string, 2)
+	if c.DpopJkt != "" {
+		claim["jkt"] = c.DpopJkt
+	}
+	if c.CertificateThumbprint != "" {
+		claim["x5t#S256"] = c.CertificateThumbprint
+	}
+	return claim
+}
+
+// SetTokenConfirmation sets the "cnf" claim in the token, binding it to the given keys
+func SetTokenConfirmation(token jwt.Token, cnf TokenConfirmation) error {
+	// Only set if the token is bound to a key
+	if cnf.IsEmpty() {
+		return nil
+	}
+	return token.Set(ConfirmationClaim, cnf.Claim())
+}
+
+// GetTokenConfirmation returns the keys the token is bound to, which are empty if the token is not bound
+func GetTokenConfirmation(token jwt.Token) TokenConfirmation {
+	var claim map[string]any
+	if !token.Has(ConfirmationClaim) || token.Get(ConfirmationClaim, &claim) != nil {
+		return TokenConfirmation{}
+	}
+	jkt, _ := claim["jkt"].(string)
+	x5t, _ := claim["x5t#S256"].(string)
+	return TokenConfirmation{DpopJkt: jkt, CertificateThumbprint: x5t}
+}
+
+// TokenEncryption contains the key of a client and the algorithms that tokens are encrypted for it with
+type TokenEncryption struct {
+	Key jwk.Key
+	Alg jwa.KeyEncryptionAlgorithm
+	Enc jwa.ContentEncryptionAlgorithm
+}
+
+// IsEmpty returns true if tokens are not encrypted
+func (e TokenEncryption) IsEmpty() bool {
+	return e.Key == nil
+}
+
+// encryptToken wraps a signed token in a JWE, as described in section 16.14 of OpenID Connect Core
+// If the encryption is empty, the signed token is returned as is
+func encryptToken(signed []byte, encryption TokenEncryption) (string, error) {
+	if encryption.IsEmpty() {
+		return string(signed), nil
+	}
+
+	return encryptPayload(signed, "JWT", encryption)
+}
+
+// encryptPayload encrypts the payload in a JWE for the client, with the content type in the 'cty' header if it's not empty
+func encryptPayload(payload []byte, contentType string, encryption TokenEncryption) (string, error) {
+	headers := jwe.NewHeaders()
+	if contentType != "" {
+		err := headers.Set(jwe.ContentTypeKey, contentType)
+		if err != nil {
+			return "", fmt.Errorf("failed to set 'cty' header: %w", err)
+		}
+	}
+	if kid, ok := encryption.Key.KeyID(); ok {
+		err := headers.Set(jwe.KeyIDKey, kid)
+		if err != nil {
+			return "", fmt.Errorf("failed to set 'kid' header: %w", err)
+		}
+	}
+
+	encrypted, err := jwe.Encrypt(payload,
+		jwe.WithKey(encryption.Alg, encryption.Key),
+		jwe.WithContentEncryption(encryption.Enc),
+		jwe.WithProtectedHeaders(headers),
+		jwe.WithCompact(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	return string(encrypted), nil
+}
+
 // TokenTypeValidator is a validator function that checks the "type" claim in the token
 func TokenTypeValidator(expectedTokenType string) jwt.ValidatorFunc {
 	return func(_ context.Context, t jwt.Token) error {
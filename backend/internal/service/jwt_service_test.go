@@ -14,7 +14,9 @@ import (
 	"time"
 
 	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwe"
 	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
 	"github.com/pocket-id/pocket-id/backend/internal/utils"
 	"github.com/stretchr/testify/assert"
@@ -165,6 +167,55 @@ func TestJwtService_Init(t *testing.T) {
 		_ = assert.True(t, ok) &&
 			assert.Equal(t, origKeyID, loadedKeyID, "Loaded key should have the same ID as the original")
 	})
+
+	t.Run("should keep retired keys from the keyring for verification", func(t *testing.T) {
+		mockEnvConfig := &common.EnvConfigSchema{
+			AppURL:      "https://test.example.com",
+			KeysStorage: "file",
+			KeysPath:    t.TempDir(),
+		}
+
+		// Issue a token with the first key
+		firstService := &JwtService{}
+		err := firstService.init(nil, mockConfig, mockEnvConfig)
+		require.NoError(t, err, "Failed to initialize first JWT service")
+		tokenString, err := firstService.GenerateOAuthRefreshToken("user123", "client123", "rt-123", RefreshTokenDuration)
+		require.NoError(t, err, "Failed to generate refresh token")
+
+		// Rotate the key, keeping the previous one in the keyring
+		keyProvider, err := jwkutils.GetKeyProvider(nil, mockEnvConfig, mockConfig.GetDbConfig().InstanceID.Value)
+		require.NoError(t, err)
+		newKey, err := jwkutils.GenerateKey("ES256", "")
+		require.NoError(t, err)
+		err = jwkutils.RotateKey(keyProvider, newKey, time.Hour)
+		require.NoError(t, err, "Failed to rotate key")
+
+		secondService := &JwtService{}
+		err = secondService.init(nil, mockConfig, mockEnvConfig)
+		require.NoError(t, err, "Failed to initialize second JWT service")
+
+		// New tokens are signed with the new key
+		newKeyID, _ := newKey.KeyID()
+		assert.Equal(t, newKeyID, secondService.keyId)
+
+		// Tokens signed with the retired key are still valid
+		userID, _, _, err := secondService.VerifyOAuthRefreshToken(tokenString)
+		require.NoError(t, err, "Token signed with the retired key should be valid")
+		assert.Equal(t, "user123", userID)
+
+		// Both keys are published, without their private parts
+		jwksBytes, err := secondService.GetPublicJWKSAsJSON()
+		require.NoError(t, err)
+		jwks, err := jwk.Parse(jwksBytes)
+		require.NoError(t, err)
+		require.Equal(t, 2, jwks.Len())
+		_, ok := jwks.LookupKeyID(firstService.keyId)
+		assert.True(t, ok, "Retired key should be published")
+		_, ok = jwks.LookupKeyID(newKeyID)
+		assert.True(t, ok, "Active key should be published")
+		assert.NotContains(t, string(jwksBytes), jwkutils.KeyStatusParam)
+		assert.NotContains(t, string(jwksBytes), `"d"`)
+	})
 }
 
 func TestJwtService_GetPublicJWK(t *testing.T) {
@@ -348,7 +399,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, AuthenticationMethodsPasskey)
 		require.NoError(t, err, "Failed to generate access token")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -366,6 +417,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		audience, ok := claims.Audience()
 		_ = assert.True(t, ok, "Audience not found in token") &&
 			assert.Equal(t, []string{"https://test.example.com"}, audience, "Audience should contain the app URL")
+		assert.Equal(t, AuthenticationMethodsPasskey, GetAuthenticationMethods(claims), "Token should contain the authentication methods")
 
 		// Check token expiration time is approximately 1 hour from now
 		expectedExp := time.Now().Add(1 * time.Hour)
@@ -391,7 +443,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(adminUser)
+		tokenString, err := service.GenerateAccessToken(adminUser, AuthenticationMethodsPasskey)
 		require.NoError(t, err, "Failed to generate access token")
 
 		// Verify the token
@@ -425,7 +477,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, AuthenticationMethodsPasskey)
 		require.NoError(t, err, "Failed to generate access token")
 
 		// Verify the token
@@ -470,7 +522,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, AuthenticationMethodsPasskey)
 		require.NoError(t, err, "Failed to generate access token with Ed25519 key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -527,7 +579,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, AuthenticationMethodsPasskey)
 		require.NoError(t, err, "Failed to generate access token with ECDSA key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -584,7 +636,7 @@ func TestGenerateVerifyAccessToken(t *testing.T) {
 		}
 
 		// Generate a token
-		tokenString, err := service.GenerateAccessToken(user)
+		tokenString, err := service.GenerateAccessToken(user, AuthenticationMethodsPasskey)
 		require.NoError(t, err, "Failed to generate access token with RSA key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -643,7 +695,7 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		const clientID = "test-client-123"
 
 		// Generate a token
-		tokenString, err := service.GenerateIDToken(userClaims, clientID, "")
+		tokenString, err := service.GenerateIDToken(userClaims, clientID, "", AuthenticationInfo{}, IdTokenDuration, "", TokenEncryption{})
 		require.NoError(t, err, "Failed to generate ID token")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -743,7 +795,7 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		nonce := "random-nonce-value"
 
 		// Generate a token with nonce
-		tokenString, err := service.GenerateIDToken(userClaims, clientID, nonce)
+		tokenString, err := service.GenerateIDToken(userClaims, clientID, nonce, AuthenticationInfo{}, IdTokenDuration, "", TokenEncryption{})
 		require.NoError(t, err, "Failed to generate ID token with nonce")
 
 		// Parse the token manually to check nonce
@@ -759,6 +811,35 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		assert.Equal(t, nonce, tokenNonce, "Token should contain the correct nonce")
 	})
 
+	t.Run("generates ID token with authentication information", func(t *testing.T) {
+		// Create a JWT service
+		service := &JwtService{}
+		err := service.init(nil, mockConfig, mockEnvConfig)
+		require.NoError(t, err, "Failed to initialize JWT service")
+
+		userClaims := map[string]interface{}{
+			"sub": "user456",
+		}
+		authTime := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+
+		tokenString, err := service.GenerateIDToken(userClaims, "test-client-456", "", AuthenticationInfo{
+			Time:    authTime,
+			Methods: AuthenticationMethodsOneTimeCode,
+		}, IdTokenDuration, "", TokenEncryption{})
+		require.NoError(t, err, "Failed to generate ID token")
+
+		token, err := service.VerifyIdToken(tokenString, false)
+		require.NoError(t, err, "Failed to verify generated token")
+
+		var tokenAuthTime float64
+		require.NoError(t, token.Get(AuthTimeClaim, &tokenAuthTime))
+		assert.Equal(t, authTime.Unix(), int64(tokenAuthTime), "Token should contain the authentication time")
+		assert.Equal(t, AuthenticationMethodsOneTimeCode, GetAuthenticationMethods(token), "Token should contain the authentication methods")
+		var acr string
+		require.NoError(t, token.Get(AuthenticationContextClassClaim, &acr))
+		assert.Equal(t, AcrOneTimeCode, acr, "Token should contain the authentication context class")
+	})
+
 	t.Run("fails verification with incorrect issuer", func(t *testing.T) {
 		// Create a JWT service
 		service := &JwtService{}
@@ -769,7 +850,7 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		userClaims := map[string]interface{}{
 			"sub": "user789",
 		}
-		tokenString, err := service.GenerateIDToken(userClaims, "client-789", "")
+		tokenString, err := service.GenerateIDToken(userClaims, "client-789", "", AuthenticationInfo{}, IdTokenDuration, "", TokenEncryption{})
 		require.NoError(t, err, "Failed to generate ID token")
 
 		// Temporarily change the app URL to simulate wrong issuer
@@ -810,7 +891,7 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		const clientID = "eddsa-client-123"
 
 		// Generate a token
-		tokenString, err := service.GenerateIDToken(userClaims, clientID, "")
+		tokenString, err := service.GenerateIDToken(userClaims, clientID, "", AuthenticationInfo{}, IdTokenDuration, "", TokenEncryption{})
 		require.NoError(t, err, "Failed to generate ID token with key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -865,7 +946,7 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		const clientID = "ecdsa-client-123"
 
 		// Generate a token
-		tokenString, err := service.GenerateIDToken(userClaims, clientID, "")
+		tokenString, err := service.GenerateIDToken(userClaims, clientID, "", AuthenticationInfo{}, IdTokenDuration, "", TokenEncryption{})
 		require.NoError(t, err, "Failed to generate ID token with key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -921,7 +1002,7 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		const clientID = "rsa-client-123"
 
 		// Generate a token
-		tokenString, err := service.GenerateIDToken(userClaims, clientID, "")
+		tokenString, err := service.GenerateIDToken(userClaims, clientID, "", AuthenticationInfo{}, IdTokenDuration, "", TokenEncryption{})
 		require.NoError(t, err, "Failed to generate ID token with key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -937,6 +1018,104 @@ func TestGenerateVerifyIdToken(t *testing.T) {
 		_ = assert.True(t, ok, "Issuer not found in token") &&
 			assert.Equal(t, service.envConfig.AppURL, issuer, "Issuer should match app URL")
 	})
+
+	t.Run("encrypts ID token for the client", func(t *testing.T) {
+		service := &JwtService{}
+		err := service.init(nil, mockConfig, mockEnvConfig)
+		require.NoError(t, err, "Failed to initialize JWT service")
+
+		// Create the encryption key of the client
+		rawClientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		clientKey, err := jwk.Import(rawClientKey)
+		require.NoError(t, err)
+		require.NoError(t, clientKey.Set(jwk.KeyIDKey, "client-enc-key"))
+		clientPublicKey, err := clientKey.PublicKey()
+		require.NoError(t, err)
+
+		tokenString, err := service.GenerateIDToken(map[string]any{"sub": "user123"}, "enc-client", "", AuthenticationInfo{}, IdTokenDuration, "", TokenEncryption{
+			Key: clientPublicKey,
+			Alg: jwa.ECDH_ES_A128KW(),
+			Enc: jwa.A256GCM(),
+		})
+		require.NoError(t, err, "Failed to generate encrypted ID token")
+
+		// The encrypted token can't be verified directly
+		_, err = service.VerifyIdToken(tokenString, false)
+		require.Error(t, err)
+
+		msg, err := jwe.Parse([]byte(tokenString))
+		require.NoError(t, err)
+		kid, _ := msg.ProtectedHeaders().KeyID()
+		assert.Equal(t, "client-enc-key", kid)
+		cty, _ := msg.ProtectedHeaders().ContentType()
+		assert.Equal(t, "JWT", cty)
+
+		// After decryption, the signed ID token is valid
+		decrypted, err := jwe.Decrypt([]byte(tokenString), jwe.WithKey(jwa.ECDH_ES_A128KW(), clientKey))
+		require.NoError(t, err, "Failed to decrypt ID token")
+		claims, err := service.VerifyIdToken(string(decrypted), false)
+		require.NoError(t, err, "Failed to verify decrypted ID token")
+		subject, _ := claims.Subject()
+		assert.Equal(t, "user123", subject)
+	})
+
+	t.Run("signs ID token with the algorithm requested by the client", func(t *testing.T) {
+		service := &JwtService{}
+		err := service.init(nil, mockConfig, mockEnvConfig)
+		require.NoError(t, err, "Failed to initialize JWT service")
+
+		// There's no key for the algorithm until it's requested
+		_, err = service.GenerateIDToken(map[string]any{"sub": "user123"}, "es-client", "", AuthenticationInfo{}, IdTokenDuration, "ES384", TokenEncryption{})
+		require.Error(t, err)
+
+		err = service.EnsureSigningKey("ES384")
+		require.NoError(t, err, "Failed to create signing key")
+
+		tokenString, err := service.GenerateIDToken(map[string]any{"sub": "user123"}, "es-client", "", AuthenticationInfo{}, IdTokenDuration, "ES384", TokenEncryption{})
+		require.NoError(t, err, "Failed to generate ID token")
+
+		msg, err := jws.Parse([]byte(tokenString))
+		require.NoError(t, err)
+		alg, _ := msg.Signatures()[0].ProtectedHeaders().Algorithm()
+		assert.Equal(t, jwa.ES384(), alg)
+
+		_, err = service.VerifyIdToken(tokenString, false)
+		require.NoError(t, err, "Failed to verify ID token")
+
+		// The additional key is published in the JWKS, and the active key is still used by default
+		jwks, err := service.GetPublicJWKSAsJSON()
+		require.NoError(t, err)
+		set, err := jwk.Parse(jwks)
+		require.NoError(t, err)
+		assert.Equal(t, 2, set.Len())
+
+		keyAlg, err := service.GetKeyAlg()
+		require.NoError(t, err)
+		assert.Equal(t, jwa.RS256(), keyAlg)
+
+		// Unsupported algorithms are rejected
+		err = service.EnsureSigningKey("HS256")
+		require.Error(t, err)
+	})
+
+	t.Run("signs with additional keys generated by another instance", func(t *testing.T) {
+		first := &JwtService{}
+		err := first.init(nil, mockConfig, mockEnvConfig)
+		require.NoError(t, err)
+		second := &JwtService{}
+		err = second.init(nil, mockConfig, mockEnvConfig)
+		require.NoError(t, err)
+
+		err = first.EnsureSigningKey("ES512")
+		require.NoError(t, err)
+
+		// The second instance loads the keyring again when it doesn't have a key for the algorithm
+		tokenString, err := second.GenerateIDToken(map[string]any{"sub": "user123"}, "es-client", "", AuthenticationInfo{}, IdTokenDuration, "ES512", TokenEncryption{})
+		require.NoError(t, err)
+		_, err = first.VerifyIdToken(tokenString, false)
+		require.NoError(t, err)
+	})
 }
 
 func TestGenerateVerifyOAuthAccessToken(t *testing.T) {
@@ -971,7 +1150,7 @@ func TestGenerateVerifyOAuthAccessToken(t *testing.T) {
 		const clientID = "test-client-123"
 
 		// Generate a token
-		tokenString, err := service.GenerateOAuthAccessToken(user, clientID)
+		tokenString, err := service.GenerateOAuthAccessToken(user.ID, clientID, "", TokenConfirmation{}, AccessTokenDuration, "")
 		require.NoError(t, err, "Failed to generate OAuth access token")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -1059,13 +1238,13 @@ func TestGenerateVerifyOAuthAccessToken(t *testing.T) {
 		const clientID = "test-client-789"
 
 		// Generate a token with the first service
-		tokenString, err := service1.GenerateOAuthAccessToken(user, clientID)
+		tokenString, err := service1.GenerateOAuthAccessToken(user.ID, clientID, "", TokenConfirmation{}, AccessTokenDuration, "")
 		require.NoError(t, err, "Failed to generate OAuth access token")
 
 		// Verify with the second service should fail due to different keys
 		_, err = service2.VerifyOAuthAccessToken(tokenString)
 		require.Error(t, err, "Verification should fail with invalid signature")
-		assert.Contains(t, err.Error(), "verification error", "Error message should indicate token verification failure")
+		assert.Contains(t, err.Error(), "failed to find key", "Error message should indicate the signing key is unknown")
 	})
 
 	t.Run("works with Ed25519 keys", func(t *testing.T) {
@@ -1098,7 +1277,7 @@ func TestGenerateVerifyOAuthAccessToken(t *testing.T) {
 		const clientID = "eddsa-oauth-client"
 
 		// Generate a token
-		tokenString, err := service.GenerateOAuthAccessToken(user, clientID)
+		tokenString, err := service.GenerateOAuthAccessToken(user.ID, clientID, "", TokenConfirmation{}, AccessTokenDuration, "")
 		require.NoError(t, err, "Failed to generate OAuth access token with key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -1155,7 +1334,7 @@ func TestGenerateVerifyOAuthAccessToken(t *testing.T) {
 		const clientID = "ecdsa-oauth-client"
 
 		// Generate a token
-		tokenString, err := service.GenerateOAuthAccessToken(user, clientID)
+		tokenString, err := service.GenerateOAuthAccessToken(user.ID, clientID, "", TokenConfirmation{}, AccessTokenDuration, "")
 		require.NoError(t, err, "Failed to generate OAuth access token with key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -1212,7 +1391,7 @@ func TestGenerateVerifyOAuthAccessToken(t *testing.T) {
 		const clientID = "rsa-oauth-client"
 
 		// Generate a token
-		tokenString, err := service.GenerateOAuthAccessToken(user, clientID)
+		tokenString, err := service.GenerateOAuthAccessToken(user.ID, clientID, "", TokenConfirmation{}, AccessTokenDuration, "")
 		require.NoError(t, err, "Failed to generate OAuth access token with key")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -1268,7 +1447,7 @@ func TestGenerateVerifyOAuthRefreshToken(t *testing.T) {
 		)
 
 		// Generate a token
-		tokenString, err := service.GenerateOAuthRefreshToken(userID, clientID, refreshToken)
+		tokenString, err := service.GenerateOAuthRefreshToken(userID, clientID, refreshToken, RefreshTokenDuration)
 		require.NoError(t, err, "Failed to generate refresh token")
 		assert.NotEmpty(t, tokenString, "Token should not be empty")
 
@@ -1322,16 +1501,61 @@ func TestGenerateVerifyOAuthRefreshToken(t *testing.T) {
 		require.NoError(t, err, "Failed to initialize second JWT service")
 
 		// Generate a token with the first service
-		tokenString, err := service1.GenerateOAuthRefreshToken("user789", "client123", "my-rt-123")
+		tokenString, err := service1.GenerateOAuthRefreshToken("user789", "client123", "my-rt-123", RefreshTokenDuration)
 		require.NoError(t, err, "Failed to generate refresh token")
 
 		// Verify with the second service should fail due to different keys
 		_, _, _, err = service2.VerifyOAuthRefreshToken(tokenString)
 		require.Error(t, err, "Verification should fail with invalid signature")
-		assert.Contains(t, err.Error(), "verification error", "Error message should indicate token verification failure")
+		assert.Contains(t, err.Error(), "failed to find key", "Error message should indicate the signing key is unknown")
 	})
 }
 
+func TestGenerateLogoutToken(t *testing.T) {
+	mockConfig := NewTestAppConfigService(&model.AppConfig{})
+	service := &JwtService{}
+	err := service.init(nil, mockConfig, &common.EnvConfigSchema{
+		AppURL:      "https://test.example.com",
+		KeysStorage: "file",
+		KeysPath:    t.TempDir(),
+	})
+	require.NoError(t, err, "Failed to initialize JWT service")
+
+	tokenString, err := service.GenerateLogoutToken("user123", "test-client-123", "")
+	require.NoError(t, err, "Failed to generate logout token")
+
+	msg, err := jws.Parse([]byte(tokenString))
+	require.NoError(t, err, "Failed to parse logout token")
+	require.Len(t, msg.Signatures(), 1)
+	typ, ok := msg.Signatures()[0].ProtectedHeaders().Type()
+	_ = assert.True(t, ok, "typ header not found in token") &&
+		assert.Equal(t, "logout+jwt", typ, "typ header should identify a logout token")
+
+	publicKey, err := service.GetPublicJWK()
+	require.NoError(t, err, "Failed to get public key")
+	alg, _ := publicKey.Algorithm()
+	token, err := jwt.Parse([]byte(tokenString),
+		jwt.WithKey(alg, publicKey),
+		jwt.WithIssuer("https://test.example.com"),
+		jwt.WithAudience("test-client-123"),
+		jwt.WithValidator(TokenTypeValidator(LogoutTokenJWTType)),
+	)
+	require.NoError(t, err, "Failed to verify logout token")
+
+	subject, ok := token.Subject()
+	_ = assert.True(t, ok, "Subject not found in token") &&
+		assert.Equal(t, "user123", subject, "Token subject should match user ID")
+	jti, ok := token.JwtID()
+	_ = assert.True(t, ok, "jti not found in token") &&
+		assert.NotEmpty(t, jti, "jti should not be empty")
+
+	var events map[string]any
+	err = token.Get("events", &events)
+	require.NoError(t, err, "Failed to get events claim")
+	assert.Contains(t, events, BackchannelLogoutEvent, "events claim should contain the back-channel logout event")
+	assert.False(t, token.Has("nonce"), "Logout tokens must not contain a nonce")
+}
+
 func TestTokenTypeValidator(t *testing.T) {
 	// Create a context for the validator function
 	ctx := context.Background()
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/pocket-id/pocket-id/backend/internal/dto"
+	"github.com/pocket-id/pocket-id/backend/internal/model"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
+)
+
+// BuiltInScopes contains the scopes that are always supported, and that can't be defined by admins
+var BuiltInScopes = []string{"openid", "profile", "email", "groups", ScopeOfflineAccess}
+
+// BuiltInClaims contains the claims that Pocket ID can return without custom scopes
+var BuiltInClaims = []string{"sub", "given_name", "family_name", "name", "email", "email_verified", "preferred_username", "picture", "groups", "auth_time", "acr", "amr"}
+
+type OidcScopeService struct {
+	db *gorm.DB
+}
+
+func NewOidcScopeService(db *gorm.DB) *OidcScopeService {
+	return &OidcScopeService{db: db}
+}
+
+func (s *OidcScopeService) List(ctx context.Context, name string, sortedPaginationRequest utils.SortedPaginationRequest) (scopes []model.OidcScope, response utils.PaginationResponse, err error) {
+	query := s.db.
+		WithContext(ctx).
+		Preload("AllowedClients").
+		Model(&model.OidcScope{})
+
+	if name != "" {
+		query = query.Where("name LIKE ?", "%"+name+"%")
+	}
+
+	response, err = utils.PaginateAndSort(sortedPaginationRequest, query, &scopes)
+	return scopes, response, err
+}
+
+// ListAll returns all the custom scopes, ordered by name
+func (s *OidcScopeService) ListAll(ctx context.Context) (scopes []model.OidcScope, err error) {
+	err = s.db.
+		WithContext(ctx).
+		Order("name").
+		Find(&scopes).
+		Error
+	return scopes, err
+}
+
+func (s *OidcScopeService) Get(ctx context.Context, id string) (scope model.OidcScope, err error) {
+	return s.getInternal(ctx, id, s.db)
+}
+
+func (s *OidcScopeService) getInternal(ctx context.Context, id string, tx *gorm.DB) (scope model.OidcScope, err error) {
+	err = tx.
+		WithContext(ctx).
+		Where("id = ?", id).
+		Preload("AllowedClients").
+		First(&scope).
+		Error
+	return scope, err
+}
+
+func (s *OidcScopeService) Create(ctx context.Context, input dto.OidcScopeCreateDto) (scope model.OidcScope, err error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	scope, err = s.saveInternal(ctx, model.OidcScope{}, input, tx)
+	if err != nil {
+		return model.OidcScope{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.OidcScope{}, err
+	}
+
+	return scope, nil
+}
+
+func (s *OidcScopeService) Update(ctx context.Context, id string, input dto.OidcScopeCreateDto) (scope model.OidcScope, err error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	scope, err = s.getInternal(ctx, id, tx)
+	if err != nil {
+		return model.OidcScope{}, err
+	}
+
+	scope, err = s.saveInternal(ctx, scope, input, tx)
+	if err != nil {
+		return model.OidcScope{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.OidcScope{}, err
+	}
+
+	return scope, nil
+}
+
+func (s *OidcScopeService) saveInternal(ctx context.Context, scope model.OidcScope, input dto.OidcScopeCreateDto, tx *gorm.DB) (model.OidcScope, error) {
+	// Built-in scopes can't be redefined
+	if slices.Contains(BuiltInScopes, input.Name) {
+		return model.OidcScope{}, &common.AlreadyInUseError{Property: "name"}
+	}
+
+	scope.Name = input.Name
+	scope.Description = input.Description
+	scope.Claims = input.Claims
+
+	var allowedClients []model.OidcClient
+	if len(input.AllowedClientIDs) > 0 {
+		err := tx.
+			WithContext(ctx).
+			Where("id IN ?", input.AllowedClientIDs).
+			Find(&allowedClients).
+			Error
+		if err != nil {
+			return model.OidcScope{}, err
+		}
+	}
+
+	err := tx.
+		WithContext(ctx).
+		Omit("AllowedClients").
+		Save(&scope).
+		Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return model.OidcScope{}, &common.AlreadyInUseError{Property: "name"}
+	} else if err != nil {
+		return model.OidcScope{}, err
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Model(&scope).
+		Association("AllowedClients").
+		Replace(allowedClients)
+	if err != nil {
+		return model.OidcScope{}, err
+	}
+
+	return scope, nil
+}
+
+func (s *OidcScopeService) Delete(ctx context.Context, id string) error {
+	result := s.db.
+		WithContext(ctx).
+		Delete(&model.OidcScope{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"crypto"
+	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -16,16 +19,20 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"slices"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/lestrrat-go/httprc/v3"
 	"github.com/lestrrat-go/httprc/v3/errsink"
+	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwk"
 	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -42,14 +49,65 @@ const (
 	GrantTypeRefreshToken      = "refresh_token"
 	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
 	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+	GrantTypeJWTBearer         = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	GrantTypeCIBA              = "urn:openid:params:grant-type:ciba"
+
+	// TokenTypeAccessToken and TokenTypeJWT identify the types of tokens used in the token exchange grant, as described in RFC 8693
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token" //nolint:gosec
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+
+	// FederatedSubjectPrefix is added to the subject of tokens issued by federated identity providers when they are exchanged,
+	// so they can't be confused with Pocket ID users
+	FederatedSubjectPrefix = "federated-"
+
+	// PushedAuthorizationRequestURIPrefix is the prefix of the request URIs returned by the pushed authorization request endpoint
+	PushedAuthorizationRequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
 
 	ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" //nolint:gosec
 
+	// ScopeOfflineAccess is the scope that clients request to receive a refresh token
+	ScopeOfflineAccess = "offline_access"
+
+	// Default lifetimes of the tokens, which can be changed for each client
 	AccessTokenDuration  = time.Hour
+	IdTokenDuration      = time.Hour
 	RefreshTokenDuration = 30 * 24 * time.Hour // 30 days
-	DeviceCodeDuration   = 15 * time.Minute
+
+	DeviceCodeDuration = 15 * time.Minute
+
+	// BackchannelAuthenticationRequestDuration is the maximum lifetime of a CIBA request, which clients can shorten with "requested_expiry"
+	BackchannelAuthenticationRequestDuration = 10 * time.Minute
+	// BackchannelAuthenticationPollingInterval is the minimum time clients should wait between polling requests to the token endpoint
+	BackchannelAuthenticationPollingInterval = 5 * time.Second
+
+	PushedAuthorizationRequestDuration = 90 * time.Second
+
+	// DpopProofLifetime is how long after being issued a DPoP proof is accepted
+	DpopProofLifetime = 5 * time.Minute
+
+	// BackchannelLogoutMaxAttempts is the number of times the delivery of a logout token is attempted
+	BackchannelLogoutMaxAttempts = 3
+
+	// pairwiseSubjectSaltKey is the key in the KV table of the secret that pairwise subject identifiers are derived with
+	pairwiseSubjectSaltKey = "pairwise_subject_salt"
 )
 
+// SupportedRequestObjectSigningAlgs contains the algorithms that can be used to sign request objects
+var SupportedRequestObjectSigningAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// SupportedDpopSigningAlgs contains the algorithms that can be used to sign DPoP proofs
+var SupportedDpopSigningAlgs = SupportedRequestObjectSigningAlgs
+
+// SupportedResponseModes contains the modes that can be used to return the authorization response to the client, including the JWT-secured modes of JARM
+var SupportedResponseModes = []string{"query", "fragment", "form_post", "query.jwt", "form_post.jwt"}
+
+// SupportedEncryptionAlgs contains the algorithms that can be used to encrypt the content encryption key of ID tokens and userinfo responses
+var SupportedEncryptionAlgs = []string{"RSA-OAEP", "RSA-OAEP-256", "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A256KW"}
+
+// SupportedEncryptionEncs contains the algorithms that can be used to encrypt the content of ID tokens and userinfo responses
+var SupportedEncryptionEncs = []string{"A128CBC-HS256", "A256CBC-HS512", "A128GCM", "A256GCM"}
+
 type OidcService struct {
 	db                 *gorm.DB
 	jwtService         *JwtService
@@ -118,7 +176,7 @@ func (s *OidcService) getJWKCache(ctx context.Context) (*jwk.Cache, error) {
 	)
 }
 
-func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClientRequestDto, userID, ipAddress, userAgent string) (string, string, error) {
+func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClientRequestDto, userID, ipAddress, userAgent string) (*dto.AuthorizeOidcClientResponseDto, error) {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -131,28 +189,99 @@ func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClie
 		First(&client, "id = ?", input.ClientID).
 		Error
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	if client.RequiresReauthentication {
-		if input.ReauthenticationToken == "" {
-			return "", "", &common.ReauthenticationRequiredError{}
-		}
-		err = s.webAuthnService.ConsumeReauthenticationToken(ctx, tx, input.ReauthenticationToken, userID)
-		if err != nil {
-			return "", "", err
-		}
+	// The parameters can also be passed in a pushed authorization request or in a signed request object
+	params, err := s.resolveAuthorizationRequestParameters(ctx, tx, &client, input, true)
+	if err != nil {
+		return nil, err
+	}
+	input.Scope = params.Scope
+	input.CallbackURL = params.CallbackURL
+	input.Nonce = params.Nonce
+	input.CodeChallenge = params.CodeChallenge
+	input.CodeChallengeMethod = params.CodeChallengeMethod
+
+	prompt, err := parsePrompt(params.Prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	claimsRequest, err := parseClaimsRequest(params.Claims)
+	if err != nil {
+		return nil, err
+	}
+
+	responseMode, err := parseResponseMode(params.ResponseMode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.verifyCustomScopesAllowed(ctx, client.ID, input.Scope, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.verifyCustomClaimsAllowed(ctx, client.ID, claimsRequest.ClaimNames(), tx)
+	if err != nil {
+		return nil, err
 	}
 
 	// If the client is not public, the code challenge must be provided
 	if client.IsPublic && input.CodeChallenge == "" {
-		return "", "", &common.OidcMissingCodeChallengeError{}
+		return nil, &common.OidcMissingCodeChallengeError{}
 	}
 
 	// Get the callback URL of the client. Return an error if the provided callback URL is not allowed
 	callbackURL, err := s.getCallbackURL(&client, input.CallbackURL, tx, ctx)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+
+	response := &dto.AuthorizeOidcClientResponseDto{
+		CallbackURL:  callbackURL,
+		Issuer:       common.EnvConfig.AppURL,
+		State:        params.State,
+		ResponseMode: responseMode,
+	}
+
+	// With prompt=none, the errors that require user interaction are returned to the client's callback URL
+	if userID == "" {
+		if prompt.none {
+			response.Error = "login_required"
+			return s.secureAuthorizationResponse(&client, response)
+		}
+		return nil, &common.NotSignedInError{}
+	}
+
+	// A fresh passkey assertion is required if the client or the request asks for it, or if the user signed in too long ago
+	auth := AuthenticationInfo{
+		Time:    input.AuthTime,
+		Methods: input.AuthenticationMethods,
+	}
+	if client.RequiresReauthentication || prompt.login || isMaxAgeExceeded(params.MaxAge, auth.Time) {
+		if prompt.none {
+			response.Error = "login_required"
+			return s.secureAuthorizationResponse(&client, response)
+		}
+		if input.ReauthenticationToken == "" {
+			return nil, &common.ReauthenticationRequiredError{}
+		}
+		methods, err := s.webAuthnService.ConsumeReauthenticationToken(ctx, tx, input.ReauthenticationToken, userID)
+		if err != nil {
+			return nil, err
+		}
+		auth = AuthenticationInfo{Time: time.Now(), Methods: methods}
+	}
+
+	// Sessions can't be stepped up, so the user needs to sign in again with a stronger method if the session doesn't satisfy the requested authentication context class
+	if !isAcrSatisfied(&client, params.AcrValues, auth) {
+		if prompt.none {
+			response.Error = "login_required"
+			return s.secureAuthorizationResponse(&client, response)
+		}
+		return nil, &common.OidcAcrNotSatisfiedError{}
 	}
 
 	// Check if the user group is allowed to authorize the client
@@ -163,22 +292,35 @@ func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClie
 		First(&user, "id = ?", userID).
 		Error
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	if !s.IsUserGroupAllowedToAuthorize(user, client) {
-		return "", "", &common.OidcAccessDeniedError{}
+		return nil, &common.OidcAccessDeniedError{}
+	}
+
+	// With prompt=none, the user must have already consented to the requested scopes and claims
+	if prompt.none {
+		hasAuthorizedClient, err := s.hasAuthorizedClientInternal(ctx, input.ClientID, userID, input.Scope, claimsRequest, tx)
+		if err != nil {
+			return nil, err
+		}
+		if !hasAuthorizedClient {
+			response.Error = "consent_required"
+			return s.secureAuthorizationResponse(&client, response)
+		}
 	}
 
-	hasAlreadyAuthorizedClient, err := s.createAuthorizedClientInternal(ctx, userID, input.ClientID, input.Scope, tx)
+	// The individual claims are stored with the authorization, like the scopes
+	hasAlreadyAuthorizedClient, err := s.createAuthorizedClientInternal(ctx, userID, input.ClientID, input.Scope, claimsRequest, tx)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	// Create the authorization code
-	code, err := s.createAuthorizationCode(ctx, input.ClientID, userID, input.Scope, input.Nonce, input.CodeChallenge, input.CodeChallengeMethod, tx)
+	code, err := s.createAuthorizationCode(ctx, input.ClientID, userID, input.Scope, claimsRequest, input.Nonce, input.CodeChallenge, input.CodeChallengeMethod, auth, tx)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	// Log the authorization event
@@ -188,171 +330,1279 @@ func (s *OidcService) Authorize(ctx context.Context, input dto.AuthorizeOidcClie
 		s.auditLogService.Create(ctx, model.AuditLogEventNewClientAuthorization, ipAddress, userAgent, userID, model.AuditLogData{"clientName": client.Name}, tx)
 	}
 
+	// The response is signed before committing, so the code can't be used if that fails
+	response.Code = code
+	response, err = s.secureAuthorizationResponse(&client, response)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit().Error
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	return code, callbackURL, nil
+	return response, nil
 }
 
-// HasAuthorizedClient checks if the user has already authorized the client with the given scope
-func (s *OidcService) HasAuthorizedClient(ctx context.Context, clientID, userID, scope string) (bool, error) {
-	return s.hasAuthorizedClientInternal(ctx, clientID, userID, scope, s.db)
+// parseResponseMode validates the "response_mode" parameter, and returns the mode to use
+// The default response mode for the authorization code flow is "query"
+func parseResponseMode(responseMode string) (string, error) {
+	if responseMode == "" {
+		return "query", nil
+	}
+	if !slices.Contains(SupportedResponseModes, responseMode) {
+		return "", &common.OidcUnsupportedResponseModeError{}
+	}
+	return responseMode, nil
 }
 
-func (s *OidcService) hasAuthorizedClientInternal(ctx context.Context, clientID, userID, scope string, tx *gorm.DB) (bool, error) {
-	var userAuthorizedOidcClient model.UserAuthorizedOidcClient
+// secureAuthorizationResponse replaces the parameters of the authorization response with a signed JWT if a JWT response mode is used, as described in JARM
+// The JWT is signed like the ID tokens of the client
+func (s *OidcService) secureAuthorizationResponse(client *model.OidcClient, response *dto.AuthorizeOidcClientResponseDto) (*dto.AuthorizeOidcClientResponseDto, error) {
+	if !strings.HasSuffix(response.ResponseMode, ".jwt") {
+		return response, nil
+	}
+
+	params := make(map[string]string, 3)
+	if response.Code != "" {
+		params["code"] = response.Code
+	}
+	if response.State != "" {
+		params["state"] = response.State
+	}
+	if response.Error != "" {
+		params["error"] = response.Error
+	}
+
+	signed, err := s.jwtService.GenerateAuthorizationResponseToken(params, client.ID, idTokenSigningAlg(client))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign authorization response: %w", err)
+	}
+
+	return &dto.AuthorizeOidcClientResponseDto{
+		CallbackURL:  response.CallbackURL,
+		Issuer:       response.Issuer,
+		ResponseMode: response.ResponseMode,
+		Response:     signed,
+	}, nil
+}
+
+// authorizationPrompt contains the values of the "prompt" parameter of an authorization request
+type authorizationPrompt struct {
+	none    bool
+	login   bool
+	consent bool
+}
+
+// parsePrompt parses the space-separated values of the "prompt" parameter
+// Unsupported values, such as "select_account", are ignored
+func parsePrompt(prompt string) (p authorizationPrompt, err error) {
+	values := strings.Fields(prompt)
+	for _, v := range values {
+		switch v {
+		case "none":
+			p.none = true
+		case "login":
+			p.login = true
+		case "consent":
+			p.consent = true
+		}
+	}
+
+	if p.none && len(values) > 1 {
+		return p, &common.ValidationError{Message: "prompt=none can't be combined with other values"}
+	}
+
+	return p, nil
+}
+
+// parseClaimsRequest parses the value of the "claims" parameter, which is a JSON object
+func parseClaimsRequest(claims string) (*model.OidcClaimsRequest, error) {
+	if claims == "" {
+		return nil, nil
+	}
+
+	var claimsRequest model.OidcClaimsRequest
+	err := json.Unmarshal([]byte(claims), &claimsRequest)
+	if err != nil {
+		return nil, &common.ValidationError{Message: "claims must be a valid JSON object"}
+	}
+
+	return &claimsRequest, nil
+}
+
+// isSameClaimsRequest returns true if two values of the "claims" parameter request the same claims, regardless of their encoding
+func isSameClaimsRequest(a string, b string) bool {
+	requestA, errA := parseClaimsRequest(a)
+	requestB, errB := parseClaimsRequest(b)
+	return errA == nil && errB == nil && reflect.DeepEqual(requestA, requestB)
+}
+
+// IsConsentPromptRequested returns true if the "prompt" parameter requires the consent screen to be shown, even if the user has already authorized the client
+func IsConsentPromptRequested(prompt string) bool {
+	p, _ := parsePrompt(prompt)
+	return p.consent
+}
+
+// isAcrSatisfied returns true if the authentication satisfies the minimum authentication context class of the client, and one of the requested ones
+// Requested values that aren't supported are ignored
+func isAcrSatisfied(client *model.OidcClient, acrValues string, auth AuthenticationInfo) bool {
+	if client.MinimumAcr != nil && *client.MinimumAcr != "" && !auth.SatisfiesAcr(*client.MinimumAcr) {
+		return false
+	}
+
+	requested := slices.DeleteFunc(strings.Fields(acrValues), func(acr string) bool {
+		return !slices.Contains(SupportedAcrValues, acr)
+	})
+	if len(requested) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(requested, auth.SatisfiesAcr)
+}
+
+// isMaxAgeExceeded returns true if the user signed in longer ago than the "max_age" parameter allows
+func isMaxAgeExceeded(maxAge *int, authTime time.Time) bool {
+	if maxAge == nil {
+		return false
+	}
+	if authTime.IsZero() {
+		return true
+	}
+	return time.Since(authTime) > time.Duration(*maxAge)*time.Second
+}
+
+// resolveAuthorizationRequestParameters returns the parameters of the authorization request, loading them from a pushed authorization request or a signed request object if needed
+// If consume is false, the pushed authorization request can still be used afterwards
+func (s *OidcService) resolveAuthorizationRequestParameters(ctx context.Context, tx *gorm.DB, client *model.OidcClient, input dto.AuthorizeOidcClientRequestDto, consume bool) (model.OidcAuthorizationRequestParameters, error) {
+	params := model.OidcAuthorizationRequestParameters{
+		Scope:               input.Scope,
+		CallbackURL:         input.CallbackURL,
+		Nonce:               input.Nonce,
+		State:               input.State,
+		CodeChallenge:       input.CodeChallenge,
+		CodeChallengeMethod: input.CodeChallengeMethod,
+		Prompt:              input.Prompt,
+		MaxAge:              input.MaxAge,
+		AcrValues:           input.AcrValues,
+		Claims:              input.Claims,
+		ResponseMode:        input.ResponseMode,
+	}
+
+	switch {
+	case input.RequestURI != "" && input.Request != "":
+		return params, &common.ValidationError{Message: "request and request URI can't be used together"}
+	case input.RequestURI != "" && !consume:
+		return s.getPushedAuthorizationRequest(ctx, tx, client.ID, input.RequestURI)
+	case input.RequestURI != "":
+		// Signed request objects are validated when the request is pushed
+		return s.consumePushedAuthorizationRequest(ctx, tx, client.ID, input.RequestURI)
+	case client.RequiresPushedAuthorizationRequests:
+		return params, &common.OidcPushedAuthorizationRequestRequiredError{}
+	case input.Request != "":
+		return s.resolveRequestObject(ctx, tx, client, input.Request, params, consume)
+	case client.RequiresSignedRequestObject:
+		return params, &common.OidcRequestObjectRequiredError{}
+	default:
+		return params, nil
+	}
+}
+
+// PushAuthorizationRequest stores the parameters of an authorization request sent by a client, as described in RFC 9126
+func (s *OidcService) PushAuthorizationRequest(ctx context.Context, input dto.OidcPushedAuthorizationRequestDto) (*dto.OidcPushedAuthorizationResponseDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	client, err := s.verifyClientCredentialsInternal(ctx, tx, ClientAuthCredentials{
+		ClientID:                       input.ClientID,
+		ClientSecret:                   input.ClientSecret,
+		ClientAssertionType:            input.ClientAssertionType,
+		ClientAssertion:                input.ClientAssertion,
+		ClientCertificate:              input.ClientCertificate,
+		ClientCertificateChainVerified: input.ClientCertificateChainVerified,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	params := model.OidcAuthorizationRequestParameters{
+		Scope:               input.Scope,
+		CallbackURL:         input.CallbackURL,
+		Nonce:               input.Nonce,
+		State:               input.State,
+		CodeChallenge:       input.CodeChallenge,
+		CodeChallengeMethod: input.CodeChallengeMethod,
+		Prompt:              input.Prompt,
+		MaxAge:              input.MaxAge,
+		AcrValues:           input.AcrValues,
+		Claims:              input.Claims,
+		ResponseMode:        input.ResponseMode,
+	}
+
+	// If the parameters are passed in a signed request object, validate it and use the parameters in it
+	if input.Request != "" {
+		params, err = s.resolveRequestObject(ctx, tx, client, input.Request, params, true)
+		if err != nil {
+			return nil, err
+		}
+	} else if client.RequiresSignedRequestObject {
+		return nil, &common.OidcRequestObjectRequiredError{}
+	}
+
+	// If the client is public, the code challenge must be provided
+	if client.IsPublic && params.CodeChallenge == "" {
+		return nil, &common.OidcMissingCodeChallengeError{}
+	}
+
+	_, err = parseResponseMode(params.ResponseMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the callback URL now if the client has callback URLs configured
+	// Otherwise, it's validated when the request is used in the authorization endpoint
+	if params.CallbackURL != "" && len(client.CallbackURLs) > 0 {
+		matched, err := s.getCallbackURLFromList(client.CallbackURLs, params.CallbackURL)
+		if err != nil {
+			return nil, err
+		} else if matched == "" {
+			return nil, &common.OidcInvalidCallbackURLError{}
+		}
+	}
+
+	randomString, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	pushedRequest := model.OidcPushedAuthorizationRequest{
+		RequestURI: PushedAuthorizationRequestURIPrefix + randomString,
+		Parameters: params,
+		ExpiresAt:  datatype.DateTime(time.Now().Add(PushedAuthorizationRequestDuration)),
+		ClientID:   client.ID,
+	}
+
+	err = tx.
+		WithContext(ctx).
+		Create(&pushedRequest).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.OidcPushedAuthorizationResponseDto{
+		RequestURI: pushedRequest.RequestURI,
+		ExpiresIn:  int(PushedAuthorizationRequestDuration.Seconds()),
+	}, nil
+}
+
+// consumePushedAuthorizationRequest loads the parameters of a pushed authorization request and deletes it, so it can only be used once
+func (s *OidcService) consumePushedAuthorizationRequest(ctx context.Context, tx *gorm.DB, clientID string, requestURI string) (model.OidcAuthorizationRequestParameters, error) {
+	var pushedRequest model.OidcPushedAuthorizationRequest
+	result := tx.
+		WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Delete(&pushedRequest, "request_uri = ? AND client_id = ? AND expires_at > ?", requestURI, clientID, datatype.DateTime(time.Now()))
+	if result.Error != nil {
+		return model.OidcAuthorizationRequestParameters{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return model.OidcAuthorizationRequestParameters{}, &common.OidcInvalidRequestURIError{}
+	}
+
+	return pushedRequest.Parameters, nil
+}
+
+// getPushedAuthorizationRequest loads the parameters of a pushed authorization request without deleting it
+func (s *OidcService) getPushedAuthorizationRequest(ctx context.Context, tx *gorm.DB, clientID string, requestURI string) (model.OidcAuthorizationRequestParameters, error) {
+	var pushedRequest model.OidcPushedAuthorizationRequest
 	err := tx.
 		WithContext(ctx).
-		First(&userAuthorizedOidcClient, "client_id = ? AND user_id = ?", clientID, userID).
+		First(&pushedRequest, "request_uri = ? AND client_id = ? AND expires_at > ?", requestURI, clientID, datatype.DateTime(time.Now())).
 		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.OidcAuthorizationRequestParameters{}, &common.OidcInvalidRequestURIError{}
+	} else if err != nil {
+		return model.OidcAuthorizationRequestParameters{}, err
+	}
+
+	return pushedRequest.Parameters, nil
+}
+
+// resolveRequestObject validates a signed request object, as described in RFC 9101, and returns the authorization request parameters it contains
+// Parameters that are passed outside of the request object must match the ones in it
+// If consume is true, the request object can't be used again
+func (s *OidcService) resolveRequestObject(ctx context.Context, tx *gorm.DB, client *model.OidcClient, requestObject string, outerParams model.OidcAuthorizationRequestParameters, consume bool) (model.OidcAuthorizationRequestParameters, error) {
+	params, err := s.verifyRequestObject(ctx, tx, client, requestObject, consume)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		return false, err
+		slog.WarnContext(ctx, "Invalid request object for client", slog.String("client", client.ID), slog.Any("error", err))
+		return model.OidcAuthorizationRequestParameters{}, &common.OidcInvalidRequestObjectError{}
 	}
 
-	if userAuthorizedOidcClient.Scope != scope {
-		return false, nil
+	mismatched := (outerParams.Scope != "" && outerParams.Scope != params.Scope) ||
+		(outerParams.CallbackURL != "" && outerParams.CallbackURL != params.CallbackURL) ||
+		(outerParams.Nonce != "" && outerParams.Nonce != params.Nonce) ||
+		(outerParams.State != "" && outerParams.State != params.State) ||
+		(outerParams.CodeChallenge != "" && outerParams.CodeChallenge != params.CodeChallenge) ||
+		(outerParams.CodeChallengeMethod != "" && outerParams.CodeChallengeMethod != params.CodeChallengeMethod) ||
+		(outerParams.Prompt != "" && outerParams.Prompt != params.Prompt) ||
+		(outerParams.AcrValues != "" && outerParams.AcrValues != params.AcrValues) ||
+		(outerParams.Claims != "" && !isSameClaimsRequest(outerParams.Claims, params.Claims)) ||
+		(outerParams.ResponseMode != "" && outerParams.ResponseMode != params.ResponseMode) ||
+		(outerParams.MaxAge != nil && (params.MaxAge == nil || *outerParams.MaxAge != *params.MaxAge))
+	if mismatched {
+		slog.WarnContext(ctx, "Request parameters don't match the ones in the request object", slog.String("client", client.ID))
+		return model.OidcAuthorizationRequestParameters{}, &common.OidcInvalidRequestObjectError{}
 	}
 
-	return true, nil
+	if params.Scope == "" {
+		return model.OidcAuthorizationRequestParameters{}, &common.ValidationError{Message: "scope is required"}
+	}
+
+	return params, nil
 }
 
-// IsUserGroupAllowedToAuthorize checks if the user group of the user is allowed to authorize the client
-func (s *OidcService) IsUserGroupAllowedToAuthorize(user model.User, client model.OidcClient) bool {
-	if len(client.AllowedUserGroups) == 0 {
-		return true
+func (s *OidcService) verifyRequestObject(ctx context.Context, tx *gorm.DB, client *model.OidcClient, requestObject string, consume bool) (params model.OidcAuthorizationRequestParameters, err error) {
+	if client.JwksURL == nil || *client.JwksURL == "" {
+		return params, errors.New("client does not have a JWKS URL configured")
 	}
 
-	isAllowedToAuthorize := false
-	for _, userGroup := range client.AllowedUserGroups {
-		for _, userGroupUser := range user.UserGroups {
-			if userGroup.ID == userGroupUser.ID {
-				isAllowedToAuthorize = true
-				break
-			}
+	// Check that the request object is signed with one of the supported algorithms
+	// This also rejects unsigned request objects, which use the "none" algorithm
+	msg, err := jws.Parse([]byte(requestObject))
+	if err != nil {
+		return params, fmt.Errorf("failed to parse request object: %w", err)
+	}
+	signatures := msg.Signatures()
+	if len(signatures) != 1 {
+		return params, errors.New("request object must have exactly one signature")
+	}
+	alg, ok := signatures[0].ProtectedHeaders().Algorithm()
+	if !ok || !slices.Contains(SupportedRequestObjectSigningAlgs, alg.String()) {
+		return params, fmt.Errorf("unsupported request object signing algorithm: %v", alg)
+	}
+
+	jwks, err := s.jwkSetForURL(ctx, *client.JwksURL)
+	if err != nil {
+		return params, fmt.Errorf("failed to get JWK set for client: %w", err)
+	}
+
+	// Per spec, the issuer is the client and the audience is Pocket ID
+	token, err := jwt.Parse([]byte(requestObject),
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(clockSkew),
+		jwt.WithKeySet(jwks, jws.WithInferAlgorithmFromKey(true), jws.WithUseDefault(true)),
+		jwt.WithIssuer(client.ID),
+		jwt.WithAudience(common.EnvConfig.AppURL),
+	)
+	if err != nil {
+		return params, fmt.Errorf("request object is not valid: %w", err)
+	}
+
+	clientID := getStringClaim(token, "client_id")
+	if clientID != "" && clientID != client.ID {
+		return params, errors.New("client ID in request object does not match")
+	}
+
+	// Request objects must expire and have an identifier, so they can't be replayed
+	expiration, ok := token.Expiration()
+	if !ok {
+		return params, errors.New("request object does not contain an 'exp' claim")
+	}
+	jti, ok := token.JwtID()
+	if !ok || jti == "" {
+		return params, errors.New("request object does not contain a 'jti' claim")
+	}
+
+	if consume {
+		err = tx.
+			WithContext(ctx).
+			Create(&model.OidcUsedAssertion{
+				JwtIDHash: utils.CreateSha256Hash("request-object:" + client.ID + ":" + jti),
+				ExpiresAt: datatype.DateTime(expiration.Add(clockSkew)),
+			}).
+			Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return params, errors.New("request object has already been used")
+		} else if err != nil {
+			return params, fmt.Errorf("failed to store request object identifier: %w", err)
 		}
 	}
 
-	return isAllowedToAuthorize
+	return model.OidcAuthorizationRequestParameters{
+		Scope:               getStringClaim(token, "scope"),
+		CallbackURL:         getStringClaim(token, "redirect_uri"),
+		Nonce:               getStringClaim(token, "nonce"),
+		State:               getStringClaim(token, "state"),
+		CodeChallenge:       getStringClaim(token, "code_challenge"),
+		CodeChallengeMethod: getStringClaim(token, "code_challenge_method"),
+		Prompt:              getStringClaim(token, "prompt"),
+		MaxAge:              getIntClaim(token, "max_age"),
+		AcrValues:           getStringClaim(token, "acr_values"),
+		Claims:              getJSONClaim(token, "claims"),
+		ResponseMode:        getStringClaim(token, "response_mode"),
+	}, nil
+}
+
+// ValidateDpopProof validates a DPoP proof (RFC 9449) sent with a request to the given path, and returns the JWK thumbprint of its key.
+// If accessToken is not empty, the proof must be bound to that access token.
+func (s *OidcService) ValidateDpopProof(ctx context.Context, proof string, method string, path string, accessToken string) (string, error) {
+	jkt, err := s.verifyDpopProof(ctx, proof, method, path, accessToken)
+	if err != nil {
+		slog.WarnContext(ctx, "Invalid DPoP proof", slog.Any("error", err))
+		return "", &common.OidcInvalidDpopProofError{}
+	}
+
+	return jkt, nil
 }
 
-type CreatedTokens struct {
-	IdToken      string
-	AccessToken  string
-	RefreshToken string
-	ExpiresIn    time.Duration
-}
+// VerifyAccessTokenDpopBinding checks that an access token bound to a DPoP key is presented with the DPoP scheme and a valid proof for that key.
+// Access tokens that aren't bound are always accepted.
+func (s *OidcService) VerifyAccessTokenDpopBinding(ctx context.Context, token jwt.Token, scheme string, accessToken string, proof string, method string, path string) error {
+	jkt := GetTokenConfirmation(token).DpopJkt
+	if jkt == "" {
+		return nil
+	}
+
+	if !strings.EqualFold(scheme, "DPoP") || proof == "" {
+		return &common.OidcInvalidDpopProofError{}
+	}
+
+	proofJkt, err := s.ValidateDpopProof(ctx, proof, method, path, accessToken)
+	if err != nil {
+		return err
+	}
+	if proofJkt != jkt {
+		return &common.OidcInvalidDpopProofError{}
+	}
+
+	return nil
+}
+
+func (s *OidcService) verifyDpopProof(ctx context.Context, proof string, method string, path string, accessToken string) (string, error) {
+	msg, err := jws.Parse([]byte(proof))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse DPoP proof: %w", err)
+	}
+	signatures := msg.Signatures()
+	if len(signatures) != 1 {
+		return "", errors.New("DPoP proof must have exactly one signature")
+	}
+	headers := signatures[0].ProtectedHeaders()
+
+	typ, _ := headers.Type()
+	if typ != "dpop+jwt" {
+		return "", fmt.Errorf("invalid DPoP proof type: %s", typ)
+	}
+	alg, ok := headers.Algorithm()
+	if !ok || !slices.Contains(SupportedDpopSigningAlgs, alg.String()) {
+		return "", fmt.Errorf("unsupported DPoP proof signing algorithm: %v", alg)
+	}
+
+	// The proof is signed with the key in its own header, which must be a public key
+	key, ok := headers.JWK()
+	if !ok {
+		return "", errors.New("DPoP proof does not contain a key")
+	}
+	isPrivate, err := jwk.IsPrivateKey(key)
+	if err != nil || isPrivate {
+		return "", errors.New("DPoP proof key must be a public key")
+	}
+
+	token, err := jwt.Parse([]byte(proof),
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(clockSkew),
+		jwt.WithKey(alg, key),
+	)
+	if err != nil {
+		return "", fmt.Errorf("DPoP proof is not valid: %w", err)
+	}
+
+	jti, ok := token.JwtID()
+	if !ok || jti == "" {
+		return "", errors.New("DPoP proof does not contain a 'jti' claim")
+	}
+	issuedAt, ok := token.IssuedAt()
+	now := time.Now()
+	if !ok || issuedAt.Before(now.Add(-DpopProofLifetime)) || issuedAt.After(now.Add(clockSkew)) {
+		return "", errors.New("DPoP proof is expired or issued in the future")
+	}
+
+	if getStringClaim(token, "htm") != method {
+		return "", errors.New("DPoP proof 'htm' claim does not match the request method")
+	}
+	htu, err := url.Parse(getStringClaim(token, "htu"))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse DPoP proof 'htu' claim: %w", err)
+	}
+	htu.RawQuery = ""
+	htu.Fragment = ""
+	if htu.String() != common.EnvConfig.AppURL+path && htu.String() != common.EnvConfig.InternalAppURL+path {
+		return "", errors.New("DPoP proof 'htu' claim does not match the request URL")
+	}
+
+	if accessToken != "" {
+		ath := sha256.Sum256([]byte(accessToken))
+		if getStringClaim(token, "ath") != base64.RawURLEncoding.EncodeToString(ath[:]) {
+			return "", errors.New("DPoP proof 'ath' claim does not match the access token")
+		}
+	}
+
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute DPoP key thumbprint: %w", err)
+	}
+	jkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	// Store the proof's identifier until it expires, so it can't be replayed
+	err = s.db.
+		WithContext(ctx).
+		Create(&model.OidcDpopProof{
+			JwtIDHash: utils.CreateSha256Hash(jkt + ":" + jti),
+			ExpiresAt: datatype.DateTime(issuedAt.Add(DpopProofLifetime + clockSkew)),
+		}).
+		Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return "", errors.New("DPoP proof has already been used")
+	} else if err != nil {
+		return "", fmt.Errorf("failed to store DPoP proof: %w", err)
+	}
+
+	return jkt, nil
+}
+
+// getStringClaim returns the value of a string claim, or an empty string if it's missing or not a string
+func getStringClaim(token jwt.Token, name string) string {
+	var value string
+	if !token.Has(name) || token.Get(name, &value) != nil {
+		return ""
+	}
+	return value
+}
+
+// getJSONClaim returns the value of a claim that contains a JSON object, encoded as a string
+func getJSONClaim(token jwt.Token, name string) string {
+	var value map[string]any
+	if !token.Has(name) || token.Get(name, &value) != nil {
+		return ""
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func getIntClaim(token jwt.Token, name string) *int {
+	var value float64
+	if !token.Has(name) || token.Get(name, &value) != nil || value < 0 {
+		return nil
+	}
+	return utils.Ptr(int(value))
+}
+
+// IsAuthorizationRequired checks if the user needs to confirm the authorization request on the consent screen
+// The request is resolved like in the authorization endpoint, but pushed authorization requests and request objects aren't consumed, as they're used again once the user authorizes the client
+func (s *OidcService) IsAuthorizationRequired(ctx context.Context, input dto.AuthorizationRequiredDto, userID string) (*dto.AuthorizationRequiredResponseDto, error) {
+	var client model.OidcClient
+	err := s.db.
+		WithContext(ctx).
+		First(&client, "id = ?", input.ClientID).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := s.resolveAuthorizationRequestParameters(ctx, s.db, &client, dto.AuthorizeOidcClientRequestDto{
+		ClientID:   input.ClientID,
+		Scope:      input.Scope,
+		Prompt:     input.Prompt,
+		Claims:     input.Claims,
+		RequestURI: input.RequestURI,
+		Request:    input.Request,
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.AuthorizationRequiredResponseDto{
+		Scope:  params.Scope,
+		Claims: params.Claims,
+		Prompt: params.Prompt,
+	}
+
+	// With prompt=consent, the consent screen is shown even if the user has already authorized the client
+	if IsConsentPromptRequested(params.Prompt) {
+		response.AuthorizationRequired = true
+		return response, nil
+	}
+
+	hasAuthorizedClient, err := s.HasAuthorizedClient(ctx, client.ID, userID, params.Scope, params.Claims)
+	if err != nil {
+		return nil, err
+	}
+	response.AuthorizationRequired = !hasAuthorizedClient
+
+	return response, nil
+}
+
+// HasAuthorizedClient checks if the user has already authorized the client with the given scope and the individual claims of the "claims" parameter
+func (s *OidcService) HasAuthorizedClient(ctx context.Context, clientID, userID, scope string, claims string) (bool, error) {
+	claimsRequest, err := parseClaimsRequest(claims)
+	if err != nil {
+		return false, err
+	}
+
+	return s.hasAuthorizedClientInternal(ctx, clientID, userID, scope, claimsRequest, s.db)
+}
+
+func (s *OidcService) hasAuthorizedClientInternal(ctx context.Context, clientID, userID, scope string, claimsRequest *model.OidcClaimsRequest, tx *gorm.DB) (bool, error) {
+	var userAuthorizedOidcClient model.UserAuthorizedOidcClient
+	err := tx.
+		WithContext(ctx).
+		First(&userAuthorizedOidcClient, "client_id = ? AND user_id = ?", clientID, userID).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if userAuthorizedOidcClient.Scope != scope {
+		return false, nil
+	}
+
+	// Individual claims are released regardless of the scopes, so the user must have consented to each of them
+	consentedClaims := userAuthorizedOidcClient.Claims.ClaimNames()
+	for _, name := range claimsRequest.ClaimNames() {
+		if !slices.Contains(consentedClaims, name) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// IsUserGroupAllowedToAuthorize checks if the user group of the user is allowed to authorize the client
+func (s *OidcService) IsUserGroupAllowedToAuthorize(user model.User, client model.OidcClient) bool {
+	if len(client.AllowedUserGroups) == 0 {
+		return true
+	}
+
+	isAllowedToAuthorize := false
+	for _, userGroup := range client.AllowedUserGroups {
+		for _, userGroupUser := range user.UserGroups {
+			if userGroup.ID == userGroupUser.ID {
+				isAllowedToAuthorize = true
+				break
+			}
+		}
+	}
+
+	return isAllowedToAuthorize
+}
+
+// clientTokenLifetime returns the lifetime of a token as configured for the client, or the default lifetime if it's not set
+func clientTokenLifetime(seconds *int, defaultLifetime time.Duration) time.Duration {
+	if seconds == nil || *seconds <= 0 {
+		return defaultLifetime
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
+type CreatedTokens struct {
+	IdToken      string
+	AccessToken  string
+	RefreshToken string
+	TokenType    string
+	ExpiresIn    time.Duration
+	// IssuedTokenType is set for the token exchange grant only
+	IssuedTokenType string
+}
+
+func (s *OidcService) CreateTokens(ctx context.Context, input dto.OidcCreateTokensDto) (tokens CreatedTokens, err error) {
+	// If the client sent a DPoP proof, the tokens are bound to its key
+	var cnf TokenConfirmation
+	if input.DpopProof != "" {
+		cnf.DpopJkt, err = s.ValidateDpopProof(ctx, input.DpopProof, http.MethodPost, "/api/oidc/token", "")
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+	}
+
+	// If the request was made over a mutual-TLS connection, the access tokens are bound to the client certificate
+	if input.ClientCertificate != nil {
+		cnf.CertificateThumbprint = utils.CertificateThumbprint(input.ClientCertificate)
+	}
+
+	switch input.GrantType {
+	case GrantTypeAuthorizationCode:
+		tokens, err = s.createTokenFromAuthorizationCode(ctx, input, cnf)
+	case GrantTypeRefreshToken:
+		tokens, err = s.createTokenFromRefreshToken(ctx, input, cnf)
+	case GrantTypeDeviceCode:
+		tokens, err = s.createTokenFromDeviceCode(ctx, input, cnf)
+	case GrantTypeClientCredentials:
+		tokens, err = s.createTokenFromClientCredentials(ctx, input, cnf)
+	case GrantTypeTokenExchange:
+		tokens, err = s.createTokenFromTokenExchange(ctx, input, cnf)
+	case GrantTypeJWTBearer:
+		tokens, err = s.createTokenFromJWTBearer(ctx, input, cnf)
+	case GrantTypeCIBA:
+		tokens, err = s.createTokenFromBackchannelAuthentication(ctx, input, cnf)
+	default:
+		return CreatedTokens{}, &common.OidcGrantTypeNotSupportedError{}
+	}
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	tokens.TokenType = "Bearer"
+	if cnf.DpopJkt != "" {
+		tokens.TokenType = "DPoP"
+	}
+
+	return tokens, nil
+}
+
+// checkDpopRequired returns an error if the client requires DPoP-bound tokens but no DPoP proof was sent
+func checkDpopRequired(client *model.OidcClient, dpopJkt string) error {
+	if client.RequiresDpop && dpopJkt == "" {
+		return &common.OidcDpopRequiredError{}
+	}
+	return nil
+}
+
+func (s *OidcService) createTokenFromDeviceCode(ctx context.Context, input dto.OidcCreateTokensDto, cnf TokenConfirmation) (CreatedTokens, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	client, err := s.verifyClientCredentialsInternal(ctx, tx, clientAuthCredentialsFromCreateTokensDto(&input), true)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	err = checkDpopRequired(client, cnf.DpopJkt)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// Get the device authorization from database with explicit query conditions
+	var deviceAuth model.OidcDeviceCode
+	err = tx.
+		WithContext(ctx).
+		Preload("User").
+		Where("device_code = ? AND client_id = ?", input.DeviceCode, input.ClientID).
+		First(&deviceAuth).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return CreatedTokens{}, &common.OidcInvalidDeviceCodeError{}
+		}
+		return CreatedTokens{}, err
+	}
+
+	// Check if device code has expired
+	if time.Now().After(deviceAuth.ExpiresAt.ToTime()) {
+		return CreatedTokens{}, &common.OidcDeviceCodeExpiredError{}
+	}
+
+	// Check if device code has been authorized
+	if !deviceAuth.IsAuthorized || deviceAuth.UserID == nil {
+		return CreatedTokens{}, &common.OidcAuthorizationPendingError{}
+	}
+
+	// Get user claims for the ID token - ensure UserID is not nil
+	if deviceAuth.UserID == nil {
+		return CreatedTokens{}, &common.OidcAuthorizationPendingError{}
+	}
+
+	userClaims, err := s.getUserClaimsForClientInternal(ctx, *deviceAuth.UserID, input.ClientID, nil, tx)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// Explicitly use the input clientID for the audience claim to ensure consistency
+	encryption, err := s.getIDTokenEncryption(ctx, client)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+	auth := authenticationInfoFromModel(deviceAuth.AuthTime, deviceAuth.AuthenticationMethods)
+	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, "", auth, clientTokenLifetime(client.IdTokenLifetime, IdTokenDuration), idTokenSigningAlg(client), encryption)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	var refreshToken string
+	if issuesRefreshToken(client, deviceAuth.Scope) {
+		refreshToken, err = s.createRefreshToken(ctx, client, *deviceAuth.UserID, deviceAuth.Scope, nil, auth, cnf.DpopJkt, nil, tx)
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+	}
+
+	subject, err := s.getSubject(ctx, client, *deviceAuth.UserID, tx)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	accessTokenLifetime := clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration)
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(subject, input.ClientID, deviceAuth.Scope, cnf, accessTokenLifetime, idTokenSigningAlg(client))
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// Delete the used device code
+	err = tx.WithContext(ctx).Delete(&deviceAuth).Error
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	return CreatedTokens{
+		IdToken:      idToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    accessTokenLifetime,
+	}, nil
+}
+
+// createTokenFromBackchannelAuthentication issues the tokens of a CIBA request once the user approved it, as described in the poll mode of CIBA
+func (s *OidcService) createTokenFromBackchannelAuthentication(ctx context.Context, input dto.OidcCreateTokensDto, cnf TokenConfirmation) (CreatedTokens, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	client, err := s.verifyClientCredentialsInternal(ctx, tx, clientAuthCredentialsFromCreateTokensDto(&input), false)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// Requests created before backchannel authentication was disabled for the client can't be used anymore
+	if !client.BackchannelAuthenticationEnabled {
+		return CreatedTokens{}, &common.OidcBackchannelAuthenticationNotAllowedError{}
+	}
+
+	err = checkDpopRequired(client, cnf.DpopJkt)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	var authRequest model.OidcBackchannelAuthenticationRequest
+	err = tx.
+		WithContext(ctx).
+		Preload("User").
+		Where("auth_req_id = ? AND client_id = ?", input.AuthReqID, client.ID).
+		First(&authRequest).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return CreatedTokens{}, &common.OidcInvalidAuthReqIDError{}
+		}
+		return CreatedTokens{}, err
+	}
+
+	switch {
+	case time.Now().After(authRequest.ExpiresAt.ToTime()):
+		return CreatedTokens{}, &common.OidcAuthReqIDExpiredError{}
+	case authRequest.IsDenied:
+		return CreatedTokens{}, &common.OidcBackchannelAuthenticationDeniedError{}
+	case !authRequest.IsAuthorized:
+		return CreatedTokens{}, &common.OidcAuthorizationPendingError{}
+	}
+
+	userClaims, err := s.getUserClaimsForClientInternal(ctx, authRequest.UserID, client.ID, nil, tx)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	auth := authenticationInfoFromModel(authRequest.AuthTime, authRequest.AuthenticationMethods)
+	encryption, err := s.getIDTokenEncryption(ctx, client)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+	idToken, err := s.jwtService.GenerateIDToken(userClaims, client.ID, "", auth, clientTokenLifetime(client.IdTokenLifetime, IdTokenDuration), idTokenSigningAlg(client), encryption)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	var refreshToken string
+	if issuesRefreshToken(client, authRequest.Scope) {
+		refreshToken, err = s.createRefreshToken(ctx, client, authRequest.UserID, authRequest.Scope, nil, auth, cnf.DpopJkt, nil, tx)
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+	}
+
+	subject, err := s.getSubject(ctx, client, authRequest.UserID, tx)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	accessTokenLifetime := clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration)
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(subject, client.ID, authRequest.Scope, cnf, accessTokenLifetime, idTokenSigningAlg(client))
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// The request can only be used once
+	err = tx.WithContext(ctx).Delete(&authRequest).Error
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	return CreatedTokens{
+		IdToken:      idToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    accessTokenLifetime,
+	}, nil
+}
+
+func (s *OidcService) createTokenFromClientCredentials(ctx context.Context, input dto.OidcCreateTokensDto, cnf TokenConfirmation) (CreatedTokens, error) {
+	client, err := s.verifyClientCredentialsInternal(ctx, s.db, clientAuthCredentialsFromCreateTokensDto(&input), false)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	err = checkDpopRequired(client, cnf.DpopJkt)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	err = s.verifyCustomScopesAllowed(ctx, client.ID, input.Scope, s.db)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// The prefix is used to take those security considerations into account:
+	// https://datatracker.ietf.org/doc/html/rfc9068#name-security-considerations
+	subject := "client-" + client.ID
+
+	audClaim := client.ID
+	if input.Resource != "" {
+		audClaim = input.Resource
+	}
+
+	accessTokenLifetime := clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration)
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(subject, audClaim, input.Scope, cnf, accessTokenLifetime, idTokenSigningAlg(client))
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	return CreatedTokens{
+		AccessToken: accessToken,
+		ExpiresIn:   accessTokenLifetime,
+	}, nil
+}
+
+// createTokenFromTokenExchange exchanges a token for an access token targeted at another audience, as described in RFC 8693
+func (s *OidcService) createTokenFromTokenExchange(ctx context.Context, input dto.OidcCreateTokensDto, cnf TokenConfirmation) (CreatedTokens, error) {
+	client, err := s.verifyClientCredentialsInternal(ctx, s.db, clientAuthCredentialsFromCreateTokensDto(&input), false)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	err = checkDpopRequired(client, cnf.DpopJkt)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	if input.RequestedTokenType != "" && input.RequestedTokenType != TokenTypeAccessToken {
+		return CreatedTokens{}, &common.OidcUnsupportedRequestedTokenTypeError{}
+	}
+
+	// The client can only request tokens for the audiences it's allowed to
+	audience := input.Audience
+	if audience == "" {
+		audience = input.Resource
+	}
+	if audience == "" || !slices.Contains(client.TokenExchangeAudiences, audience) {
+		return CreatedTokens{}, &common.OidcTokenExchangeAudienceNotAllowedError{}
+	}
+
+	subject, subjectToken, err := s.verifyExchangedToken(ctx, client, input.SubjectToken, input.SubjectTokenType)
+	if err != nil {
+		slog.WarnContext(ctx, "Invalid subject token for token exchange", slog.String("client", client.ID), slog.Any("error", err))
+		return CreatedTokens{}, &common.OidcInvalidSubjectTokenError{}
+	}
+
+	// If the subject token is bound to a key, the new token must be requested with proof of possession of the same key, and stays bound to it
+	subjectCnf := GetTokenConfirmation(subjectToken)
+	if (subjectCnf.DpopJkt != "" && subjectCnf.DpopJkt != cnf.DpopJkt) ||
+		(subjectCnf.CertificateThumbprint != "" && subjectCnf.CertificateThumbprint != cnf.CertificateThumbprint) {
+		slog.WarnContext(ctx, "Token exchange request does not prove possession of the subject token's key", slog.String("client", client.ID))
+		return CreatedTokens{}, &common.OidcInvalidSubjectTokenError{}
+	}
+
+	// The new token can only be restricted to a subset of the scopes of the subject token
+	scope := getStringClaim(subjectToken, ScopeClaim)
+	if input.Scope != "" {
+		allowedScopes := strings.Fields(scope)
+		for _, requested := range strings.Fields(input.Scope) {
+			if !slices.Contains(allowedScopes, requested) {
+				return CreatedTokens{}, &common.OidcInvalidScopeError{}
+			}
+		}
+		scope = input.Scope
+	}
+
+	// The exchanging client must itself be allowed to request the custom scopes
+	err = s.verifyCustomScopesAllowed(ctx, client.ID, scope, s.db)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// If the audience is a client with pairwise subject identifiers, the token contains the user's identifier for that client
+	subjectClient := client
+	var audienceClient model.OidcClient
+	err = s.db.WithContext(ctx).First(&audienceClient, "id = ?", audience).Error
+	if err == nil {
+		subjectClient = &audienceClient
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return CreatedTokens{}, fmt.Errorf("failed to load audience client: %w", err)
+	}
+	subject, err = s.getSubject(ctx, subjectClient, subject, s.db)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	claims := make(map[string]any, 1)
+
+	// If the subject token was itself obtained through delegation, the chain of actors is kept
+	var act map[string]any
+	if subjectToken.Has(ActorClaim) {
+		_ = subjectToken.Get(ActorClaim, &act)
+	}
+	if input.ActorToken != "" {
+		actorSubject, _, err := s.verifyExchangedToken(ctx, client, input.ActorToken, input.ActorTokenType)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid actor token for token exchange", slog.String("client", client.ID), slog.Any("error", err))
+			return CreatedTokens{}, &common.OidcInvalidActorTokenError{}
+		}
+
+		actorSubject, err = s.getSubject(ctx, subjectClient, actorSubject, s.db)
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+
+		// The current actor is the outermost one, and prior actors are nested
+		newAct := map[string]any{"sub": actorSubject}
+		if act != nil {
+			newAct[ActorClaim] = act
+		}
+		act = newAct
+	}
+	if act != nil {
+		claims[ActorClaim] = act
+	}
+
+	accessTokenLifetime := clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration)
+	accessToken, err := s.jwtService.GenerateOAuthAccessTokenWithClaims(subject, audience, scope, cnf, accessTokenLifetime, idTokenSigningAlg(client), claims)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	return CreatedTokens{
+		AccessToken:     accessToken,
+		ExpiresIn:       accessTokenLifetime,
+		IssuedTokenType: TokenTypeAccessToken,
+	}, nil
+}
+
+// verifyExchangedToken validates a subject or actor token of a token exchange request and returns its subject
+// Tokens can be access tokens issued by Pocket ID to the client, or JWTs issued by an identity provider the client is federated with
+func (s *OidcService) verifyExchangedToken(ctx context.Context, client *model.OidcClient, tokenString string, tokenType string) (string, jwt.Token, error) {
+	if tokenString == "" {
+		return "", nil, errors.New("token is missing")
+	}
+	if tokenType != TokenTypeAccessToken && tokenType != TokenTypeJWT {
+		return "", nil, fmt.Errorf("unsupported token type: %s", tokenType)
+	}
+
+	insecureToken, err := jwt.ParseInsecure([]byte(tokenString))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	if issuer, _ := insecureToken.Issuer(); issuer != common.EnvConfig.AppURL {
+		// If the federated identity doesn't set a subject, it defaults to the client ID, like for the JWT bearer grant
+		token, ocfi, err := s.verifyFederatedToken(ctx, client, tokenString, client.ID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// An identity mapped to a user must pin the subject, so the user can't be impersonated by other tokens of the issuer
+		if ocfi.UserID != "" && ocfi.Subject == "" {
+			return "", nil, errors.New("federated identity mapped to a user does not restrict the subject")
+		}
+
+		err = s.consumeFederatedAssertion(ctx, token)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// If the federated identity is mapped to a user, the token represents that user
+		if ocfi.UserID != "" {
+			_, err = s.getEnabledUser(ctx, ocfi.UserID)
+			if err != nil {
+				return "", nil, err
+			}
+			return ocfi.UserID, token, nil
+		}
+
+		sub, ok := token.Subject()
+		if !ok || sub == "" {
+			return "", nil, errors.New("token does not contain a subject claim")
+		}
+		return FederatedSubjectPrefix + sub, token, nil
+	}
+
+	token, err := s.jwtService.VerifyOAuthAccessToken(tokenString)
+	if err != nil {
+		return "", nil, err
+	}
 
-func (s *OidcService) CreateTokens(ctx context.Context, input dto.OidcCreateTokensDto) (CreatedTokens, error) {
-	switch input.GrantType {
-	case GrantTypeAuthorizationCode:
-		return s.createTokenFromAuthorizationCode(ctx, input)
-	case GrantTypeRefreshToken:
-		return s.createTokenFromRefreshToken(ctx, input)
-	case GrantTypeDeviceCode:
-		return s.createTokenFromDeviceCode(ctx, input)
-	case GrantTypeClientCredentials:
-		return s.createTokenFromClientCredentials(ctx, input)
-	default:
-		return CreatedTokens{}, &common.OidcGrantTypeNotSupportedError{}
+	// The token must have been issued to the client that exchanges it
+	audience, _ := token.Audience()
+	if !slices.Contains(audience, client.ID) {
+		return "", nil, errors.New("token was not issued to the client")
 	}
-}
 
-func (s *OidcService) createTokenFromDeviceCode(ctx context.Context, input dto.OidcCreateTokensDto) (CreatedTokens, error) {
-	tx := s.db.Begin()
-	defer func() {
-		tx.Rollback()
-	}()
+	sub, ok := token.Subject()
+	if !ok || sub == "" {
+		return "", nil, errors.New("token does not contain a subject claim")
+	}
 
-	_, err := s.verifyClientCredentialsInternal(ctx, tx, clientAuthCredentialsFromCreateTokensDto(&input), true)
+	sub, err = s.resolveSubject(ctx, client, sub, s.db)
 	if err != nil {
-		return CreatedTokens{}, err
+		return "", nil, err
 	}
 
-	// Get the device authorization from database with explicit query conditions
-	var deviceAuth model.OidcDeviceCode
-	err = tx.
-		WithContext(ctx).
-		Preload("User").
-		Where("device_code = ? AND client_id = ?", input.DeviceCode, input.ClientID).
-		First(&deviceAuth).
-		Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return CreatedTokens{}, &common.OidcInvalidDeviceCodeError{}
+	// Tokens of users that are disabled or deleted can't be exchanged anymore
+	if isUserSubject(sub) {
+		_, err = s.getEnabledUser(ctx, sub)
+		if err != nil {
+			return "", nil, err
 		}
-		return CreatedTokens{}, err
 	}
 
-	// Check if device code has expired
-	if time.Now().After(deviceAuth.ExpiresAt.ToTime()) {
-		return CreatedTokens{}, &common.OidcDeviceCodeExpiredError{}
-	}
+	return sub, token, nil
+}
 
-	// Check if device code has been authorized
-	if !deviceAuth.IsAuthorized || deviceAuth.UserID == nil {
-		return CreatedTokens{}, &common.OidcAuthorizationPendingError{}
+// consumeFederatedAssertion records the identifier of a JWT issued by a federated identity provider until the JWT expires, so it can't be replayed
+func (s *OidcService) consumeFederatedAssertion(ctx context.Context, token jwt.Token) error {
+	jti, ok := token.JwtID()
+	if !ok || jti == "" {
+		return errors.New("token does not contain a 'jti' claim")
 	}
-
-	// Get user claims for the ID token - ensure UserID is not nil
-	if deviceAuth.UserID == nil {
-		return CreatedTokens{}, &common.OidcAuthorizationPendingError{}
+	expiration, ok := token.Expiration()
+	if !ok {
+		return errors.New("token does not contain an 'exp' claim")
 	}
+	issuer, _ := token.Issuer()
 
-	userClaims, err := s.getUserClaimsForClientInternal(ctx, *deviceAuth.UserID, input.ClientID, tx)
-	if err != nil {
-		return CreatedTokens{}, err
+	err := s.db.
+		WithContext(ctx).
+		Create(&model.OidcUsedAssertion{
+			JwtIDHash: utils.CreateSha256Hash(issuer + ":" + jti),
+			ExpiresAt: datatype.DateTime(expiration.Add(clockSkew)),
+		}).
+		Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errors.New("token has already been used")
+	} else if err != nil {
+		return fmt.Errorf("failed to store token identifier: %w", err)
 	}
 
-	// Explicitly use the input clientID for the audience claim to ensure consistency
-	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, "")
+	return nil
+}
+
+// getEnabledUser loads a user, returning an error if the user doesn't exist or is disabled
+func (s *OidcService) getEnabledUser(ctx context.Context, userID string) (user model.User, err error) {
+	err = s.db.
+		WithContext(ctx).
+		First(&user, "id = ?", userID).
+		Error
 	if err != nil {
-		return CreatedTokens{}, err
+		return model.User{}, fmt.Errorf("failed to load user: %w", err)
+	}
+	if user.Disabled {
+		return model.User{}, errors.New("user is disabled")
 	}
 
-	refreshToken, err := s.createRefreshToken(ctx, input.ClientID, *deviceAuth.UserID, deviceAuth.Scope, tx)
+	return user, nil
+}
+
+// createTokenFromJWTBearer issues an access token for a JWT of an identity provider the client is federated with, as described in RFC 7523
+// The token is mapped to the user configured in the federated identity, or to the client's service account
+func (s *OidcService) createTokenFromJWTBearer(ctx context.Context, input dto.OidcCreateTokensDto, cnf TokenConfirmation) (CreatedTokens, error) {
+	// Client authentication is optional for public clients, as the assertion is the credential
+	client, err := s.verifyClientCredentialsInternal(ctx, s.db, clientAuthCredentialsFromCreateTokensDto(&input), true)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	accessToken, err := s.jwtService.GenerateOAuthAccessToken(deviceAuth.User, input.ClientID)
+	err = checkDpopRequired(client, cnf.DpopJkt)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	// Delete the used device code
-	err = tx.WithContext(ctx).Delete(&deviceAuth).Error
-	if err != nil {
-		return CreatedTokens{}, err
+	if input.Assertion == "" {
+		return CreatedTokens{}, &common.OidcInvalidAssertionError{}
 	}
 
-	err = tx.Commit().Error
+	err = s.verifyCustomScopesAllowed(ctx, client.ID, input.Scope, s.db)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	return CreatedTokens{
-		IdToken:      idToken,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    AccessTokenDuration,
-	}, nil
-}
-
-func (s *OidcService) createTokenFromClientCredentials(ctx context.Context, input dto.OidcCreateTokensDto) (CreatedTokens, error) {
-	client, err := s.verifyClientCredentialsInternal(ctx, s.db, clientAuthCredentialsFromCreateTokensDto(&input), false)
+	// If the federated identity doesn't set a subject, it defaults to the client ID, like for client assertions
+	assertion, ocfi, err := s.verifyFederatedToken(ctx, client, input.Assertion, client.ID)
+	if err == nil {
+		err = s.consumeFederatedAssertion(ctx, assertion)
+	}
 	if err != nil {
-		return CreatedTokens{}, err
+		slog.WarnContext(ctx, "Invalid assertion for JWT bearer grant", slog.String("client", client.ID), slog.Any("error", err))
+		return CreatedTokens{}, &common.OidcInvalidAssertionError{}
 	}
 
-	// GenerateOAuthAccessToken uses user.ID as a "sub" claim. Prefix is used to take those security considerations
-	// into account: https://datatracker.ietf.org/doc/html/rfc9068#name-security-considerations
-	dummyUser := model.User{
-		Base: model.Base{ID: "client-" + client.ID},
+	var subject string
+	if ocfi.UserID != "" {
+		_, err = s.getEnabledUser(ctx, ocfi.UserID)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid user for JWT bearer grant", slog.String("client", client.ID), slog.Any("error", err))
+			return CreatedTokens{}, &common.OidcInvalidAssertionError{}
+		}
+
+		subject, err = s.getSubject(ctx, client, ocfi.UserID, s.db)
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+	} else {
+		// Same as for the client credentials grant
+		subject = "client-" + client.ID
 	}
 
 	audClaim := client.ID
@@ -360,18 +1610,19 @@ func (s *OidcService) createTokenFromClientCredentials(ctx context.Context, inpu
 		audClaim = input.Resource
 	}
 
-	accessToken, err := s.jwtService.GenerateOAuthAccessToken(dummyUser, audClaim)
+	accessTokenLifetime := clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration)
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(subject, audClaim, input.Scope, cnf, accessTokenLifetime, idTokenSigningAlg(client))
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
 	return CreatedTokens{
 		AccessToken: accessToken,
-		ExpiresIn:   AccessTokenDuration,
+		ExpiresIn:   accessTokenLifetime,
 	}, nil
 }
 
-func (s *OidcService) createTokenFromAuthorizationCode(ctx context.Context, input dto.OidcCreateTokensDto) (CreatedTokens, error) {
+func (s *OidcService) createTokenFromAuthorizationCode(ctx context.Context, input dto.OidcCreateTokensDto, cnf TokenConfirmation) (CreatedTokens, error) {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -382,6 +1633,11 @@ func (s *OidcService) createTokenFromAuthorizationCode(ctx context.Context, inpu
 		return CreatedTokens{}, err
 	}
 
+	err = checkDpopRequired(client, cnf.DpopJkt)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
 	var authorizationCodeMetaData model.OidcAuthorizationCode
 	err = tx.
 		WithContext(ctx).
@@ -403,23 +1659,37 @@ func (s *OidcService) createTokenFromAuthorizationCode(ctx context.Context, inpu
 		return CreatedTokens{}, &common.OidcInvalidAuthorizationCodeError{}
 	}
 
-	userClaims, err := s.getUserClaimsForClientInternal(ctx, authorizationCodeMetaData.UserID, input.ClientID, tx)
+	userClaims, err := s.getUserClaimsForClientInternal(ctx, authorizationCodeMetaData.UserID, input.ClientID, authorizationCodeMetaData.Claims.IDTokenClaimNames(), tx)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, authorizationCodeMetaData.Nonce)
+	auth := authenticationInfoFromModel(authorizationCodeMetaData.AuthTime, authorizationCodeMetaData.AuthenticationMethods)
+	encryption, err := s.getIDTokenEncryption(ctx, client)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, authorizationCodeMetaData.Nonce, auth, clientTokenLifetime(client.IdTokenLifetime, IdTokenDuration), idTokenSigningAlg(client), encryption)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	// Generate a refresh token
-	refreshToken, err := s.createRefreshToken(ctx, input.ClientID, authorizationCodeMetaData.UserID, authorizationCodeMetaData.Scope, tx)
+	// Generate a refresh token, if the client requested offline access
+	var refreshToken string
+	if issuesRefreshToken(client, authorizationCodeMetaData.Scope) {
+		refreshToken, err = s.createRefreshToken(ctx, client, authorizationCodeMetaData.UserID, authorizationCodeMetaData.Scope, authorizationCodeMetaData.Claims, auth, cnf.DpopJkt, nil, tx)
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+	}
+
+	subject, err := s.getSubject(ctx, client, authorizationCodeMetaData.UserID, tx)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	accessToken, err := s.jwtService.GenerateOAuthAccessToken(authorizationCodeMetaData.User, input.ClientID)
+	accessTokenLifetime := clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration)
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(subject, input.ClientID, authorizationCodeMetaData.Scope, cnf, accessTokenLifetime, idTokenSigningAlg(client))
 	if err != nil {
 		return CreatedTokens{}, err
 	}
@@ -441,17 +1711,17 @@ func (s *OidcService) createTokenFromAuthorizationCode(ctx context.Context, inpu
 		IdToken:      idToken,
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresIn:    AccessTokenDuration,
+		ExpiresIn:    accessTokenLifetime,
 	}, nil
 }
 
-func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto.OidcCreateTokensDto) (CreatedTokens, error) {
+func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto.OidcCreateTokensDto, cnf TokenConfirmation) (CreatedTokens, error) {
 	if input.RefreshToken == "" {
 		return CreatedTokens{}, &common.OidcMissingRefreshTokenError{}
 	}
 
 	// Validate the signed refresh token and extract the actual token (which is a claim in the signed one)
-	userID, clientID, rt, err := s.jwtService.VerifyOAuthRefreshToken(input.RefreshToken)
+	subject, clientID, rt, err := s.jwtService.VerifyOAuthRefreshToken(input.RefreshToken)
 	if err != nil {
 		return CreatedTokens{}, &common.OidcInvalidRefreshTokenError{}
 	}
@@ -471,7 +1741,23 @@ func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto
 		return CreatedTokens{}, &common.OidcInvalidRefreshTokenError{}
 	}
 
+	userID, err := s.resolveSubject(ctx, client, subject, tx)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	// Refresh tokens issued before they were disabled for the client can't be used anymore
+	if client.RefreshTokensDisabled {
+		return CreatedTokens{}, &common.OidcInvalidRefreshTokenError{}
+	}
+
+	err = checkDpopRequired(client, cnf.DpopJkt)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
 	// Verify refresh token
+	// Tokens that were already rotated are still returned here, so their reuse can be detected
 	var storedRefreshToken model.OidcRefreshToken
 	err = tx.
 		WithContext(ctx).
@@ -496,40 +1782,78 @@ func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto
 		return CreatedTokens{}, &common.OidcInvalidRefreshTokenError{}
 	}
 
+	// A refresh token bound to a DPoP key can only be used with a proof for the same key
+	if storedRefreshToken.DpopJkt != nil {
+		if *storedRefreshToken.DpopJkt != cnf.DpopJkt {
+			return CreatedTokens{}, &common.OidcInvalidDpopProofError{}
+		}
+	}
+
+	// A refresh token that was already rotated is being used again
+	// Within the grace period, this is allowed so concurrent refreshes by the same client don't fail
+	// Otherwise, the token was likely stolen, so we revoke the whole family of tokens
+	if storedRefreshToken.RotatedAt != nil {
+		gracePeriod := s.appConfigService.GetDbConfig().RefreshTokenReuseGracePeriod.AsDurationSeconds()
+		if time.Since(storedRefreshToken.RotatedAt.ToTime()) > gracePeriod {
+			err = s.revokeRefreshTokenFamily(ctx, client, &storedRefreshToken, input.IPAddress, input.UserAgent, tx)
+			if err != nil {
+				return CreatedTokens{}, err
+			}
+			return CreatedTokens{}, &common.OidcInvalidRefreshTokenError{}
+		}
+	}
+
 	// Generate a new access token
-	accessToken, err := s.jwtService.GenerateOAuthAccessToken(storedRefreshToken.User, input.ClientID)
+	subject, err = s.getSubject(ctx, client, storedRefreshToken.UserID, tx)
+	if err != nil {
+		return CreatedTokens{}, err
+	}
+
+	accessTokenLifetime := clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration)
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(subject, input.ClientID, storedRefreshToken.Scope, cnf, accessTokenLifetime, idTokenSigningAlg(client))
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
 	// Load the profile, which we need for the ID token
-	userClaims, err := s.getUserClaims(ctx, &storedRefreshToken.User, storedRefreshToken.Scopes(), tx)
+	userClaims, err := s.getUserClaims(ctx, client, &storedRefreshToken.User, storedRefreshToken.Scopes(), storedRefreshToken.Claims.IDTokenClaimNames(), tx)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
 	// Generate a new ID token
 	// There's no nonce here because we don't have one with the refresh token, but that's not required
-	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, "")
+	// The authentication information is the one of the original sign-in
+	auth := authenticationInfoFromModel(storedRefreshToken.AuthTime, storedRefreshToken.AuthenticationMethods)
+	encryption, err := s.getIDTokenEncryption(ctx, client)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
-
-	// Generate a new refresh token and invalidate the old one
-	newRefreshToken, err := s.createRefreshToken(ctx, input.ClientID, storedRefreshToken.UserID, storedRefreshToken.Scope, tx)
+	idToken, err := s.jwtService.GenerateIDToken(userClaims, input.ClientID, "", auth, clientTokenLifetime(client.IdTokenLifetime, IdTokenDuration), idTokenSigningAlg(client), encryption)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
-	// Delete the used refresh token
-	err = tx.
-		WithContext(ctx).
-		Delete(&storedRefreshToken).
-		Error
+	// Generate a new refresh token and invalidate the old one
+	newRefreshToken, err := s.createRefreshToken(ctx, client, storedRefreshToken.UserID, storedRefreshToken.Scope, storedRefreshToken.Claims, auth, cnf.DpopJkt, &storedRefreshToken, tx)
 	if err != nil {
 		return CreatedTokens{}, err
 	}
 
+	// Mark the used refresh token as rotated
+	// It's not deleted so we can detect if it's used again
+	if storedRefreshToken.RotatedAt == nil {
+		err = tx.
+			WithContext(ctx).
+			Model(&model.OidcRefreshToken{}).
+			Where("id = ?", storedRefreshToken.ID).
+			Update("rotated_at", datatype.DateTime(time.Now())).
+			Error
+		if err != nil {
+			return CreatedTokens{}, err
+		}
+	}
+
 	err = tx.Commit().Error
 	if err != nil {
 		return CreatedTokens{}, err
@@ -539,10 +1863,32 @@ func (s *OidcService) createTokenFromRefreshToken(ctx context.Context, input dto
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken,
 		IdToken:      idToken,
-		ExpiresIn:    AccessTokenDuration,
+		ExpiresIn:    accessTokenLifetime,
 	}, nil
 }
 
+// revokeRefreshTokenFamily deletes all refresh tokens in the family of the given token and records the reuse in the audit log
+// The transaction is committed, so the revocation persists even if the request fails
+func (s *OidcService) revokeRefreshTokenFamily(ctx context.Context, client *model.OidcClient, refreshToken *model.OidcRefreshToken, ipAddress string, userAgent string, tx *gorm.DB) error {
+	slog.WarnContext(ctx, "Detected reuse of a rotated refresh token; revoking the token family",
+		slog.String("client", client.ID),
+		slog.String("user", refreshToken.UserID),
+	)
+
+	err := tx.
+		WithContext(ctx).
+		Where("family_id = ?", refreshToken.FamilyID).
+		Delete(&model.OidcRefreshToken{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	s.auditLogService.CreateRefreshTokenReuseWithEmail(ctx, ipAddress, userAgent, refreshToken.UserID, *client, tx)
+
+	return tx.Commit().Error
+}
+
 func (s *OidcService) IntrospectToken(ctx context.Context, creds ClientAuthCredentials, tokenString string) (introspectDto dto.OidcIntrospectionResponseDto, err error) {
 	client, err := s.verifyClientCredentialsInternal(ctx, s.db, creds, false)
 	if err != nil {
@@ -572,9 +1918,9 @@ func (s *OidcService) IntrospectToken(ctx context.Context, creds ClientAuthCrede
 	// Introspect the token
 	switch tokenType {
 	case OAuthAccessTokenJWTType:
-		return s.introspectAccessToken(client.ID, tokenString)
+		return s.introspectAccessToken(ctx, client, tokenString)
 	case OAuthRefreshTokenJWTType:
-		return s.introspectRefreshToken(ctx, client.ID, tokenString)
+		return s.introspectRefreshToken(ctx, client, tokenString)
 	default:
 		// We just treat the token as invalid
 		introspectDto.Active = false
@@ -582,7 +1928,7 @@ func (s *OidcService) IntrospectToken(ctx context.Context, creds ClientAuthCrede
 	}
 }
 
-func (s *OidcService) introspectAccessToken(clientID string, tokenString string) (introspectDto dto.OidcIntrospectionResponseDto, err error) {
+func (s *OidcService) introspectAccessToken(ctx context.Context, client *model.OidcClient, tokenString string) (introspectDto dto.OidcIntrospectionResponseDto, err error) {
 	token, err := s.jwtService.VerifyOAuthAccessToken(tokenString)
 	if err != nil {
 		// Every failure we get means the token is invalid. Nothing more to do with the error.
@@ -596,7 +1942,7 @@ func (s *OidcService) introspectAccessToken(clientID string, tokenString string)
 		introspectDto.Active = false
 		return introspectDto, nil
 	}
-	if audience[0] != clientID {
+	if audience[0] != client.ID {
 		return introspectDto, &common.OidcMissingClientCredentialsError{}
 	}
 
@@ -624,6 +1970,7 @@ func (s *OidcService) introspectAccessToken(clientID string, tokenString string)
 		introspectDto.NotBefore = notBefore.Unix()
 	}
 	if subject, ok := token.Subject(); ok {
+		// The subject is already the identifier of the user for the token's audience
 		introspectDto.Subject = subject
 	}
 	if issuer, ok := token.Issuer(); ok {
@@ -632,28 +1979,37 @@ func (s *OidcService) introspectAccessToken(clientID string, tokenString string)
 	if identifier, ok := token.JwtID(); ok {
 		introspectDto.Identifier = identifier
 	}
+	// Resource servers need to check bound tokens against the keys in the confirmation claim
+	if cnf := GetTokenConfirmation(token); !cnf.IsEmpty() {
+		introspectDto.Confirmation = cnf.Claim()
+	}
 
 	return introspectDto, nil
 }
 
-func (s *OidcService) introspectRefreshToken(ctx context.Context, clientID string, refreshToken string) (introspectDto dto.OidcIntrospectionResponseDto, err error) {
+func (s *OidcService) introspectRefreshToken(ctx context.Context, client *model.OidcClient, refreshToken string) (introspectDto dto.OidcIntrospectionResponseDto, err error) {
 	// Validate the signed refresh token and extract the actual token (which is a claim in the signed one)
-	tokenUserID, tokenClientID, tokenRT, err := s.jwtService.VerifyOAuthRefreshToken(refreshToken)
+	tokenSubject, tokenClientID, tokenRT, err := s.jwtService.VerifyOAuthRefreshToken(refreshToken)
 	if err != nil {
 		return introspectDto, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
 	// The ID of the client that made the call must match the client ID in the token
-	if tokenClientID != clientID {
+	if tokenClientID != client.ID {
 		return introspectDto, errors.New("invalid refresh token: client ID does not match")
 	}
 
+	tokenUserID, err := s.resolveSubject(ctx, client, tokenSubject, s.db)
+	if err != nil {
+		return introspectDto, err
+	}
+
 	var storedRefreshToken model.OidcRefreshToken
 	err = s.db.
 		WithContext(ctx).
 		Preload("User").
 		Where(
-			"token = ? AND expires_at > ? AND user_id = ? AND client_id = ?",
+			"token = ? AND expires_at > ? AND user_id = ? AND client_id = ? AND rotated_at IS NULL",
 			utils.CreateSha256Hash(tokenRT),
 			datatype.DateTime(time.Now()),
 			tokenUserID,
@@ -671,9 +2027,86 @@ func (s *OidcService) introspectRefreshToken(ctx context.Context, clientID strin
 
 	introspectDto.Active = true
 	introspectDto.TokenType = "refresh_token"
+	introspectDto.Subject = tokenSubject
+	if storedRefreshToken.DpopJkt != nil {
+		introspectDto.Confirmation = map[string]string{"jkt": *storedRefreshToken.DpopJkt}
+	}
 	return introspectDto, nil
 }
 
+// RevokeToken revokes a token that was issued to the authenticated client, as described in RFC 7009
+func (s *OidcService) RevokeToken(ctx context.Context, creds ClientAuthCredentials, tokenString string) error {
+	client, err := s.verifyClientCredentialsInternal(ctx, s.db, creds, true)
+	if err != nil {
+		return err
+	}
+
+	// Get the type of the token
+	tokenType, _, err := s.jwtService.GetTokenType(tokenString)
+	if err != nil {
+		// Per spec, invalid tokens don't cause an error response
+		return nil //nolint:nilerr
+	}
+
+	switch tokenType {
+	case OAuthRefreshTokenJWTType:
+		return s.revokeRefreshToken(ctx, client, tokenString)
+	case OAuthAccessTokenJWTType:
+		// Access tokens are self-contained and short-lived, so they can't be revoked
+		return &common.OidcUnsupportedTokenTypeError{}
+	default:
+		return nil
+	}
+}
+
+func (s *OidcService) revokeRefreshToken(ctx context.Context, client *model.OidcClient, refreshToken string) error {
+	// Validate the signed refresh token and extract the actual token (which is a claim in the signed one)
+	tokenSubject, tokenClientID, tokenRT, err := s.jwtService.VerifyOAuthRefreshToken(refreshToken)
+	if err != nil {
+		// Per spec, invalid tokens don't cause an error response
+		return nil //nolint:nilerr
+	}
+
+	// Clients can only revoke tokens that were issued to them
+	if tokenClientID != client.ID {
+		return &common.OidcClientIdNotMatchingError{}
+	}
+
+	tokenUserID, err := s.resolveSubject(ctx, client, tokenSubject, s.db)
+	if err != nil {
+		return err
+	}
+
+	// Revoking a refresh token revokes all tokens in its family
+	var storedRefreshToken model.OidcRefreshToken
+	err = s.db.
+		WithContext(ctx).
+		Where(
+			"token = ? AND user_id = ? AND client_id = ?",
+			utils.CreateSha256Hash(tokenRT),
+			tokenUserID,
+			tokenClientID,
+		).
+		First(&storedRefreshToken).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err = s.db.
+		WithContext(ctx).
+		Where("family_id = ?", storedRefreshToken.FamilyID).
+		Delete(&model.OidcRefreshToken{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *OidcService) GetClient(ctx context.Context, clientID string) (model.OidcClient, error) {
 	return s.getClientInternal(ctx, clientID, s.db)
 }
@@ -725,15 +2158,47 @@ func (s *OidcService) CreateClient(ctx context.Context, input dto.OidcClientCrea
 		tx.Rollback()
 	}()
 
+	client, err := s.createClientInternal(ctx, input, userID, tx)
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	return client, nil
+}
+
+func (s *OidcService) createClientInternal(ctx context.Context, input dto.OidcClientCreateDto, userID string, tx *gorm.DB) (model.OidcClient, error) {
 	client := model.OidcClient{
 		Base: model.Base{
 			ID: input.ID,
 		},
-		CreatedByID: utils.Ptr(userID),
+	}
+	// Dynamically registered clients may no longer have a user that created them
+	if userID != "" {
+		client.CreatedByID = utils.Ptr(userID)
 	}
 	updateOIDCClientModelFromDto(&client, &input.OidcClientUpdateDto)
 
-	err := tx.
+	err := validateTokenEncryption(&client)
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	err = s.validateSigningAlgs(&client)
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	err = s.validateSectorIdentifier(ctx, &client)
+	if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	err = tx.
 		WithContext(ctx).
 		Create(&client).
 		Error
@@ -751,11 +2216,6 @@ func (s *OidcService) CreateClient(ctx context.Context, input dto.OidcClientCrea
 		}
 	}
 
-	err = tx.Commit().Error
-	if err != nil {
-		return model.OidcClient{}, err
-	}
-
 	return client, nil
 }
 
@@ -770,23 +2230,46 @@ func (s *OidcService) UpdateClient(ctx context.Context, clientID string, input d
 		return model.OidcClient{}, err
 	}
 
-	updateOIDCClientModelFromDto(&client, &input)
+	if err := s.updateClientInternal(ctx, &client, input, tx); err != nil {
+		return model.OidcClient{}, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return model.OidcClient{}, err
+	}
+	return client, nil
+}
+
+func (s *OidcService) updateClientInternal(ctx context.Context, client *model.OidcClient, input dto.OidcClientUpdateDto, tx *gorm.DB) error {
+	updateOIDCClientModelFromDto(client, &input)
+
+	err := validateTokenEncryption(client)
+	if err != nil {
+		return err
+	}
+
+	err = s.validateSigningAlgs(client)
+	if err != nil {
+		return err
+	}
+
+	err = s.validateSectorIdentifier(ctx, client)
+	if err != nil {
+		return err
+	}
 
-	if err := tx.WithContext(ctx).Save(&client).Error; err != nil {
-		return model.OidcClient{}, err
+	if err := tx.WithContext(ctx).Save(client).Error; err != nil {
+		return err
 	}
 
 	if input.LogoURL != nil {
-		err := s.downloadAndSaveLogoFromURL(ctx, tx, client.ID, *input.LogoURL)
+		err = s.downloadAndSaveLogoFromURL(ctx, tx, client.ID, *input.LogoURL)
 		if err != nil {
-			return model.OidcClient{}, fmt.Errorf("failed to download logo: %w", err)
+			return fmt.Errorf("failed to download logo: %w", err)
 		}
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return model.OidcClient{}, err
-	}
-	return client, nil
+	return nil
 }
 
 func updateOIDCClientModelFromDto(client *model.OidcClient, input *dto.OidcClientUpdateDto) {
@@ -798,9 +2281,39 @@ func updateOIDCClientModelFromDto(client *model.OidcClient, input *dto.OidcClien
 	// PKCE is required for public clients
 	client.PkceEnabled = input.IsPublic || input.PkceEnabled
 	client.RequiresReauthentication = input.RequiresReauthentication
+	client.RequiresPushedAuthorizationRequests = input.RequiresPushedAuthorizationRequests
 	client.LaunchURL = input.LaunchURL
+	client.JwksURL = input.JwksURL
+	client.Jwks = input.Jwks
+	client.RequiresSignedRequestObject = input.RequiresSignedRequestObject
+	client.BackchannelLogoutURL = input.BackchannelLogoutURL
+	client.FrontchannelLogoutURL = input.FrontchannelLogoutURL
+	client.FrontchannelLogoutSessionRequired = input.FrontchannelLogoutSessionRequired
+	client.RequiresDpop = input.RequiresDpop
+	client.MinimumAcr = input.MinimumAcr
+	client.TokenExchangeAudiences = input.TokenExchangeAudiences
+	client.RefreshTokensDisabled = input.RefreshTokensDisabled
+	client.RefreshTokensWithoutOfflineAccess = input.RefreshTokensWithoutOfflineAccess
+	client.BackchannelAuthenticationEnabled = input.BackchannelAuthenticationEnabled
+	client.SubjectType = input.SubjectType
+	if client.SubjectType == "" {
+		client.SubjectType = model.OidcSubjectTypePublic
+	}
+	client.SectorIdentifierURI = input.SectorIdentifierURI
+	client.AccessTokenLifetime = input.AccessTokenLifetime
+	client.IdTokenLifetime = input.IdTokenLifetime
+	client.RefreshTokenIdleLifetime = input.RefreshTokenIdleLifetime
+	client.RefreshTokenAbsoluteLifetime = input.RefreshTokenAbsoluteLifetime
+	client.IdTokenEncryptedResponseAlg = input.IdTokenEncryptedResponseAlg
+	client.IdTokenEncryptedResponseEnc = input.IdTokenEncryptedResponseEnc
+	client.UserinfoEncryptedResponseAlg = input.UserinfoEncryptedResponseAlg
+	client.UserinfoEncryptedResponseEnc = input.UserinfoEncryptedResponseEnc
+	client.UserinfoSignedResponseAlg = input.UserinfoSignedResponseAlg
+	client.IdTokenSignedResponseAlg = input.IdTokenSignedResponseAlg
 
 	// Credentials
+	client.Credentials.TLSClientAuthSubjectDN = input.Credentials.TLSClientAuthSubjectDN
+	client.Credentials.TLSClientAuthThumbprints = input.Credentials.TLSClientAuthThumbprints
 	client.Credentials.FederatedIdentities = make([]model.OidcClientFederatedIdentity, len(input.Credentials.FederatedIdentities))
 	for i, fi := range input.Credentials.FederatedIdentities {
 		client.Credentials.FederatedIdentities[i] = model.OidcClientFederatedIdentity{
@@ -808,6 +2321,8 @@ func updateOIDCClientModelFromDto(client *model.OidcClient, input *dto.OidcClien
 			Audience: fi.Audience,
 			Subject:  fi.Subject,
 			JWKS:     fi.JWKS,
+			Claims:   fi.Claims,
+			UserID:   fi.UserID,
 		}
 	}
 
@@ -842,6 +2357,20 @@ func (s *OidcService) CreateClientSecret(ctx context.Context, clientID string) (
 		return "", err
 	}
 
+	clientSecret, err := s.createClientSecretInternal(ctx, &client, tx)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return "", err
+	}
+
+	return clientSecret, nil
+}
+
+func (s *OidcService) createClientSecretInternal(ctx context.Context, client *model.OidcClient, tx *gorm.DB) (string, error) {
 	clientSecret, err := utils.GenerateRandomAlphanumericString(32)
 	if err != nil {
 		return "", err
@@ -855,18 +2384,295 @@ func (s *OidcService) CreateClientSecret(ctx context.Context, clientID string) (
 	client.Secret = string(hashedSecret)
 	err = tx.
 		WithContext(ctx).
-		Save(&client).
+		Save(client).
 		Error
 	if err != nil {
 		return "", err
 	}
 
+	return clientSecret, nil
+}
+
+func (s *OidcService) CreateInitialAccessToken(ctx context.Context, ttl time.Duration, usageLimit int, userID string) (model.OidcInitialAccessToken, string, error) {
+	randomString, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return model.OidcInitialAccessToken{}, "", err
+	}
+
+	token := model.OidcInitialAccessToken{
+		Token:       utils.CreateSha256Hash(randomString), // Hash the token for storage
+		ExpiresAt:   datatype.DateTime(time.Now().Round(time.Second).Add(ttl)),
+		UsageLimit:  usageLimit,
+		CreatedByID: utils.Ptr(userID),
+	}
+
+	err = s.db.WithContext(ctx).Create(&token).Error
+	if err != nil {
+		return model.OidcInitialAccessToken{}, "", err
+	}
+
+	// Return the raw token only once - it cannot be retrieved later
+	return token, randomString, nil
+}
+
+func (s *OidcService) ListInitialAccessTokens(ctx context.Context, sortedPaginationRequest utils.SortedPaginationRequest) ([]model.OidcInitialAccessToken, utils.PaginationResponse, error) {
+	var tokens []model.OidcInitialAccessToken
+	query := s.db.WithContext(ctx).Model(&model.OidcInitialAccessToken{})
+
+	pagination, err := utils.PaginateAndSort(sortedPaginationRequest, query, &tokens)
+	return tokens, pagination, err
+}
+
+func (s *OidcService) DeleteInitialAccessToken(ctx context.Context, tokenID string) error {
+	return s.db.WithContext(ctx).Delete(&model.OidcInitialAccessToken{}, "id = ?", tokenID).Error
+}
+
+// RegisterClient creates a new client from the client metadata sent to the dynamic client registration endpoint (RFC 7591)
+func (s *OidcService) RegisterClient(ctx context.Context, initialAccessToken string, input dto.OidcClientRegistrationDto) (*dto.OidcClientRegistrationResponseDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// Consume one use of the initial access token
+	initialAccessTokenHash := utils.CreateSha256Hash(initialAccessToken)
+	result := tx.
+		WithContext(ctx).
+		Model(&model.OidcInitialAccessToken{}).
+		Where("token = ? AND expires_at > ? AND usage_count < usage_limit", initialAccessTokenHash, datatype.DateTime(time.Now())).
+		Update("usage_count", gorm.Expr("usage_count + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, &common.OidcInvalidInitialAccessTokenError{}
+	}
+
+	var token model.OidcInitialAccessToken
+	err := tx.
+		WithContext(ctx).
+		First(&token, "token = ?", initialAccessTokenHash).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	var createdByID string
+	if token.CreatedByID != nil {
+		createdByID = *token.CreatedByID
+	}
+
+	client, err := s.createClientInternal(ctx, dto.OidcClientCreateDto{
+		OidcClientUpdateDto: clientUpdateDtoFromRegistration(&model.OidcClient{}, input),
+	}, createdByID, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientSecret string
+	if !client.IsPublic {
+		clientSecret, err = s.createClientSecretInternal(ctx, &client, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	registrationAccessToken, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	client.RegistrationAccessToken = utils.Ptr(utils.CreateSha256Hash(registrationAccessToken))
+	err = tx.
+		WithContext(ctx).
+		Model(&client).
+		Update("registration_access_token", client.RegistrationAccessToken).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit().Error
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return clientSecret, nil
+	response := clientRegistrationResponse(client)
+	response.ClientSecret = clientSecret
+	response.RegistrationAccessToken = registrationAccessToken
+	return &response, nil
+}
+
+// GetRegisteredClient returns the metadata of a dynamically registered client (RFC 7592)
+func (s *OidcService) GetRegisteredClient(ctx context.Context, clientID string, registrationAccessToken string) (*dto.OidcClientRegistrationResponseDto, error) {
+	client, err := s.getClientByRegistrationAccessToken(ctx, clientID, registrationAccessToken, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	response := clientRegistrationResponse(client)
+	return &response, nil
+}
+
+// UpdateRegisteredClient replaces the metadata of a dynamically registered client (RFC 7592)
+func (s *OidcService) UpdateRegisteredClient(ctx context.Context, clientID string, registrationAccessToken string, input dto.OidcClientRegistrationDto) (*dto.OidcClientRegistrationResponseDto, error) {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	client, err := s.getClientByRegistrationAccessToken(ctx, clientID, registrationAccessToken, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.updateClientInternal(ctx, &client, clientUpdateDtoFromRegistration(&client, input), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A public client that became confidential needs a secret
+	var clientSecret string
+	if !client.IsPublic && client.Secret == "" {
+		clientSecret, err = s.createClientSecretInternal(ctx, &client, tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := clientRegistrationResponse(client)
+	response.ClientSecret = clientSecret
+	return &response, nil
+}
+
+// DeleteRegisteredClient deletes a dynamically registered client (RFC 7592)
+func (s *OidcService) DeleteRegisteredClient(ctx context.Context, clientID string, registrationAccessToken string) error {
+	_, err := s.getClientByRegistrationAccessToken(ctx, clientID, registrationAccessToken, s.db)
+	if err != nil {
+		return err
+	}
+
+	return s.DeleteClient(ctx, clientID)
+}
+
+func (s *OidcService) getClientByRegistrationAccessToken(ctx context.Context, clientID string, registrationAccessToken string, tx *gorm.DB) (model.OidcClient, error) {
+	if registrationAccessToken == "" {
+		return model.OidcClient{}, &common.OidcInvalidRegistrationAccessTokenError{}
+	}
+
+	var client model.OidcClient
+	err := tx.
+		WithContext(ctx).
+		First(&client, "id = ? AND registration_access_token = ?", clientID, utils.CreateSha256Hash(registrationAccessToken)).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.OidcClient{}, &common.OidcInvalidRegistrationAccessTokenError{}
+	} else if err != nil {
+		return model.OidcClient{}, err
+	}
+
+	return client, nil
+}
+
+// clientUpdateDtoFromRegistration maps the client metadata to the fields of the client.
+// Settings that can't be expressed as client metadata are kept from the existing client.
+func clientUpdateDtoFromRegistration(client *model.OidcClient, input dto.OidcClientRegistrationDto) dto.OidcClientUpdateDto {
+	updateDto := dto.OidcClientUpdateDto{
+		Name:                                input.ClientName,
+		CallbackURLs:                        input.RedirectURIs,
+		LogoutCallbackURLs:                  input.PostLogoutRedirectURIs,
+		IsPublic:                            input.TokenEndpointAuthMethod == "none",
+		PkceEnabled:                         client.PkceEnabled,
+		RequiresReauthentication:            client.RequiresReauthentication,
+		RequiresPushedAuthorizationRequests: client.RequiresPushedAuthorizationRequests,
+		RequiresSignedRequestObject:         client.RequiresSignedRequestObject,
+		RequiresDpop:                        client.RequiresDpop,
+		MinimumAcr:                          client.MinimumAcr,
+		TokenExchangeAudiences:              client.TokenExchangeAudiences,
+		RefreshTokensDisabled:               client.RefreshTokensDisabled,
+		RefreshTokensWithoutOfflineAccess:   client.RefreshTokensWithoutOfflineAccess,
+		BackchannelAuthenticationEnabled:    client.BackchannelAuthenticationEnabled,
+		AccessTokenLifetime:                 client.AccessTokenLifetime,
+		IdTokenLifetime:                     client.IdTokenLifetime,
+		RefreshTokenIdleLifetime:            client.RefreshTokenIdleLifetime,
+		RefreshTokenAbsoluteLifetime:        client.RefreshTokenAbsoluteLifetime,
+		JwksURL:                             input.JwksURI,
+		IdTokenEncryptedResponseAlg:         input.IdTokenEncryptedResponseAlg,
+		IdTokenEncryptedResponseEnc:         input.IdTokenEncryptedResponseEnc,
+		UserinfoEncryptedResponseAlg:        input.UserinfoEncryptedResponseAlg,
+		UserinfoEncryptedResponseEnc:        input.UserinfoEncryptedResponseEnc,
+		UserinfoSignedResponseAlg:           input.UserinfoSignedResponseAlg,
+		IdTokenSignedResponseAlg:            input.IdTokenSignedResponseAlg,
+		LaunchURL:                           input.ClientURI,
+		LogoURL:                             input.LogoURI,
+		BackchannelLogoutURL:                input.BackchannelLogoutURI,
+		FrontchannelLogoutURL:               input.FrontchannelLogoutURI,
+		FrontchannelLogoutSessionRequired:   input.FrontchannelLogoutSessionRequired,
+		SubjectType:                         input.SubjectType,
+		SectorIdentifierURI:                 input.SectorIdentifierURI,
+	}
+	if len(input.Jwks) > 0 {
+		updateDto.Jwks = utils.Ptr(string(input.Jwks))
+	}
+
+	updateDto.Credentials.TLSClientAuthSubjectDN = client.Credentials.TLSClientAuthSubjectDN
+	updateDto.Credentials.TLSClientAuthThumbprints = client.Credentials.TLSClientAuthThumbprints
+	updateDto.Credentials.FederatedIdentities = make([]dto.OidcClientFederatedIdentityDto, len(client.Credentials.FederatedIdentities))
+	for i, fi := range client.Credentials.FederatedIdentities {
+		updateDto.Credentials.FederatedIdentities[i] = dto.OidcClientFederatedIdentityDto{
+			Issuer:   fi.Issuer,
+			Subject:  fi.Subject,
+			Audience: fi.Audience,
+			JWKS:     fi.JWKS,
+			Claims:   fi.Claims,
+			UserID:   fi.UserID,
+		}
+	}
+
+	return updateDto
+}
+
+func clientRegistrationResponse(client model.OidcClient) dto.OidcClientRegistrationResponseDto {
+	tokenEndpointAuthMethod := "client_secret_basic"
+	if client.IsPublic {
+		tokenEndpointAuthMethod = "none"
+	}
+
+	var jwks json.RawMessage
+	if client.Jwks != nil {
+		jwks = json.RawMessage(*client.Jwks)
+	}
+
+	return dto.OidcClientRegistrationResponseDto{
+		OidcClientRegistrationDto: dto.OidcClientRegistrationDto{
+			ClientName:                        client.Name,
+			RedirectURIs:                      client.CallbackURLs,
+			PostLogoutRedirectURIs:            client.LogoutCallbackURLs,
+			TokenEndpointAuthMethod:           tokenEndpointAuthMethod,
+			JwksURI:                           client.JwksURL,
+			Jwks:                              jwks,
+			ClientURI:                         client.LaunchURL,
+			BackchannelLogoutURI:              client.BackchannelLogoutURL,
+			FrontchannelLogoutURI:             client.FrontchannelLogoutURL,
+			FrontchannelLogoutSessionRequired: client.FrontchannelLogoutSessionRequired,
+			SubjectType:                       client.SubjectType,
+			SectorIdentifierURI:               client.SectorIdentifierURI,
+			IdTokenEncryptedResponseAlg:       client.IdTokenEncryptedResponseAlg,
+			IdTokenEncryptedResponseEnc:       client.IdTokenEncryptedResponseEnc,
+			UserinfoEncryptedResponseAlg:      client.UserinfoEncryptedResponseAlg,
+			UserinfoEncryptedResponseEnc:      client.UserinfoEncryptedResponseEnc,
+			UserinfoSignedResponseAlg:         client.UserinfoSignedResponseAlg,
+			IdTokenSignedResponseAlg:          client.IdTokenSignedResponseAlg,
+		},
+		ClientID:              client.ID,
+		ClientIDIssuedAt:      time.Time(client.CreatedAt).Unix(),
+		RegistrationClientURI: common.EnvConfig.InternalAppURL + "/api/oidc/register/" + client.ID,
+	}
 }
 
 func (s *OidcService) GetClientLogo(ctx context.Context, clientID string) (string, string, error) {
@@ -1053,7 +2859,159 @@ func (s *OidcService) ValidateEndSession(ctx context.Context, input dto.OidcLogo
 	return callbackURL, nil
 }
 
-func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID string, userID string, scope string, nonce string, codeChallenge string, codeChallengeMethod string, tx *gorm.DB) (string, error) {
+// SendBackchannelLogout notifies all clients the user has authorized, and that have a back-channel logout URL, that the user has logged out.
+// The logout tokens are delivered in the background.
+func (s *OidcService) SendBackchannelLogout(ctx context.Context, userID string, ipAddress string, userAgent string) error {
+	clients, err := s.ListBackchannelLogoutClients(ctx, userID, s.db)
+	if err != nil {
+		return err
+	}
+
+	s.SendBackchannelLogoutToClients(ctx, userID, clients, ipAddress, userAgent, true)
+	return nil
+}
+
+// ListBackchannelLogoutClients returns the clients the user has authorized that have a back-channel logout URL.
+func (s *OidcService) ListBackchannelLogoutClients(ctx context.Context, userID string, tx *gorm.DB) ([]model.OidcClient, error) {
+	var clients []model.OidcClient
+	err := tx.
+		WithContext(ctx).
+		Joins("JOIN user_authorized_oidc_clients ON user_authorized_oidc_clients.client_id = oidc_clients.id").
+		Where("user_authorized_oidc_clients.user_id = ? AND oidc_clients.backchannel_logout_url IS NOT NULL AND oidc_clients.backchannel_logout_url != ''", userID).
+		Find(&clients).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clients for back-channel logout: %w", err)
+	}
+
+	return clients, nil
+}
+
+// GetFrontchannelLogoutURLs returns the front-channel logout URLs of all clients the user has authorized.
+// They need to be rendered in iframes by the user agent when the user logs out.
+func (s *OidcService) GetFrontchannelLogoutURLs(ctx context.Context, userID string) ([]string, error) {
+	var clients []model.OidcClient
+	err := s.db.
+		WithContext(ctx).
+		Joins("JOIN user_authorized_oidc_clients ON user_authorized_oidc_clients.client_id = oidc_clients.id").
+		Where("user_authorized_oidc_clients.user_id = ? AND oidc_clients.frontchannel_logout_url IS NOT NULL AND oidc_clients.frontchannel_logout_url != ''", userID).
+		Find(&clients).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clients for front-channel logout: %w", err)
+	}
+
+	logoutURLs := make([]string, 0, len(clients))
+	for _, client := range clients {
+		logoutURL, err := url.Parse(*client.FrontchannelLogoutURL)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid front-channel logout URL", slog.String("client", client.ID), slog.Any("error", err))
+			continue
+		}
+
+		// Sessions aren't tracked per client, so only the issuer can be included
+		if client.FrontchannelLogoutSessionRequired {
+			q := logoutURL.Query()
+			q.Set("iss", common.EnvConfig.AppURL)
+			logoutURL.RawQuery = q.Encode()
+		}
+
+		logoutURLs = append(logoutURLs, logoutURL.String())
+	}
+
+	return logoutURLs, nil
+}
+
+// SendBackchannelLogoutToClients delivers a logout token to every client in the background.
+// If createAuditLog is false, deliveries are only logged, e.g. because the user doesn't exist anymore.
+func (s *OidcService) SendBackchannelLogoutToClients(ctx context.Context, userID string, clients []model.OidcClient, ipAddress string, userAgent string, createAuditLog bool) {
+	for _, client := range clients {
+		// We use a background context here as this is running in a goroutine
+		//nolint:contextcheck
+		go func() {
+			span := trace.SpanFromContext(ctx)
+			innerCtx := trace.ContextWithSpan(context.Background(), span)
+
+			status := "delivered"
+			err := s.deliverBackchannelLogout(innerCtx, userID, client)
+			if err != nil {
+				status = "failed"
+				slog.WarnContext(innerCtx, "Failed to deliver back-channel logout token", slog.String("client", client.ID), slog.Any("error", err))
+			}
+
+			if createAuditLog {
+				s.auditLogService.Create(innerCtx, model.AuditLogEventBackchannelLogout, ipAddress, userAgent, userID, model.AuditLogData{
+					"clientName": client.Name,
+					"status":     status,
+				}, s.db)
+			}
+		}()
+	}
+}
+
+func (s *OidcService) deliverBackchannelLogout(ctx context.Context, userID string, client model.OidcClient) error {
+	subject, err := s.getSubject(ctx, &client, userID, s.db)
+	if err != nil {
+		return err
+	}
+
+	logoutToken, err := s.jwtService.GenerateLogoutToken(subject, client.ID, idTokenSigningAlg(&client))
+	if err != nil {
+		return err
+	}
+
+	body := url.Values{"logout_token": {logoutToken}}.Encode()
+
+	for attempt := 1; ; attempt++ {
+		err = s.postBackchannelLogout(ctx, *client.BackchannelLogoutURL, body)
+		if err == nil || attempt == BackchannelLogoutMaxAttempts {
+			return err
+		}
+
+		// Wait before the next attempt, doubling the delay every time
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(1<<(attempt-1)) * time.Second):
+		}
+	}
+}
+
+func (s *OidcService) postBackchannelLogout(parentCtx context.Context, logoutURL string, body string) error {
+	u, err := url.Parse(logoutURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	err = verifyPublicHost(ctx, u.Hostname())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, logoutURL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "pocket-id/oidc-backchannel-logout")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID string, userID string, scope string, claimsRequest *model.OidcClaimsRequest, nonce string, codeChallenge string, codeChallengeMethod string, auth AuthenticationInfo, tx *gorm.DB) (string, error) {
 	randomString, err := utils.GenerateRandomAlphanumericString(32)
 	if err != nil {
 		return "", err
@@ -1067,9 +3025,12 @@ func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID stri
 		ClientID:                  clientID,
 		UserID:                    userID,
 		Scope:                     scope,
+		Claims:                    claimsRequest,
 		Nonce:                     nonce,
 		CodeChallenge:             &codeChallenge,
 		CodeChallengeMethodSha256: &codeChallengeMethodSha256,
+		AuthTime:                  authTimeToModel(auth.Time),
+		AuthenticationMethods:     strings.Join(auth.Methods, " "),
 	}
 
 	err = tx.
@@ -1083,6 +3044,23 @@ func (s *OidcService) createAuthorizationCode(ctx context.Context, clientID stri
 	return randomString, nil
 }
 
+// authTimeToModel returns the authentication time to store in the database, which is nil if it's unknown
+func authTimeToModel(authTime time.Time) *datatype.DateTime {
+	if authTime.IsZero() {
+		return nil
+	}
+	return utils.Ptr(datatype.DateTime(authTime))
+}
+
+// authenticationInfoFromModel returns the authentication information stored in the database
+func authenticationInfoFromModel(authTime *datatype.DateTime, authenticationMethods string) (auth AuthenticationInfo) {
+	if authTime != nil {
+		auth.Time = authTime.ToTime()
+	}
+	auth.Methods = strings.Fields(authenticationMethods)
+	return auth
+}
+
 func (s *OidcService) validateCodeVerifier(codeVerifier, codeChallenge string, codeChallengeMethodSha256 bool) bool {
 	if codeVerifier == "" || codeChallenge == "" {
 		return false
@@ -1133,6 +3111,27 @@ func (s *OidcService) getCallbackURL(client *model.OidcClient, inputCallbackURL
 	return inputCallbackURL, nil
 }
 
+// ValidateCallbackURL checks that the callback URL is registered for the client
+func (s *OidcService) ValidateCallbackURL(ctx context.Context, clientID string, callbackURL string) error {
+	var client model.OidcClient
+	err := s.db.
+		WithContext(ctx).
+		First(&client, "id = ?", clientID).
+		Error
+	if err != nil {
+		return err
+	}
+
+	matched, err := s.getCallbackURLFromList(client.CallbackURLs, callbackURL)
+	if err != nil {
+		return err
+	} else if matched == "" {
+		return &common.OidcInvalidCallbackURLError{}
+	}
+
+	return nil
+}
+
 func (s *OidcService) getLogoutCallbackURL(client *model.OidcClient, inputLogoutCallbackURL string) (callbackURL string, err error) {
 	if inputLogoutCallbackURL == "" {
 		return client.LogoutCallbackURLs[0], nil
@@ -1177,15 +3176,22 @@ func (s *OidcService) addCallbackURLToClient(ctx context.Context, client *model.
 
 func (s *OidcService) CreateDeviceAuthorization(ctx context.Context, input dto.OidcDeviceAuthorizationRequestDto) (*dto.OidcDeviceAuthorizationResponseDto, error) {
 	client, err := s.verifyClientCredentialsInternal(ctx, s.db, ClientAuthCredentials{
-		ClientID:            input.ClientID,
-		ClientSecret:        input.ClientSecret,
-		ClientAssertionType: input.ClientAssertionType,
-		ClientAssertion:     input.ClientAssertion,
+		ClientID:                       input.ClientID,
+		ClientSecret:                   input.ClientSecret,
+		ClientAssertionType:            input.ClientAssertionType,
+		ClientAssertion:                input.ClientAssertion,
+		ClientCertificate:              input.ClientCertificate,
+		ClientCertificateChainVerified: input.ClientCertificateChainVerified,
 	}, true)
 	if err != nil {
 		return nil, err
 	}
 
+	err = s.verifyCustomScopesAllowed(ctx, client.ID, input.Scope, s.db)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate codes
 	deviceCode, err := utils.GenerateRandomAlphanumericString(32)
 	if err != nil {
@@ -1220,7 +3226,7 @@ func (s *OidcService) CreateDeviceAuthorization(ctx context.Context, input dto.O
 	}, nil
 }
 
-func (s *OidcService) VerifyDeviceCode(ctx context.Context, userCode string, userID string, ipAddress string, userAgent string) error {
+func (s *OidcService) VerifyDeviceCode(ctx context.Context, userCode string, userID string, auth AuthenticationInfo, ipAddress string, userAgent string) error {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -1268,8 +3274,15 @@ func (s *OidcService) VerifyDeviceCode(ctx context.Context, userCode string, use
 		return &common.OidcDeviceCodeExpiredError{}
 	}
 
+	// The user must have signed in with a method that satisfies the requirements of the client
+	if !isAcrSatisfied(&deviceAuth.Client, "", auth) {
+		return &common.OidcAcrNotSatisfiedError{}
+	}
+
 	deviceAuth.UserID = &userID
 	deviceAuth.IsAuthorized = true
+	deviceAuth.AuthTime = authTimeToModel(auth.Time)
+	deviceAuth.AuthenticationMethods = strings.Join(auth.Methods, " ")
 
 	err = tx.
 		WithContext(ctx).
@@ -1279,7 +3292,7 @@ func (s *OidcService) VerifyDeviceCode(ctx context.Context, userCode string, use
 		return fmt.Errorf("error saving device auth: %w", err)
 	}
 
-	hasAlreadyAuthorizedClient, err := s.createAuthorizedClientInternal(ctx, userID, deviceAuth.ClientID, deviceAuth.Scope, tx)
+	hasAlreadyAuthorizedClient, err := s.createAuthorizedClientInternal(ctx, userID, deviceAuth.ClientID, deviceAuth.Scope, nil, tx)
 	if err != nil {
 		return err
 	}
@@ -1316,7 +3329,7 @@ func (s *OidcService) GetDeviceCodeInfo(ctx context.Context, userCode string, us
 	hasAuthorizedClient := false
 	if userID != "" {
 		var err error
-		hasAuthorizedClient, err = s.HasAuthorizedClient(ctx, deviceAuth.ClientID, userID, deviceAuth.Scope)
+		hasAuthorizedClient, err = s.hasAuthorizedClientInternal(ctx, deviceAuth.ClientID, userID, deviceAuth.Scope, nil, s.db)
 		if err != nil {
 			return nil, err
 		}
@@ -1333,6 +3346,225 @@ func (s *OidcService) GetDeviceCodeInfo(ctx context.Context, userCode string, us
 	}, nil
 }
 
+// CreateBackchannelAuthentication starts a CIBA request for the user identified by the login hint, who then approves it from their Pocket ID session
+func (s *OidcService) CreateBackchannelAuthentication(ctx context.Context, input dto.OidcBackchannelAuthenticationRequestDto) (*dto.OidcBackchannelAuthenticationResponseDto, error) {
+	// Only confidential clients can use CIBA
+	client, err := s.verifyClientCredentialsInternal(ctx, s.db, ClientAuthCredentials{
+		ClientID:                       input.ClientID,
+		ClientSecret:                   input.ClientSecret,
+		ClientAssertionType:            input.ClientAssertionType,
+		ClientAssertion:                input.ClientAssertion,
+		ClientCertificate:              input.ClientCertificate,
+		ClientCertificateChainVerified: input.ClientCertificateChainVerified,
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clients must be explicitly allowed to ask users to sign in
+	if !client.BackchannelAuthenticationEnabled {
+		return nil, &common.OidcBackchannelAuthenticationNotAllowedError{}
+	}
+
+	if !slices.Contains(strings.Fields(input.Scope), "openid") {
+		return nil, &common.ValidationError{Message: "scope must contain openid"}
+	}
+
+	err = s.verifyCustomScopesAllowed(ctx, client.ID, input.Scope, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	// The login hint is the username or the email address of the user
+	var user model.User
+	err = s.db.
+		WithContext(ctx).
+		Where("(username = ? OR email = ?) AND disabled = ?", input.LoginHint, input.LoginHint, false).
+		First(&user).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &common.OidcUnknownUserError{}
+		}
+		return nil, err
+	}
+
+	authReqID, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresIn := BackchannelAuthenticationRequestDuration
+	if input.RequestedExpiry != nil {
+		expiresIn = min(expiresIn, time.Duration(*input.RequestedExpiry)*time.Second)
+	}
+
+	authRequest := &model.OidcBackchannelAuthenticationRequest{
+		AuthReqID:      authReqID,
+		Scope:          input.Scope,
+		BindingMessage: input.BindingMessage,
+		ExpiresAt:      datatype.DateTime(time.Now().Add(expiresIn)),
+		UserID:         user.ID,
+		ClientID:       client.ID,
+	}
+
+	err = s.db.WithContext(ctx).Create(authRequest).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.OidcBackchannelAuthenticationResponseDto{
+		AuthReqID: authReqID,
+		ExpiresIn: int(expiresIn.Seconds()),
+		Interval:  int(BackchannelAuthenticationPollingInterval.Seconds()),
+	}, nil
+}
+
+// ListPendingBackchannelAuthentications returns the CIBA requests that are waiting for the user to approve or deny them
+func (s *OidcService) ListPendingBackchannelAuthentications(ctx context.Context, userID string) ([]model.OidcBackchannelAuthenticationRequest, error) {
+	var authRequests []model.OidcBackchannelAuthenticationRequest
+	err := s.db.
+		WithContext(ctx).
+		Preload("Client").
+		Where("user_id = ? AND is_authorized = ? AND is_denied = ? AND expires_at > ?", userID, false, false, datatype.DateTime(time.Now())).
+		Order("created_at DESC").
+		Find(&authRequests).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return authRequests, nil
+}
+
+// ApproveBackchannelAuthentication approves a pending CIBA request of the user, so the client can retrieve the tokens
+func (s *OidcService) ApproveBackchannelAuthentication(ctx context.Context, id string, userID string, reauthenticationToken string, auth AuthenticationInfo, ipAddress string, userAgent string) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	authRequest, err := s.getPendingBackchannelAuthentication(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	var client model.OidcClient
+	err = tx.
+		WithContext(ctx).
+		Preload("AllowedUserGroups").
+		First(&client, "id = ?", authRequest.ClientID).
+		Error
+	if err != nil {
+		return err
+	}
+
+	// Check if the user group is allowed to authorize the client
+	var user model.User
+	err = tx.
+		WithContext(ctx).
+		Preload("UserGroups").
+		First(&user, "id = ?", userID).
+		Error
+	if err != nil {
+		return err
+	}
+
+	if !s.IsUserGroupAllowedToAuthorize(user, client) {
+		return &common.OidcAccessDeniedError{}
+	}
+
+	// A fresh passkey assertion is required if the client asks for it, like in the authorization endpoint
+	if client.RequiresReauthentication {
+		if reauthenticationToken == "" {
+			return &common.ReauthenticationRequiredError{}
+		}
+		methods, err := s.webAuthnService.ConsumeReauthenticationToken(ctx, tx, reauthenticationToken, userID)
+		if err != nil {
+			return err
+		}
+		auth = AuthenticationInfo{Time: time.Now(), Methods: methods}
+	}
+
+	// The user must have signed in with a method that satisfies the requirements of the client
+	if !isAcrSatisfied(&client, "", auth) {
+		return &common.OidcAcrNotSatisfiedError{}
+	}
+
+	authRequest.IsAuthorized = true
+	authRequest.AuthTime = authTimeToModel(auth.Time)
+	authRequest.AuthenticationMethods = strings.Join(auth.Methods, " ")
+
+	err = tx.
+		WithContext(ctx).
+		Save(&authRequest).
+		Error
+	if err != nil {
+		return err
+	}
+
+	hasAlreadyAuthorizedClient, err := s.createAuthorizedClientInternal(ctx, userID, authRequest.ClientID, authRequest.Scope, nil, tx)
+	if err != nil {
+		return err
+	}
+
+	auditLogData := model.AuditLogData{"clientName": client.Name}
+	if authRequest.BindingMessage != "" {
+		auditLogData["bindingMessage"] = authRequest.BindingMessage
+	}
+	if hasAlreadyAuthorizedClient {
+		s.auditLogService.Create(ctx, model.AuditLogEventBackchannelAuthorization, ipAddress, userAgent, userID, auditLogData, tx)
+	} else {
+		s.auditLogService.Create(ctx, model.AuditLogEventNewBackchannelAuthorization, ipAddress, userAgent, userID, auditLogData, tx)
+	}
+
+	return tx.Commit().Error
+}
+
+// DenyBackchannelAuthentication denies a pending CIBA request of the user, so the client receives an "access_denied" error
+func (s *OidcService) DenyBackchannelAuthentication(ctx context.Context, id string, userID string) error {
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	authRequest, err := s.getPendingBackchannelAuthentication(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	authRequest.IsDenied = true
+	err = tx.
+		WithContext(ctx).
+		Save(&authRequest).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// getPendingBackchannelAuthentication loads a CIBA request of the user that hasn't been approved or denied yet
+func (s *OidcService) getPendingBackchannelAuthentication(ctx context.Context, tx *gorm.DB, id string, userID string) (authRequest model.OidcBackchannelAuthenticationRequest, err error) {
+	err = tx.
+		WithContext(ctx).
+		First(&authRequest, "id = ? AND user_id = ?", id, userID).
+		Error
+	if err != nil {
+		return authRequest, err
+	}
+
+	if time.Now().After(authRequest.ExpiresAt.ToTime()) {
+		return authRequest, &common.OidcAuthReqIDExpiredError{}
+	}
+	if authRequest.IsAuthorized || authRequest.IsDenied {
+		return authRequest, &common.ValidationError{Message: "authentication request has already been answered"}
+	}
+
+	return authRequest, nil
+}
+
 func (s *OidcService) GetAllowedGroupsCountOfClient(ctx context.Context, id string) (int64, error) {
 	// We only perform select queries here, so we can rollback in all cases
 	tx := s.db.Begin()
@@ -1364,7 +3596,7 @@ func (s *OidcService) ListAuthorizedClients(ctx context.Context, userID string,
 	return authorizedClients, response, err
 }
 
-func (s *OidcService) RevokeAuthorizedClient(ctx context.Context, userID string, clientID string) error {
+func (s *OidcService) RevokeAuthorizedClient(ctx context.Context, userID string, clientID string, ipAddress string, userAgent string) error {
 	tx := s.db.Begin()
 	defer func() {
 		tx.Rollback()
@@ -1373,6 +3605,7 @@ func (s *OidcService) RevokeAuthorizedClient(ctx context.Context, userID string,
 	var authorizedClient model.UserAuthorizedOidcClient
 	err := tx.
 		WithContext(ctx).
+		Preload("Client").
 		Where("user_id = ? AND client_id = ?", userID, clientID).
 		First(&authorizedClient).Error
 	if err != nil {
@@ -1389,6 +3622,11 @@ func (s *OidcService) RevokeAuthorizedClient(ctx context.Context, userID string,
 		return err
 	}
 
+	// The session of the user at the client ends with the revocation
+	if authorizedClient.Client.BackchannelLogoutURL != nil && *authorizedClient.Client.BackchannelLogoutURL != "" {
+		s.SendBackchannelLogoutToClients(ctx, userID, []model.OidcClient{authorizedClient.Client}, ipAddress, userAgent, true)
+	}
+
 	return nil
 }
 
@@ -1470,7 +3708,19 @@ func (s *OidcService) ListAccessibleOidcClients(ctx context.Context, userID stri
 	return dtos, response, err
 }
 
-func (s *OidcService) createRefreshToken(ctx context.Context, clientID string, userID string, scope string, tx *gorm.DB) (string, error) {
+// issuesRefreshToken returns true if a refresh token is issued to the client for the given scope
+// Refresh tokens are only issued when the offline_access scope is requested, and they aren't disabled for the client
+// Clients created before offline_access was required keep receiving them without it, unless an admin opts them out
+func issuesRefreshToken(client *model.OidcClient, scope string) bool {
+	if client.RefreshTokensDisabled {
+		return false
+	}
+	return client.RefreshTokensWithoutOfflineAccess || slices.Contains(strings.Fields(scope), ScopeOfflineAccess)
+}
+
+// createRefreshToken creates a new refresh token for the client
+// If the refresh token replaces a rotated one, it keeps its absolute expiration and family
+func (s *OidcService) createRefreshToken(ctx context.Context, client *model.OidcClient, userID string, scope string, claimsRequest *model.OidcClaimsRequest, auth AuthenticationInfo, dpopJkt string, rotated *model.OidcRefreshToken, tx *gorm.DB) (string, error) {
 	refreshToken, err := utils.GenerateRandomAlphanumericString(40)
 	if err != nil {
 		return "", err
@@ -1480,12 +3730,41 @@ func (s *OidcService) createRefreshToken(ctx context.Context, clientID string, u
 	// Refresh tokens are pretty long already, so a "simple" SHA-256 hash is enough
 	refreshTokenHash := utils.CreateSha256Hash(refreshToken)
 
+	// Refresh tokens expire if they're not used for the idle lifetime, and they can't be used past the absolute expiration
+	now := time.Now()
+	lifetime := clientTokenLifetime(client.RefreshTokenIdleLifetime, RefreshTokenDuration)
+	var absoluteExpiresAt *datatype.DateTime
+	switch {
+	case rotated != nil:
+		absoluteExpiresAt = rotated.AbsoluteExpiresAt
+	case client.RefreshTokenAbsoluteLifetime != nil:
+		absoluteExpiresAt = utils.Ptr(datatype.DateTime(now.Add(clientTokenLifetime(client.RefreshTokenAbsoluteLifetime, 0))))
+	}
+	if absoluteExpiresAt != nil {
+		lifetime = min(lifetime, absoluteExpiresAt.ToTime().Sub(now))
+	}
+
 	m := model.OidcRefreshToken{
-		ExpiresAt: datatype.DateTime(time.Now().Add(RefreshTokenDuration)),
-		Token:     refreshTokenHash,
-		ClientID:  clientID,
-		UserID:    userID,
-		Scope:     scope,
+		ExpiresAt:             datatype.DateTime(now.Add(lifetime)),
+		AbsoluteExpiresAt:     absoluteExpiresAt,
+		Token:                 refreshTokenHash,
+		ClientID:              client.ID,
+		UserID:                userID,
+		Scope:                 scope,
+		Claims:                claimsRequest,
+		AuthTime:              authTimeToModel(auth.Time),
+		AuthenticationMethods: strings.Join(auth.Methods, " "),
+	}
+	if dpopJkt != "" {
+		m.DpopJkt = &dpopJkt
+	}
+
+	// A rotated token stays in the family of the token it replaces, while a new token starts its own family
+	if rotated != nil {
+		m.FamilyID = rotated.FamilyID
+	} else {
+		m.ID = uuid.New().String()
+		m.FamilyID = m.ID
 	}
 
 	err = tx.
@@ -1497,7 +3776,12 @@ func (s *OidcService) createRefreshToken(ctx context.Context, clientID string, u
 	}
 
 	// Sign the refresh token
-	signed, err := s.jwtService.GenerateOAuthRefreshToken(userID, clientID, refreshToken)
+	subject, err := s.getSubject(ctx, client, userID, tx)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := s.jwtService.GenerateOAuthRefreshToken(subject, client.ID, refreshToken, lifetime)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign refresh token: %w", err)
 	}
@@ -1505,20 +3789,38 @@ func (s *OidcService) createRefreshToken(ctx context.Context, clientID string, u
 	return signed, nil
 }
 
-func (s *OidcService) createAuthorizedClientInternal(ctx context.Context, userID string, clientID string, scope string, tx *gorm.DB) (hasAlreadyAuthorizedClient bool, err error) {
+func (s *OidcService) createAuthorizedClientInternal(ctx context.Context, userID string, clientID string, scope string, claimsRequest *model.OidcClaimsRequest, tx *gorm.DB) (hasAlreadyAuthorizedClient bool, err error) {
 
-	// Check if the user has already authorized the client with the given scope
-	hasAlreadyAuthorizedClient, err = s.hasAuthorizedClientInternal(ctx, clientID, userID, scope, tx)
+	// Check if the user has already authorized the client with the given scope and claims
+	hasAlreadyAuthorizedClient, err = s.hasAuthorizedClientInternal(ctx, clientID, userID, scope, claimsRequest, tx)
 	if err != nil {
 		return false, err
 	}
 
+	// Consent to individual claims adds up, so an authorization without the claims parameter keeps the earlier consent
+	updates := map[string]any{
+		"last_used_at": datatype.DateTime(time.Now()),
+	}
+	if claimsRequest != nil {
+		var existing model.UserAuthorizedOidcClient
+		err = tx.
+			WithContext(ctx).
+			Select("claims").
+			Take(&existing, "user_id = ? AND client_id = ?", userID, clientID).
+			Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+		claimsRequest = existing.Claims.Merge(claimsRequest)
+		updates["claims"] = claimsRequest
+	}
+
 	if hasAlreadyAuthorizedClient {
 		err = tx.
 			WithContext(ctx).
 			Model(&model.UserAuthorizedOidcClient{}).
 			Where("user_id = ? AND client_id = ?", userID, clientID).
-			Update("last_used_at", datatype.DateTime(time.Now())).
+			Updates(updates).
 			Error
 
 		if err != nil {
@@ -1532,13 +3834,18 @@ func (s *OidcService) createAuthorizedClientInternal(ctx context.Context, userID
 		UserID:     userID,
 		ClientID:   clientID,
 		Scope:      scope,
+		Claims:     claimsRequest,
 		LastUsedAt: datatype.DateTime(time.Now()),
 	}
 
+	updatedColumns := []string{"scope"}
+	if claimsRequest != nil {
+		updatedColumns = append(updatedColumns, "claims")
+	}
 	err = tx.WithContext(ctx).
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
-			DoUpdates: clause.AssignmentColumns([]string{"scope"}),
+			DoUpdates: clause.AssignmentColumns(updatedColumns),
 		}).
 		Create(&userAuthorizedClient).
 		Error
@@ -1551,14 +3858,19 @@ type ClientAuthCredentials struct {
 	ClientSecret        string
 	ClientAssertion     string
 	ClientAssertionType string
+	ClientCertificate   *x509.Certificate
+	// ClientCertificateChainVerified is true if the chain of the client certificate was verified against the client CAs or by the trusted proxy
+	ClientCertificateChainVerified bool
 }
 
 func clientAuthCredentialsFromCreateTokensDto(d *dto.OidcCreateTokensDto) ClientAuthCredentials {
 	return ClientAuthCredentials{
-		ClientID:            d.ClientID,
-		ClientSecret:        d.ClientSecret,
-		ClientAssertion:     d.ClientAssertion,
-		ClientAssertionType: d.ClientAssertionType,
+		ClientID:                       d.ClientID,
+		ClientSecret:                   d.ClientSecret,
+		ClientAssertion:                d.ClientAssertion,
+		ClientAssertionType:            d.ClientAssertionType,
+		ClientCertificate:              d.ClientCertificate,
+		ClientCertificateChainVerified: d.ClientCertificateChainVerified,
 	}
 }
 
@@ -1613,6 +3925,15 @@ func (s *OidcService) verifyClientCredentialsInternal(ctx context.Context, tx *g
 		}
 		return client, nil
 
+	// Next, check if the client authenticated with a TLS client certificate
+	case input.ClientCertificate != nil && client.Credentials.HasTLSClientAuth():
+		err = verifyClientCertificate(client, input.ClientCertificate, input.ClientCertificateChainVerified)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid certificate for client", slog.String("client", client.ID), slog.Any("error", err))
+			return nil, &common.OidcClientCertificateInvalidError{}
+		}
+		return client, nil
+
 	// There's no credentials
 	// This is allowed only if the client is public
 	case client.IsPublic && allowPublicClientsWithoutAuth:
@@ -1624,6 +3945,47 @@ func (s *OidcService) verifyClientCredentialsInternal(ctx context.Context, tx *g
 	}
 }
 
+// verifyClientCertificate checks that the client certificate matches the credentials registered for the client, as described in RFC 8705
+// With "tls_client_auth", the subject is only trusted if the certificate chain was verified against the client CAs or by the trusted proxy
+// With "self_signed_tls_client_auth", the certificate's public key must match one of the registered thumbprints
+func verifyClientCertificate(client *model.OidcClient, cert *x509.Certificate, chainVerified bool) error {
+	subjectDN := client.Credentials.TLSClientAuthSubjectDN
+	if subjectDN != "" && chainVerified && cert.Subject.String() == subjectDN {
+		return nil
+	}
+
+	if len(client.Credentials.TLSClientAuthThumbprints) > 0 {
+		key, err := jwk.Import(cert.PublicKey)
+		if err != nil {
+			return fmt.Errorf("failed to import certificate public key: %w", err)
+		}
+		thumbprint, err := key.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return fmt.Errorf("failed to compute certificate public key thumbprint: %w", err)
+		}
+		if slices.Contains(client.Credentials.TLSClientAuthThumbprints, base64.RawURLEncoding.EncodeToString(thumbprint)) {
+			return nil
+		}
+	}
+
+	return errors.New("client certificate does not match the registered credentials")
+}
+
+// VerifyAccessTokenCertificateBinding checks that an access token bound to a client certificate is presented with that certificate.
+// Access tokens that aren't bound are always accepted.
+func VerifyAccessTokenCertificateBinding(token jwt.Token, cert *x509.Certificate) error {
+	thumbprint := GetTokenConfirmation(token).CertificateThumbprint
+	if thumbprint == "" {
+		return nil
+	}
+
+	if cert == nil || utils.CertificateThumbprint(cert) != thumbprint {
+		return &common.TokenInvalidError{}
+	}
+
+	return nil
+}
+
 func (s *OidcService) jwkSetForURL(ctx context.Context, url string) (set jwk.Set, err error) {
 	// Check if we have already registered the URL
 	if !s.jwkCache.IsRegistered(ctx, url) {
@@ -1644,46 +4006,212 @@ func (s *OidcService) jwkSetForURL(ctx context.Context, url string) (set jwk.Set
 		}
 	}
 
-	jwks, err := s.jwkCache.CachedSet(url)
+	jwks, err := s.jwkCache.CachedSet(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached JWK set: %w", err)
+	}
+
+	return jwks, nil
+}
+
+// getClientKeySet returns the public keys of the client, which are either configured inline or loaded from its JWKS URL
+func (s *OidcService) getClientKeySet(ctx context.Context, client *model.OidcClient) (jwk.Set, error) {
+	if client.Jwks != nil && *client.Jwks != "" {
+		return jwk.ParseString(*client.Jwks)
+	}
+
+	if client.JwksURL != nil && *client.JwksURL != "" {
+		return s.jwkSetForURL(ctx, *client.JwksURL)
+	}
+
+	return nil, errors.New("client does not have a JWKS configured")
+}
+
+// getIDTokenEncryption returns how ID tokens are encrypted for the client
+func (s *OidcService) getIDTokenEncryption(ctx context.Context, client *model.OidcClient) (TokenEncryption, error) {
+	return s.getTokenEncryption(ctx, client, client.IdTokenEncryptedResponseAlg, client.IdTokenEncryptedResponseEnc)
+}
+
+// getUserInfoEncryption returns how userinfo responses are encrypted for the client
+func (s *OidcService) getUserInfoEncryption(ctx context.Context, client *model.OidcClient) (TokenEncryption, error) {
+	return s.getTokenEncryption(ctx, client, client.UserinfoEncryptedResponseAlg, client.UserinfoEncryptedResponseEnc)
+}
+
+// getTokenEncryption returns the key of the client and the algorithms to encrypt tokens with
+// If the client didn't register an algorithm, the encryption is empty
+func (s *OidcService) getTokenEncryption(ctx context.Context, client *model.OidcClient, alg *string, enc *string) (TokenEncryption, error) {
+	if alg == nil || *alg == "" {
+		return TokenEncryption{}, nil
+	}
+
+	keyAlg, ok := jwa.LookupKeyEncryptionAlgorithm(*alg)
+	if !ok || !slices.Contains(SupportedEncryptionAlgs, *alg) {
+		return TokenEncryption{}, fmt.Errorf("unsupported encryption algorithm: %s", *alg)
+	}
+
+	// Per spec, the content is encrypted with A128CBC-HS256 if the client only registered the algorithm for the key
+	contentEnc := jwa.A128CBC_HS256()
+	if enc != nil && *enc != "" {
+		contentEnc, ok = jwa.LookupContentEncryptionAlgorithm(*enc)
+		if !ok || !slices.Contains(SupportedEncryptionEncs, *enc) {
+			return TokenEncryption{}, fmt.Errorf("unsupported content encryption algorithm: %s", *enc)
+		}
+	}
+
+	jwks, err := s.getClientKeySet(ctx, client)
+	if err != nil {
+		return TokenEncryption{}, fmt.Errorf("failed to get JWK set for client: %w", err)
+	}
+
+	key, err := findEncryptionKey(jwks, keyAlg)
+	if err != nil {
+		return TokenEncryption{}, err
+	}
+
+	return TokenEncryption{Key: key, Alg: keyAlg, Enc: contentEnc}, nil
+}
+
+// findEncryptionKey returns the first key in the set that can be used to encrypt with the algorithm
+func findEncryptionKey(jwks jwk.Set, alg jwa.KeyEncryptionAlgorithm) (jwk.Key, error) {
+	// RSA-OAEP algorithms need an RSA key, ECDH-ES algorithms an EC or OKP key
+	keyTypes := []jwa.KeyType{jwa.RSA()}
+	if strings.HasPrefix(alg.String(), "ECDH-ES") {
+		keyTypes = []jwa.KeyType{jwa.EC(), jwa.OKP()}
+	}
+
+	for i := range jwks.Len() {
+		key, ok := jwks.Key(i)
+		if !ok || !slices.Contains(keyTypes, key.KeyType()) {
+			continue
+		}
+		if use, ok := key.KeyUsage(); ok && use != string(jwk.ForEncryption) {
+			continue
+		}
+		if keyAlg, ok := key.Algorithm(); ok && keyAlg.String() != alg.String() {
+			continue
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("client does not have a key for the encryption algorithm %s", alg.String())
+}
+
+// validateTokenEncryption checks that a client that wants encrypted tokens has keys to encrypt them with
+func validateTokenEncryption(client *model.OidcClient) error {
+	isSet := func(v *string) bool { return v != nil && *v != "" }
+
+	if (isSet(client.IdTokenEncryptedResponseEnc) && !isSet(client.IdTokenEncryptedResponseAlg)) ||
+		(isSet(client.UserinfoEncryptedResponseEnc) && !isSet(client.UserinfoEncryptedResponseAlg)) {
+		return &common.ValidationError{Message: "an encryption algorithm is required when setting the content encryption algorithm"}
+	}
+
+	if isSet(client.Jwks) {
+		_, err := jwk.ParseString(*client.Jwks)
+		if err != nil {
+			return &common.ValidationError{Message: "JWKS is not a valid JSON Web Key Set"}
+		}
+	}
+
+	encrypted := isSet(client.IdTokenEncryptedResponseAlg) || isSet(client.UserinfoEncryptedResponseAlg)
+	if encrypted && !isSet(client.Jwks) && !isSet(client.JwksURL) {
+		return &common.ValidationError{Message: "a JWKS or JWKS URL is required to encrypt tokens"}
+	}
+
+	return nil
+}
+
+// validateSigningAlgs checks that tokens and userinfo responses can be signed with the algorithms the client registered
+// If needed, a key for the algorithm is generated
+func (s *OidcService) validateSigningAlgs(client *model.OidcClient) error {
+	for _, alg := range []string{ptrValueOrEmpty(client.IdTokenSignedResponseAlg), ptrValueOrEmpty(client.UserinfoSignedResponseAlg)} {
+		if alg == "" {
+			continue
+		}
+		if !slices.Contains(common.SigningKeyAlgs, alg) {
+			return &common.ValidationError{Message: "unsupported signing algorithm: " + alg}
+		}
+
+		err := s.jwtService.EnsureSigningKey(alg)
+		if err != nil {
+			return fmt.Errorf("failed to get signing key for algorithm '%s': %w", alg, err)
+		}
+	}
+
+	return nil
+}
+
+// idTokenSigningAlg returns the algorithm used to sign the tokens issued to the client
+// An empty value means that tokens are signed with the active key
+func idTokenSigningAlg(client *model.OidcClient) string {
+	return ptrValueOrEmpty(client.IdTokenSignedResponseAlg)
+}
+
+func ptrValueOrEmpty(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func (s *OidcService) verifyClientAssertionFromFederatedIdentities(ctx context.Context, client *model.OidcClient, input ClientAuthCredentials) error {
+	// The subject defaults to the client ID, per RFC 7523
+	_, _, err := s.verifyFederatedToken(ctx, client, input.ClientAssertion, client.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cached JWK set: %w", err)
+		return fmt.Errorf("client assertion is not valid: %w", err)
 	}
 
-	return jwks, nil
+	// If we're here, the assertion is valid
+	return nil
 }
 
-func (s *OidcService) verifyClientAssertionFromFederatedIdentities(ctx context.Context, client *model.OidcClient, input ClientAuthCredentials) error {
-	// First, parse the assertion JWT, without validating it, to check the issuer
-	assertion := []byte(input.ClientAssertion)
-	insecureToken, err := jwt.ParseInsecure(assertion)
+// verifyFederatedToken checks the signature and claims of a JWT against each federated identity of the client that trusts the token's issuer, and returns the first identity that accepts it
+// The token's subject must equal the identity's subject or, for identities without one, defaultSubject; the subject isn't checked if both are empty
+func (s *OidcService) verifyFederatedToken(ctx context.Context, client *model.OidcClient, tokenString string, defaultSubject string) (jwt.Token, model.OidcClientFederatedIdentity, error) {
+	// First, parse the JWT, without validating it, to check the issuer
+	raw := []byte(tokenString)
+	insecureToken, err := jwt.ParseInsecure(raw)
 	if err != nil {
-		return fmt.Errorf("failed to parse client assertion JWT: %w", err)
+		return nil, model.OidcClientFederatedIdentity{}, fmt.Errorf("failed to parse JWT: %w", err)
 	}
 
 	issuer, _ := insecureToken.Issuer()
 	if issuer == "" {
-		return errors.New("client assertion does not contain an issuer claim")
+		return nil, model.OidcClientFederatedIdentity{}, errors.New("token does not contain an issuer claim")
 	}
 
 	// Ensure that this client is federated with the one that issued the token
-	ocfi, ok := client.Credentials.FederatedIdentityForIssuer(issuer)
-	if !ok {
-		return fmt.Errorf("client assertion is not from an allowed issuer: %s", issuer)
+	identities := client.Credentials.FederatedIdentitiesForIssuer(issuer)
+	if len(identities) == 0 {
+		return nil, model.OidcClientFederatedIdentity{}, fmt.Errorf("token is not from an allowed issuer: %s", issuer)
 	}
 
+	// There can be multiple federated identities for the same issuer, with different rules: the first one that matches is used
+	for _, ocfi := range identities {
+		var token jwt.Token
+		token, err = s.verifyFederatedTokenForIdentity(ctx, ocfi, raw, defaultSubject)
+		if err == nil {
+			return token, ocfi, nil
+		}
+	}
+
+	return nil, model.OidcClientFederatedIdentity{}, err
+}
+
+func (s *OidcService) verifyFederatedTokenForIdentity(ctx context.Context, ocfi model.OidcClientFederatedIdentity, raw []byte, defaultSubject string) (jwt.Token, error) {
 	// Get the JWK set for the issuer
 	jwksURL := ocfi.JWKS
 	if jwksURL == "" {
 		// Default URL is from the issuer
-		if strings.HasSuffix(issuer, "/") {
-			jwksURL = issuer + ".well-known/jwks.json"
+		if strings.HasSuffix(ocfi.Issuer, "/") {
+			jwksURL = ocfi.Issuer + ".well-known/jwks.json"
 		} else {
-			jwksURL = issuer + "/.well-known/jwks.json"
+			jwksURL = ocfi.Issuer + "/.well-known/jwks.json"
 		}
 	}
 	jwks, err := s.jwkSetForURL(ctx, jwksURL)
 	if err != nil {
-		return fmt.Errorf("failed to get JWK set for issuer '%s': %w", issuer, err)
+		return nil, fmt.Errorf("failed to get JWK set for issuer '%s': %w", ocfi.Issuer, err)
 	}
 
 	// Set default audience and subject if missing
@@ -1694,25 +4222,34 @@ func (s *OidcService) verifyClientAssertionFromFederatedIdentities(ctx context.C
 	}
 	subject := ocfi.Subject
 	if subject == "" {
-		// Default to the client ID, per RFC 7523
-		subject = client.ID
+		subject = defaultSubject
 	}
 
 	// Now re-parse the token with proper validation
 	// (Note: we don't use jwt.WithIssuer() because that would be redundant)
-	_, err = jwt.Parse(assertion,
+	opts := []jwt.ParseOption{
 		jwt.WithValidate(true),
 		jwt.WithAcceptableSkew(clockSkew),
 		jwt.WithKeySet(jwks, jws.WithInferAlgorithmFromKey(true), jws.WithUseDefault(true)),
 		jwt.WithAudience(audience),
-		jwt.WithSubject(subject),
-	)
+	}
+	if subject != "" {
+		opts = append(opts, jwt.WithSubject(subject))
+	}
+	token, err := jwt.Parse(raw, opts...)
 	if err != nil {
-		return fmt.Errorf("client assertion is not valid: %w", err)
+		return nil, err
 	}
 
-	// If we're here, the assertion is valid
-	return nil
+	// Check the additional claims the federated identity requires
+	for name, expected := range ocfi.Claims {
+		var value any
+		if !token.Has(name) || token.Get(name, &value) != nil || fmt.Sprint(value) != expected {
+			return nil, fmt.Errorf("claim '%s' does not have the expected value", name)
+		}
+	}
+
+	return token, nil
 }
 
 // extractClientIDFromAssertion extracts the client_id from the JWT assertion's 'sub' claim
@@ -1757,7 +4294,12 @@ func (s *OidcService) GetClientPreview(ctx context.Context, clientID string, use
 		return nil, &common.OidcAccessDeniedError{}
 	}
 
-	userClaims, err := s.getUserClaims(ctx, &user, scopes, tx)
+	userClaims, err := s.getUserClaims(ctx, &client, &user, scopes, nil, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := s.getSubject(ctx, &client, user.ID, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -1768,12 +4310,12 @@ func (s *OidcService) GetClientPreview(ctx context.Context, clientID string, use
 		return nil, err
 	}
 
-	idToken, err := s.jwtService.BuildIDToken(userClaims, clientID, "")
+	idToken, err := s.jwtService.BuildIDToken(userClaims, clientID, "", AuthenticationInfo{Time: time.Now(), Methods: AuthenticationMethodsPasskey}, clientTokenLifetime(client.IdTokenLifetime, IdTokenDuration))
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, err := s.jwtService.BuildOAuthAccessToken(user, clientID)
+	accessToken, err := s.jwtService.BuildOAuthAccessToken(subject, clientID, strings.Join(scopes, " "), TokenConfirmation{}, clientTokenLifetime(client.AccessTokenLifetime, AccessTokenDuration))
 	if err != nil {
 		return nil, err
 	}
@@ -1789,34 +4331,417 @@ func (s *OidcService) GetClientPreview(ctx context.Context, clientID string, use
 	}
 
 	return &dto.OidcClientPreviewDto{
-		IdToken:     idTokenPayload,
-		AccessToken: accessTokenPayload,
-		UserInfo:    userClaims,
+		IdToken:       idTokenPayload,
+		AccessToken:   accessTokenPayload,
+		UserInfo:      userClaims,
+		OfflineAccess: issuesRefreshToken(&client, strings.Join(scopes, " ")),
 	}, nil
 }
 
+// GetUserClaimsForClient returns the claims of the userinfo endpoint, for the scopes and claims the client was authorized for
 func (s *OidcService) GetUserClaimsForClient(ctx context.Context, userID string, clientID string) (map[string]any, error) {
-	return s.getUserClaimsForClientInternal(ctx, userID, clientID, s.db)
+	authorizedOidcClient, err := s.getAuthorizedClientInternal(ctx, userID, clientID, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getUserInfoClaims(ctx, &authorizedOidcClient)
+}
+
+// GetUserInfo returns the claims of the user identified by the subject of an access token issued to the client
+// If the client registered a signing or encryption algorithm for userinfo responses, the claims are also returned as a signed and/or encrypted token
+func (s *OidcService) GetUserInfo(ctx context.Context, subject string, clientID string) (claims map[string]any, token string, err error) {
+	var tokenClient model.OidcClient
+	err = s.db.WithContext(ctx).First(&tokenClient, "id = ?", clientID).Error
+	if err != nil {
+		return nil, "", err
+	}
+	userID, err := s.resolveSubject(ctx, &tokenClient, subject, s.db)
+	if err != nil {
+		return nil, "", err
+	}
+
+	authorizedOidcClient, err := s.getAuthorizedClientInternal(ctx, userID, clientID, s.db)
+	if err != nil {
+		return nil, "", err
+	}
+
+	claims, err = s.getUserInfoClaims(ctx, &authorizedOidcClient)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &authorizedOidcClient.Client
+	encryption, err := s.getUserInfoEncryption(ctx, client)
+	if err != nil {
+		return nil, "", err
+	}
+	// Without a signing algorithm, encrypted responses contain the claims as plain JSON
+	signingAlg := ptrValueOrEmpty(client.UserinfoSignedResponseAlg)
+	if signingAlg == "" && encryption.IsEmpty() {
+		return claims, "", nil
+	}
+
+	token, err = s.jwtService.GenerateUserInfoToken(claims, clientID, signingAlg, encryption)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return claims, token, nil
+}
+
+func (s *OidcService) getUserInfoClaims(ctx context.Context, authorizedOidcClient *model.UserAuthorizedOidcClient) (map[string]any, error) {
+	return s.getUserClaims(ctx, &authorizedOidcClient.Client, &authorizedOidcClient.User, authorizedOidcClient.Scopes(), authorizedOidcClient.Claims.UserinfoClaimNames(), s.db)
+}
+
+// getUserClaimsForClientInternal returns the claims for the scopes the client was authorized for, and the individual requested claims
+func (s *OidcService) getUserClaimsForClientInternal(ctx context.Context, userID string, clientID string, requestedClaims []string, tx *gorm.DB) (map[string]any, error) {
+	authorizedOidcClient, err := s.getAuthorizedClientInternal(ctx, userID, clientID, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getUserClaims(ctx, &authorizedOidcClient.Client, &authorizedOidcClient.User, authorizedOidcClient.Scopes(), requestedClaims, tx)
 }
 
-func (s *OidcService) getUserClaimsForClientInternal(ctx context.Context, userID string, clientID string, tx *gorm.DB) (map[string]any, error) {
+func (s *OidcService) getAuthorizedClientInternal(ctx context.Context, userID string, clientID string, tx *gorm.DB) (model.UserAuthorizedOidcClient, error) {
 	var authorizedOidcClient model.UserAuthorizedOidcClient
 	err := tx.
 		WithContext(ctx).
 		Preload("User.UserGroups").
+		Preload("Client").
 		First(&authorizedOidcClient, "user_id = ? AND client_id = ?", userID, clientID).
 		Error
+	return authorizedOidcClient, err
+}
+
+// getSubject returns the subject identifier of the user for the client
+// Clients with pairwise subject identifiers get a value derived from their sector, so clients of different sectors can't correlate users
+// The pairwise subject is recorded, so it can be mapped back to the user when the client presents a token containing it
+func (s *OidcService) getSubject(ctx context.Context, client *model.OidcClient, userID string, tx *gorm.DB) (string, error) {
+	if client.SubjectType != model.OidcSubjectTypePairwise || !isUserSubject(userID) {
+		return userID, nil
+	}
+
+	salt, err := s.getPairwiseSubjectSalt(ctx, tx)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(sectorIdentifier(client) + " " + userID))
+	subject := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	err = tx.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.OidcPairwiseSubject{Subject: subject, UserID: userID}).
+		Error
+	if err != nil {
+		return "", fmt.Errorf("failed to store pairwise subject: %w", err)
+	}
+
+	return subject, nil
+}
+
+// resolveSubject returns the ID of the user that the subject identifier, which was issued to the client, belongs to
+// Subjects that don't represent users are returned unchanged
+func (s *OidcService) resolveSubject(ctx context.Context, client *model.OidcClient, subject string, tx *gorm.DB) (string, error) {
+	if client.SubjectType != model.OidcSubjectTypePairwise || !isUserSubject(subject) {
+		return subject, nil
+	}
+
+	var pairwiseSubject model.OidcPairwiseSubject
+	err := tx.
+		WithContext(ctx).
+		First(&pairwiseSubject, "subject = ?", subject).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Tokens issued before pairwise subjects were used in every token contain the user ID
+		// This is safe, because the subject comes from a token that was signed by us
+		return subject, nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to load pairwise subject: %w", err)
+	}
+
+	return pairwiseSubject.UserID, nil
+}
+
+// isUserSubject returns true if the subject identifies a user, rather than a client or a federated identity
+func isUserSubject(subject string) bool {
+	return !strings.HasPrefix(subject, "client-") && !strings.HasPrefix(subject, FederatedSubjectPrefix)
+}
+
+// getPairwiseSubjectSalt returns the secret that pairwise subject identifiers are derived with
+// The secret is generated the first time it's needed
+func (s *OidcService) getPairwiseSubjectSalt(ctx context.Context, tx *gorm.DB) ([]byte, error) {
+	row := model.KV{Key: pairwiseSubjectSaltKey}
+	err := tx.WithContext(ctx).First(&row).Error
+	if err == nil && row.Value != nil {
+		return []byte(*row.Value), nil
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load pairwise subject salt: %w", err)
+	}
+
+	salt, err := utils.GenerateRandomAlphanumericString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// If a salt was stored concurrently, we keep that one
+	err = tx.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.KV{Key: pairwiseSubjectSaltKey, Value: &salt}).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store pairwise subject salt: %w", err)
+	}
+
+	row = model.KV{Key: pairwiseSubjectSaltKey}
+	err = tx.WithContext(ctx).First(&row).Error
+	if err != nil || row.Value == nil {
+		return nil, fmt.Errorf("failed to load pairwise subject salt: %w", err)
+	}
+
+	return []byte(*row.Value), nil
+}
+
+// sectorIdentifier returns the host that the pairwise subject identifiers of the client are derived from
+// This is the host of the sector identifier URI if set, otherwise the host of the callback URLs
+func sectorIdentifier(client *model.OidcClient) string {
+	var raw string
+	switch {
+	case client.SectorIdentifierURI != nil && *client.SectorIdentifierURI != "":
+		raw = *client.SectorIdentifierURI
+	case len(client.CallbackURLs) > 0:
+		raw = client.CallbackURLs[0]
+	default:
+		// Clients without callback URLs, e.g. the ones that only use the device authorization flow, are their own sector
+		return client.ID
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return client.ID
+	}
+	return u.Hostname()
+}
+
+// validateSectorIdentifier checks that the callback URLs of a client with pairwise subject identifiers belong to a single sector
+// If the client has a sector identifier URI, the JSON array it returns must contain all callback URLs
+// Otherwise, all callback URLs must have the same host
+func (s *OidcService) validateSectorIdentifier(ctx context.Context, client *model.OidcClient) error {
+	if client.SubjectType != model.OidcSubjectTypePairwise {
+		return nil
+	}
+
+	if client.SectorIdentifierURI == nil || *client.SectorIdentifierURI == "" {
+		for _, callbackURL := range client.CallbackURLs {
+			u, err := url.Parse(callbackURL)
+			if err != nil || u.Hostname() != sectorIdentifier(client) {
+				return &common.ValidationError{Message: "all callback URLs must have the same host when using pairwise subject identifiers without a sector identifier URI"}
+			}
+		}
+		return nil
+	}
+
+	redirectURIs, err := s.fetchSectorRedirectURIs(ctx, *client.SectorIdentifierURI)
+	if err != nil {
+		return &common.ValidationError{Message: "failed to fetch the sector identifier URI: " + err.Error()}
+	}
+
+	for _, callbackURL := range client.CallbackURLs {
+		if !slices.Contains(redirectURIs, callbackURL) {
+			return &common.ValidationError{Message: "callback URL is not listed in the sector identifier URI: " + callbackURL}
+		}
+	}
+
+	return nil
+}
+
+// fetchSectorRedirectURIs downloads the list of redirect URIs that the sector identifier URI points to
+func (s *OidcService) fetchSectorRedirectURIs(parentCtx context.Context, raw string) ([]string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, errors.New("the URL must use https")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 15*time.Second)
+	defer cancel()
+
+	err = verifyPublicHost(ctx, u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var redirectURIs []string
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&redirectURIs)
+	if err != nil {
+		return nil, errors.New("the response is not a JSON array of URLs")
+	}
+
+	return redirectURIs, nil
+}
+
+// getUserClaims returns the claims of the user for the scopes, with the subject identifier for the client
+// requestedClaims contains the names of individual claims that are included even if their scope isn't requested
+func (s *OidcService) getUserClaims(ctx context.Context, client *model.OidcClient, user *model.User, scopes []string, requestedClaims []string, tx *gorm.DB) (map[string]any, error) {
+	// Custom claims that a custom scope is defined for are only released with that scope, and not with "profile"
+	var allCustomScopes []model.OidcScope
+	err := tx.
+		WithContext(ctx).
+		Select("claims").
+		Find(&allCustomScopes).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	var withheldCustomClaims []string
+	for _, scope := range allCustomScopes {
+		withheldCustomClaims = append(withheldCustomClaims, scope.Claims...)
+	}
+
+	claims, err := s.getUserClaimsForScopes(ctx, user, scopes, withheldCustomClaims, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims["sub"], err = s.getSubject(ctx, client, user.ID, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Custom scopes release the claims that are configured for them
+	customScopes, err := s.getCustomScopes(ctx, scopes, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range customScopes {
+		requestedClaims = slices.Concat(requestedClaims, scope.Claims)
+	}
+
+	missing := slices.ContainsFunc(requestedClaims, func(name string) bool {
+		_, ok := claims[name]
+		return !ok
+	})
+	if !missing {
+		return claims, nil
+	}
+
+	// Load the claims of all scopes, then add the ones that were requested individually
+	allClaims, err := s.getUserClaimsForScopes(ctx, user, []string{"email", "groups", "profile"}, nil, tx)
 	if err != nil {
 		return nil, err
 	}
+	for _, name := range requestedClaims {
+		value, ok := allClaims[name]
+		if ok {
+			claims[name] = value
+		}
+	}
+
+	return claims, nil
+}
+
+// getCustomScopes returns the custom scopes defined by admins among the scopes
+func (s *OidcService) getCustomScopes(ctx context.Context, scopes []string, tx *gorm.DB) ([]model.OidcScope, error) {
+	var customScopes []model.OidcScope
+	if len(scopes) == 0 {
+		return customScopes, nil
+	}
+
+	err := tx.
+		WithContext(ctx).
+		Preload("AllowedClients").
+		Where("name IN ?", scopes).
+		Find(&customScopes).
+		Error
+	return customScopes, err
+}
+
+// verifyCustomClaimsAllowed checks that the client is allowed to request the claims individually
+// A claim that custom scopes are defined for can only be requested by clients that are allowed to request one of these scopes
+func (s *OidcService) verifyCustomClaimsAllowed(ctx context.Context, clientID string, claimNames []string, tx *gorm.DB) error {
+	if len(claimNames) == 0 {
+		return nil
+	}
+
+	var allCustomScopes []model.OidcScope
+	err := tx.
+		WithContext(ctx).
+		Preload("AllowedClients").
+		Find(&allCustomScopes).
+		Error
+	if err != nil {
+		return err
+	}
+
+	for _, name := range claimNames {
+		// Built-in claims are also released by the built-in scopes
+		if slices.Contains(BuiltInClaims, name) {
+			continue
+		}
+
+		restricted := false
+		for _, customScope := range allCustomScopes {
+			if !slices.Contains(customScope.Claims, name) {
+				continue
+			}
+			if customScope.IsAllowedForClient(clientID) {
+				restricted = false
+				break
+			}
+			restricted = true
+		}
+		if restricted {
+			return &common.OidcInvalidScopeError{}
+		}
+	}
+
+	return nil
+}
+
+// verifyCustomScopesAllowed checks that the client is allowed to request the custom scopes in the scope
+func (s *OidcService) verifyCustomScopesAllowed(ctx context.Context, clientID string, scope string, tx *gorm.DB) error {
+	customScopes, err := s.getCustomScopes(ctx, strings.Fields(scope), tx)
+	if err != nil {
+		return err
+	}
+
+	for _, customScope := range customScopes {
+		if !customScope.IsAllowedForClient(clientID) {
+			return &common.OidcInvalidScopeError{}
+		}
+	}
 
-	return s.getUserClaims(ctx, &authorizedOidcClient.User, authorizedOidcClient.Scopes(), tx)
+	return nil
 }
 
-func (s *OidcService) getUserClaims(ctx context.Context, user *model.User, scopes []string, tx *gorm.DB) (map[string]any, error) {
+// getUserClaimsForScopes returns the claims of the built-in scopes
+// The custom claims in withheldCustomClaims aren't included in the "profile" scope
+func (s *OidcService) getUserClaimsForScopes(ctx context.Context, user *model.User, scopes []string, withheldCustomClaims []string, tx *gorm.DB) (map[string]any, error) {
 	claims := make(map[string]any, 10)
 
-	claims["sub"] = user.ID
 	if slices.Contains(scopes, "email") {
 		claims["email"] = user.Email
 		claims["email_verified"] = s.appConfigService.GetDbConfig().EmailsVerified.IsTrue()
@@ -1838,6 +4763,10 @@ func (s *OidcService) getUserClaims(ctx context.Context, user *model.User, scope
 		}
 
 		for _, customClaim := range customClaims {
+			if slices.Contains(withheldCustomClaims, customClaim.Key) {
+				continue
+			}
+
 			// The value of the custom claim can be a JSON object or a string
 			var jsonValue any
 			err := json.Unmarshal([]byte(customClaim.Value), &jsonValue)
@@ -1892,17 +4821,9 @@ func (s *OidcService) downloadAndSaveLogoFromURL(parentCtx context.Context, tx *
 	ctx, cancel := context.WithTimeout(parentCtx, 15*time.Second)
 	defer cancel()
 
-	r := net.Resolver{}
-	ips, err := r.LookupIPAddr(ctx, u.Hostname())
-	if err != nil || len(ips) == 0 {
-		return fmt.Errorf("cannot resolve hostname")
-	}
-
-	// Prevents SSRF by allowing only public IPs
-	for _, addr := range ips {
-		if utils.IsPrivateIP(addr.IP) {
-			return fmt.Errorf("private IP addresses are not allowed")
-		}
+	err = verifyPublicHost(ctx, u.Hostname())
+	if err != nil {
+		return err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
@@ -1957,6 +4878,23 @@ func (s *OidcService) downloadAndSaveLogoFromURL(parentCtx context.Context, tx *
 	return nil
 }
 
+// verifyPublicHost checks that the hostname only resolves to public IPs, to prevent SSRF
+func verifyPublicHost(ctx context.Context, hostname string) error {
+	r := net.Resolver{}
+	ips, err := r.LookupIPAddr(ctx, hostname)
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("cannot resolve hostname")
+	}
+
+	for _, addr := range ips {
+		if utils.IsPrivateIP(addr.IP) {
+			return fmt.Errorf("private IP addresses are not allowed")
+		}
+	}
+
+	return nil
+}
+
 func (s *OidcService) updateClientLogoType(ctx context.Context, tx *gorm.DB, clientID, ext string) error {
 	uploadsDir := common.EnvConfig.UploadPath + "/oidc-client-images"
 
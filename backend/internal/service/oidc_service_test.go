@@ -2,23 +2,38 @@ package service
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/json"
+	"math/big"
 	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwe"
 	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 
 	"github.com/pocket-id/pocket-id/backend/internal/common"
 	"github.com/pocket-id/pocket-id/backend/internal/dto"
 	"github.com/pocket-id/pocket-id/backend/internal/model"
+	datatype "github.com/pocket-id/pocket-id/backend/internal/model/types"
+	"github.com/pocket-id/pocket-id/backend/internal/utils"
 	testutils "github.com/pocket-id/pocket-id/backend/internal/utils/testing"
 )
 
@@ -403,7 +418,7 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 					ClientID:     confidentialClient.ID,
 					ClientSecret: confidentialSecret,
 				}
-				token, err := s.createTokenFromClientCredentials(t.Context(), input)
+				token, err := s.createTokenFromClientCredentials(t.Context(), input, TokenConfirmation{})
 				require.NoError(t, err)
 				require.NotNil(t, token)
 
@@ -425,7 +440,7 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 					ClientID:     confidentialClient.ID,
 					ClientSecret: "invalid-secret",
 				}
-				_, err := s.createTokenFromClientCredentials(t.Context(), input)
+				_, err := s.createTokenFromClientCredentials(t.Context(), input, TokenConfirmation{})
 				require.Error(t, err)
 				require.ErrorIs(t, err, &common.OidcClientSecretInvalidError{})
 			})
@@ -434,7 +449,7 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 				input := dto.OidcCreateTokensDto{
 					ClientID: publicClient.ID,
 				}
-				_, err := s.createTokenFromClientCredentials(t.Context(), input)
+				_, err := s.createTokenFromClientCredentials(t.Context(), input, TokenConfirmation{})
 				require.Error(t, err)
 				require.ErrorIs(t, err, &common.OidcMissingClientCredentialsError{})
 			})
@@ -457,7 +472,7 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 					ClientAssertion:     string(signedToken),
 					ClientAssertionType: ClientAssertionTypeJWTBearer,
 				}
-				createdToken, err := s.createTokenFromClientCredentials(t.Context(), input)
+				createdToken, err := s.createTokenFromClientCredentials(t.Context(), input, TokenConfirmation{})
 				require.NoError(t, err)
 				require.NotNil(t, token)
 
@@ -479,7 +494,7 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 					ClientAssertion:     "invalid.jwt.token",
 					ClientAssertionType: ClientAssertionTypeJWTBearer,
 				}
-				_, err := s.createTokenFromClientCredentials(t.Context(), input)
+				_, err := s.createTokenFromClientCredentials(t.Context(), input, TokenConfirmation{})
 				require.Error(t, err)
 				require.ErrorIs(t, err, &common.OidcClientAssertionInvalidError{})
 			})
@@ -491,7 +506,7 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 					ClientSecret: confidentialSecret,
 					Resource:     "https://example.com/",
 				}
-				token, err := s.createTokenFromClientCredentials(t.Context(), input)
+				token, err := s.createTokenFromClientCredentials(t.Context(), input, TokenConfirmation{})
 				require.NoError(t, err)
 				require.NotNil(t, token)
 
@@ -510,3 +525,3248 @@ func TestOidcService_verifyClientCredentialsInternal(t *testing.T) {
 		})
 	})
 }
+
+func TestOidcService_RevokeToken(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+	}
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Confidential Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+		},
+	}, "test-user-id")
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	otherClient, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Other Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+		},
+	}, "test-user-id")
+	require.NoError(t, err)
+	otherClientSecret, err := s.CreateClientSecret(t.Context(), otherClient.ID)
+	require.NoError(t, err)
+
+	creds := ClientAuthCredentials{
+		ClientID:     client.ID,
+		ClientSecret: clientSecret,
+	}
+
+	countRefreshTokens := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		err := db.Model(&model.OidcRefreshToken{}).Where("client_id = ?", client.ID).Count(&count).Error
+		require.NoError(t, err)
+		return count
+	}
+
+	t.Run("Revokes refresh token", func(t *testing.T) {
+		refreshToken, err := s.createRefreshToken(t.Context(), &client, "test-user-id", "openid", nil, AuthenticationInfo{}, "", nil, db)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), countRefreshTokens(t))
+
+		err = s.RevokeToken(t.Context(), creds, refreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), countRefreshTokens(t))
+
+		// Revoking the same token again is not an error
+		err = s.RevokeToken(t.Context(), creds, refreshToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("Fails for token issued to another client", func(t *testing.T) {
+		refreshToken, err := s.createRefreshToken(t.Context(), &client, "test-user-id", "openid", nil, AuthenticationInfo{}, "", nil, db)
+		require.NoError(t, err)
+
+		err = s.RevokeToken(t.Context(), ClientAuthCredentials{
+			ClientID:     otherClient.ID,
+			ClientSecret: otherClientSecret,
+		}, refreshToken)
+		require.ErrorIs(t, err, &common.OidcClientIdNotMatchingError{})
+		assert.Equal(t, int64(1), countRefreshTokens(t))
+	})
+
+	t.Run("Fails with invalid client credentials", func(t *testing.T) {
+		err := s.RevokeToken(t.Context(), ClientAuthCredentials{
+			ClientID:     client.ID,
+			ClientSecret: "invalid-secret",
+		}, "some-token")
+		require.ErrorIs(t, err, &common.OidcClientSecretInvalidError{})
+	})
+
+	t.Run("Ignores invalid tokens", func(t *testing.T) {
+		err := s.RevokeToken(t.Context(), creds, "invalid.jwt.token")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rejects access tokens", func(t *testing.T) {
+		accessToken, err := s.jwtService.GenerateOAuthAccessToken("test-user-id", client.ID, "", TokenConfirmation{}, AccessTokenDuration, "")
+		require.NoError(t, err)
+
+		err = s.RevokeToken(t.Context(), creds, accessToken)
+		require.ErrorIs(t, err, &common.OidcUnsupportedTokenTypeError{})
+	})
+}
+
+func TestOidcService_PushAuthorizationRequest(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+	}
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Confidential Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+		},
+	}, "test-user-id")
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	t.Run("Stores request and consumes it once", func(t *testing.T) {
+		res, err := s.PushAuthorizationRequest(t.Context(), dto.OidcPushedAuthorizationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "openid profile",
+			CallbackURL:  "https://example.com/callback",
+			Nonce:        "test-nonce",
+			State:        "test-state",
+		})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(res.RequestURI, PushedAuthorizationRequestURIPrefix))
+		assert.Equal(t, int(PushedAuthorizationRequestDuration.Seconds()), res.ExpiresIn)
+
+		params, err := s.consumePushedAuthorizationRequest(t.Context(), db, client.ID, res.RequestURI)
+		require.NoError(t, err)
+		assert.Equal(t, "openid profile", params.Scope)
+		assert.Equal(t, "https://example.com/callback", params.CallbackURL)
+		assert.Equal(t, "test-nonce", params.Nonce)
+		assert.Equal(t, "test-state", params.State)
+
+		_, err = s.consumePushedAuthorizationRequest(t.Context(), db, client.ID, res.RequestURI)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestURIError{})
+	})
+
+	t.Run("Resolves the request for the consent screen without consuming it", func(t *testing.T) {
+		user := model.User{
+			Base:     model.Base{ID: "test-user-id"},
+			Username: "testuser",
+			Email:    utils.Ptr("test@example.com"),
+		}
+		require.NoError(t, db.Create(&user).Error)
+
+		res, err := s.PushAuthorizationRequest(t.Context(), dto.OidcPushedAuthorizationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "openid email",
+			CallbackURL:  "https://example.com/callback",
+			Claims:       `{"id_token":{"groups":null}}`,
+			Prompt:       "login",
+		})
+		require.NoError(t, err)
+
+		response, err := s.IsAuthorizationRequired(t.Context(), dto.AuthorizationRequiredDto{
+			ClientID:   client.ID,
+			RequestURI: res.RequestURI,
+		}, user.ID)
+		require.NoError(t, err)
+		assert.True(t, response.AuthorizationRequired)
+		assert.Equal(t, "openid email", response.Scope)
+		assert.JSONEq(t, `{"id_token":{"groups":null}}`, response.Claims)
+		assert.Equal(t, "login", response.Prompt)
+
+		params, err := s.consumePushedAuthorizationRequest(t.Context(), db, client.ID, res.RequestURI)
+		require.NoError(t, err)
+		assert.Equal(t, "openid email", params.Scope)
+
+		_, err = s.IsAuthorizationRequired(t.Context(), dto.AuthorizationRequiredDto{
+			ClientID:   client.ID,
+			RequestURI: res.RequestURI,
+		}, user.ID)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestURIError{})
+	})
+
+	t.Run("Fails for another client", func(t *testing.T) {
+		res, err := s.PushAuthorizationRequest(t.Context(), dto.OidcPushedAuthorizationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "openid",
+		})
+		require.NoError(t, err)
+
+		_, err = s.consumePushedAuthorizationRequest(t.Context(), db, "other-client", res.RequestURI)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestURIError{})
+	})
+
+	t.Run("Fails with invalid callback URL", func(t *testing.T) {
+		_, err := s.PushAuthorizationRequest(t.Context(), dto.OidcPushedAuthorizationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "openid",
+			CallbackURL:  "https://evil.com/callback",
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidCallbackURLError{})
+	})
+
+	t.Run("Fails without client credentials", func(t *testing.T) {
+		_, err := s.PushAuthorizationRequest(t.Context(), dto.OidcPushedAuthorizationRequestDto{
+			ClientID: client.ID,
+			Scope:    "openid",
+		})
+		require.ErrorIs(t, err, &common.OidcMissingClientCredentialsError{})
+	})
+}
+
+func TestOidcService_resolveRequestObject(t *testing.T) {
+	const jwksURL = "https://client.example.com/jwks.json"
+
+	db := testutils.NewDatabaseForTest(t)
+	privateJWK, jwkSetJSON := generateTestECDSAKey(t)
+	otherPrivateJWK, _ := generateTestECDSAKey(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	httpClient := &http.Client{
+		Transport: &testutils.MockRoundTripper{
+			Responses: map[string]*http.Response{
+				//nolint:bodyclose
+				jwksURL: testutils.NewMockResponse(http.StatusOK, string(jwkSetJSON)),
+			},
+		},
+	}
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		httpClient:       httpClient,
+	}
+	s.jwkCache, err = s.getJWKCache(t.Context())
+	require.NoError(t, err)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:                        "Client",
+			CallbackURLs:                []string{"https://example.com/callback"},
+			JwksURL:                     utils.Ptr(jwksURL),
+			RequiresSignedRequestObject: true,
+		},
+	}, "test-user-id")
+	require.NoError(t, err)
+
+	signRequestObject := func(t *testing.T, key jwk.Key, builderFn func(builder *jwt.Builder)) string {
+		t.Helper()
+
+		builder := jwt.NewBuilder().
+			Issuer(client.ID).
+			Audience([]string{common.EnvConfig.AppURL}).
+			IssuedAt(time.Now()).
+			Expiration(time.Now().Add(5*time.Minute)).
+			JwtID(uuid.New().String()).
+			Claim("client_id", client.ID).
+			Claim("scope", "openid profile").
+			Claim("redirect_uri", "https://example.com/callback").
+			Claim("state", "test-state")
+		if builderFn != nil {
+			builderFn(builder)
+		}
+
+		token, err := builder.Build()
+		require.NoError(t, err)
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), key))
+		require.NoError(t, err)
+		return string(signed)
+	}
+
+	t.Run("Succeeds with valid request object", func(t *testing.T) {
+		params, err := s.resolveRequestObject(t.Context(), db, &client, signRequestObject(t, privateJWK, nil), model.OidcAuthorizationRequestParameters{
+			Scope: "openid profile",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, "openid profile", params.Scope)
+		assert.Equal(t, "https://example.com/callback", params.CallbackURL)
+		assert.Equal(t, "test-state", params.State)
+	})
+
+	t.Run("Fails with mismatched parameters", func(t *testing.T) {
+		_, err := s.resolveRequestObject(t.Context(), db, &client, signRequestObject(t, privateJWK, nil), model.OidcAuthorizationRequestParameters{
+			Scope: "openid email",
+		}, true)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestObjectError{})
+	})
+
+	t.Run("Fails with unknown key", func(t *testing.T) {
+		_, err := s.resolveRequestObject(t.Context(), db, &client, signRequestObject(t, otherPrivateJWK, nil), model.OidcAuthorizationRequestParameters{}, true)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestObjectError{})
+	})
+
+	t.Run("Fails with wrong issuer", func(t *testing.T) {
+		_, err := s.resolveRequestObject(t.Context(), db, &client, signRequestObject(t, privateJWK, func(builder *jwt.Builder) {
+			builder.Issuer("another-client")
+		}), model.OidcAuthorizationRequestParameters{}, true)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestObjectError{})
+	})
+
+	t.Run("Fails without expiration or identifier", func(t *testing.T) {
+		for _, claim := range []string{jwt.ExpirationKey, jwt.JwtIDKey} {
+			token, err := jwt.ParseInsecure([]byte(signRequestObject(t, privateJWK, nil)))
+			require.NoError(t, err)
+			require.NoError(t, token.Remove(claim))
+			signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), privateJWK))
+			require.NoError(t, err)
+
+			_, err = s.resolveRequestObject(t.Context(), db, &client, string(signed), model.OidcAuthorizationRequestParameters{}, true)
+			require.ErrorIs(t, err, &common.OidcInvalidRequestObjectError{}, claim)
+		}
+	})
+
+	t.Run("Fails when the request object is replayed", func(t *testing.T) {
+		requestObject := signRequestObject(t, privateJWK, nil)
+
+		// Resolving the request object for the consent screen doesn't use it up
+		_, err := s.resolveRequestObject(t.Context(), db, &client, requestObject, model.OidcAuthorizationRequestParameters{}, false)
+		require.NoError(t, err)
+
+		_, err = s.resolveRequestObject(t.Context(), db, &client, requestObject, model.OidcAuthorizationRequestParameters{}, true)
+		require.NoError(t, err)
+
+		_, err = s.resolveRequestObject(t.Context(), db, &client, requestObject, model.OidcAuthorizationRequestParameters{}, true)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestObjectError{})
+	})
+
+	t.Run("Fails with unsigned request object", func(t *testing.T) {
+		token, err := jwt.NewBuilder().
+			Issuer(client.ID).
+			Audience([]string{common.EnvConfig.AppURL}).
+			Claim("scope", "openid").
+			Build()
+		require.NoError(t, err)
+		payload, err := json.Marshal(token)
+		require.NoError(t, err)
+		unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+
+		_, err = s.resolveRequestObject(t.Context(), db, &client, unsigned, model.OidcAuthorizationRequestParameters{}, true)
+		require.ErrorIs(t, err, &common.OidcInvalidRequestObjectError{})
+	})
+
+	t.Run("Pushed authorization request requires request object", func(t *testing.T) {
+		secret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+
+		_, err = s.PushAuthorizationRequest(t.Context(), dto.OidcPushedAuthorizationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: secret,
+			Scope:        "openid",
+		})
+		require.ErrorIs(t, err, &common.OidcRequestObjectRequiredError{})
+
+		res, err := s.PushAuthorizationRequest(t.Context(), dto.OidcPushedAuthorizationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: secret,
+			Request:      signRequestObject(t, privateJWK, nil),
+		})
+		require.NoError(t, err)
+
+		params, err := s.consumePushedAuthorizationRequest(t.Context(), db, client.ID, res.RequestURI)
+		require.NoError(t, err)
+		assert.Equal(t, "openid profile", params.Scope)
+	})
+}
+
+func TestOidcService_RegisterClient(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+	}
+
+	storedInitialAccessToken, initialAccessToken, err := s.CreateInitialAccessToken(t.Context(), time.Hour, 1, "test-user-id")
+	require.NoError(t, err)
+	assert.Equal(t, utils.CreateSha256Hash(initialAccessToken), storedInitialAccessToken.Token, "Only the hash of the token should be stored")
+
+	metadata := dto.OidcClientRegistrationDto{
+		ClientName:   "Registered Client",
+		RedirectURIs: []string{"https://example.com/callback"},
+	}
+
+	var registered *dto.OidcClientRegistrationResponseDto
+
+	t.Run("Registers a confidential client", func(t *testing.T) {
+		registered, err = s.RegisterClient(t.Context(), initialAccessToken, metadata)
+		require.NoError(t, err)
+		assert.NotEmpty(t, registered.ClientID)
+		assert.NotEmpty(t, registered.ClientSecret)
+		assert.NotEmpty(t, registered.RegistrationAccessToken)
+		assert.Equal(t, "client_secret_basic", registered.TokenEndpointAuthMethod)
+		assert.True(t, strings.HasSuffix(registered.RegistrationClientURI, "/api/oidc/register/"+registered.ClientID))
+
+		client, err := s.GetClient(t.Context(), registered.ClientID)
+		require.NoError(t, err)
+		assert.Equal(t, "Registered Client", client.Name)
+		require.NotNil(t, client.CreatedByID)
+		assert.Equal(t, "test-user-id", *client.CreatedByID)
+
+		_, err = s.verifyClientCredentialsInternal(t.Context(), db, ClientAuthCredentials{
+			ClientID:     registered.ClientID,
+			ClientSecret: registered.ClientSecret,
+		}, false)
+		require.NoError(t, err)
+	})
+
+	t.Run("Fails when the initial access token is used up", func(t *testing.T) {
+		_, err := s.RegisterClient(t.Context(), initialAccessToken, metadata)
+		require.ErrorIs(t, err, &common.OidcInvalidInitialAccessTokenError{})
+	})
+
+	t.Run("Fails with an unknown initial access token", func(t *testing.T) {
+		_, err := s.RegisterClient(t.Context(), "unknown", metadata)
+		require.ErrorIs(t, err, &common.OidcInvalidInitialAccessTokenError{})
+	})
+
+	t.Run("Reads and updates the client with the registration access token", func(t *testing.T) {
+		_, err := s.GetRegisteredClient(t.Context(), registered.ClientID, "wrong-token")
+		require.ErrorIs(t, err, &common.OidcInvalidRegistrationAccessTokenError{})
+
+		res, err := s.GetRegisteredClient(t.Context(), registered.ClientID, registered.RegistrationAccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "Registered Client", res.ClientName)
+		assert.Empty(t, res.ClientSecret)
+
+		updated := metadata
+		updated.ClientName = "Renamed Client"
+		updated.TokenEndpointAuthMethod = "none"
+		res, err = s.UpdateRegisteredClient(t.Context(), registered.ClientID, registered.RegistrationAccessToken, updated)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed Client", res.ClientName)
+		assert.Equal(t, "none", res.TokenEndpointAuthMethod)
+
+		client, err := s.GetClient(t.Context(), registered.ClientID)
+		require.NoError(t, err)
+		assert.True(t, client.IsPublic)
+		assert.True(t, client.PkceEnabled)
+	})
+
+	t.Run("Deletes the client with the registration access token", func(t *testing.T) {
+		err := s.DeleteRegisteredClient(t.Context(), registered.ClientID, "wrong-token")
+		require.ErrorIs(t, err, &common.OidcInvalidRegistrationAccessTokenError{})
+
+		err = s.DeleteRegisteredClient(t.Context(), registered.ClientID, registered.RegistrationAccessToken)
+		require.NoError(t, err)
+
+		_, err = s.GetClient(t.Context(), registered.ClientID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestOidcService_BackchannelLogout(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		httpClient: &http.Client{
+			Transport: &testutils.MockRoundTripper{
+				Responses: map[string]*http.Response{
+					"https://203.0.113.10/backchannel-logout": testutils.NewMockResponse(http.StatusOK, ""),
+				},
+			},
+		},
+	}
+
+	createClient := func(t *testing.T, name string, backchannelLogoutURL *string) model.OidcClient {
+		t.Helper()
+
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                 name,
+				CallbackURLs:         []string{"https://rp.example.com/callback"},
+				BackchannelLogoutURL: backchannelLogoutURL,
+			},
+		}, "test-user-id")
+		require.NoError(t, err)
+
+		err = db.Create(&model.UserAuthorizedOidcClient{
+			UserID:   "test-user-id",
+			ClientID: client.ID,
+			Scope:    "openid",
+		}).Error
+		require.NoError(t, err)
+
+		return client
+	}
+
+	workingClient := createClient(t, "Working Client", utils.Ptr("https://203.0.113.10/backchannel-logout"))
+	failingClient := createClient(t, "Failing Client", utils.Ptr("https://203.0.113.10/missing"))
+	createClient(t, "Client Without Logout", nil)
+
+	t.Run("Lists only clients with a back-channel logout URL", func(t *testing.T) {
+		clients, err := s.ListBackchannelLogoutClients(t.Context(), "test-user-id", db)
+		require.NoError(t, err)
+
+		clientIDs := make([]string, len(clients))
+		for i, c := range clients {
+			clientIDs[i] = c.ID
+		}
+		assert.ElementsMatch(t, []string{workingClient.ID, failingClient.ID}, clientIDs)
+	})
+
+	t.Run("Delivers the logout token", func(t *testing.T) {
+		err := s.deliverBackchannelLogout(t.Context(), "test-user-id", workingClient)
+		require.NoError(t, err)
+	})
+
+	t.Run("Rejects logout URLs of private hosts", func(t *testing.T) {
+		err := s.postBackchannelLogout(t.Context(), "https://127.0.0.1/backchannel-logout", "")
+		require.ErrorContains(t, err, "private IP addresses are not allowed")
+	})
+
+	t.Run("Stops retrying when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		err := s.deliverBackchannelLogout(ctx, "test-user-id", failingClient)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Revoking the authorization logs the user out of the client", func(t *testing.T) {
+		err := s.RevokeAuthorizedClient(t.Context(), "test-user-id", workingClient.ID, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			var auditLog model.AuditLog
+			err := db.First(&auditLog, "event = ?", model.AuditLogEventBackchannelLogout).Error
+			return err == nil && auditLog.Data["clientName"] == workingClient.Name && auditLog.Data["status"] == "delivered"
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+}
+
+func TestOidcService_GetFrontchannelLogoutURLs(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+	}
+
+	createClient := func(t *testing.T, input dto.OidcClientUpdateDto, userID string) {
+		t.Helper()
+
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{OidcClientUpdateDto: input}, "test-user-id")
+		require.NoError(t, err)
+
+		err = db.Create(&model.UserAuthorizedOidcClient{
+			UserID:   userID,
+			ClientID: client.ID,
+			Scope:    "openid",
+		}).Error
+		require.NoError(t, err)
+	}
+
+	createClient(t, dto.OidcClientUpdateDto{
+		Name:                  "Client",
+		FrontchannelLogoutURL: utils.Ptr("https://rp1.example.com/logout"),
+	}, "test-user-id")
+	createClient(t, dto.OidcClientUpdateDto{
+		Name:                              "Client With Session",
+		FrontchannelLogoutURL:             utils.Ptr("https://rp2.example.com/logout?foo=bar"),
+		FrontchannelLogoutSessionRequired: true,
+	}, "test-user-id")
+	createClient(t, dto.OidcClientUpdateDto{
+		Name: "Client Without Logout",
+	}, "test-user-id")
+	createClient(t, dto.OidcClientUpdateDto{
+		Name:                  "Client Of Other User",
+		FrontchannelLogoutURL: utils.Ptr("https://rp3.example.com/logout"),
+	}, "other-user-id")
+
+	logoutURLs, err := s.GetFrontchannelLogoutURLs(t.Context(), "test-user-id")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"https://rp1.example.com/logout",
+		"https://rp2.example.com/logout?foo=bar&iss=" + url.QueryEscape(common.EnvConfig.AppURL),
+	}, logoutURLs)
+}
+
+func TestOidcService_DPoP(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+	}
+
+	dpopKey, _ := generateTestECDSAKey(t)
+	publicDpopKey, err := dpopKey.PublicKey()
+	require.NoError(t, err)
+	thumbprint, err := publicDpopKey.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	expectedJkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	createProof := func(t *testing.T, method string, path string, accessToken string) string {
+		t.Helper()
+
+		builder := jwt.NewBuilder().
+			JwtID(uuid.New().String()).
+			IssuedAt(time.Now()).
+			Claim("htm", method).
+			Claim("htu", common.EnvConfig.AppURL+path)
+		if accessToken != "" {
+			ath := sha256.Sum256([]byte(accessToken))
+			builder = builder.Claim("ath", base64.RawURLEncoding.EncodeToString(ath[:]))
+		}
+		token, err := builder.Build()
+		require.NoError(t, err)
+
+		headers := jws.NewHeaders()
+		require.NoError(t, headers.Set(jws.TypeKey, "dpop+jwt"))
+		require.NoError(t, headers.Set(jws.JWKKey, publicDpopKey))
+
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), dpopKey, jws.WithProtectedHeaders(headers)))
+		require.NoError(t, err)
+		return string(signed)
+	}
+
+	t.Run("Validates a proof and rejects replays", func(t *testing.T) {
+		proof := createProof(t, http.MethodPost, "/api/oidc/token", "")
+
+		jkt, err := s.ValidateDpopProof(t.Context(), proof, http.MethodPost, "/api/oidc/token", "")
+		require.NoError(t, err)
+		assert.Equal(t, expectedJkt, jkt)
+
+		_, err = s.ValidateDpopProof(t.Context(), proof, http.MethodPost, "/api/oidc/token", "")
+		require.ErrorIs(t, err, &common.OidcInvalidDpopProofError{})
+	})
+
+	t.Run("Rejects a proof for another request", func(t *testing.T) {
+		_, err := s.ValidateDpopProof(t.Context(), createProof(t, http.MethodGet, "/api/oidc/token", ""), http.MethodPost, "/api/oidc/token", "")
+		require.ErrorIs(t, err, &common.OidcInvalidDpopProofError{})
+
+		_, err = s.ValidateDpopProof(t.Context(), createProof(t, http.MethodPost, "/api/oidc/userinfo", ""), http.MethodPost, "/api/oidc/token", "")
+		require.ErrorIs(t, err, &common.OidcInvalidDpopProofError{})
+	})
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "DPoP Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+			RequiresDpop: true,
+		},
+	}, "test-user-id")
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	t.Run("Requires a proof when the client requires DPoP", func(t *testing.T) {
+		_, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeClientCredentials,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.ErrorIs(t, err, &common.OidcDpopRequiredError{})
+	})
+
+	t.Run("Binds the access token to the proof key", func(t *testing.T) {
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeClientCredentials,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			DpopProof:    createProof(t, http.MethodPost, "/api/oidc/token", ""),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "DPoP", tokens.TokenType)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, expectedJkt, GetTokenConfirmation(token).DpopJkt)
+
+		introspection, err := s.IntrospectToken(t.Context(), ClientAuthCredentials{ClientID: client.ID, ClientSecret: clientSecret}, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"jkt": expectedJkt}, introspection.Confirmation)
+
+		// Using the bound token requires a proof for the same key
+		err = s.VerifyAccessTokenDpopBinding(t.Context(), token, "Bearer", tokens.AccessToken, "", http.MethodGet, "/api/oidc/userinfo")
+		require.ErrorIs(t, err, &common.OidcInvalidDpopProofError{})
+
+		proof := createProof(t, http.MethodGet, "/api/oidc/userinfo", tokens.AccessToken)
+		err = s.VerifyAccessTokenDpopBinding(t.Context(), token, "DPoP", tokens.AccessToken, proof, http.MethodGet, "/api/oidc/userinfo")
+		require.NoError(t, err)
+
+		proof = createProof(t, http.MethodGet, "/api/oidc/userinfo", "another-token")
+		err = s.VerifyAccessTokenDpopBinding(t.Context(), token, "DPoP", tokens.AccessToken, proof, http.MethodGet, "/api/oidc/userinfo")
+		require.ErrorIs(t, err, &common.OidcInvalidDpopProofError{})
+	})
+}
+
+// generateTestCertificate creates a self-signed certificate for testing
+func generateTestCertificate(t *testing.T, commonName string) *x509.Certificate {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Pocket ID"}},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func TestOidcService_TLSClientAuth(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+	}
+
+	cert := generateTestCertificate(t, "internal-service")
+	otherCert := generateTestCertificate(t, "internal-service")
+
+	certKey, err := jwk.Import(cert.PublicKey)
+	require.NoError(t, err)
+	thumbprint, err := certKey.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Self-signed Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+			Credentials: dto.OidcClientCredentialsDto{
+				TLSClientAuthThumbprints: []string{base64.RawURLEncoding.EncodeToString(thumbprint)},
+			},
+		},
+	}, "test-user-id")
+	require.NoError(t, err)
+
+	subjectDNClient, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Subject DN Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+			Credentials: dto.OidcClientCredentialsDto{
+				TLSClientAuthSubjectDN: cert.Subject.String(),
+			},
+		},
+	}, "test-user-id")
+	require.NoError(t, err)
+
+	t.Run("Authenticates with a registered self-signed certificate", func(t *testing.T) {
+		authenticatedClient, err := s.verifyClientCredentialsInternal(t.Context(), s.db, ClientAuthCredentials{
+			ClientID:          client.ID,
+			ClientCertificate: cert,
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, client.ID, authenticatedClient.ID)
+	})
+
+	t.Run("Rejects a certificate with the same subject but another key", func(t *testing.T) {
+		_, err := s.verifyClientCredentialsInternal(t.Context(), s.db, ClientAuthCredentials{
+			ClientID:          client.ID,
+			ClientCertificate: otherCert,
+		}, false)
+		require.ErrorIs(t, err, &common.OidcClientCertificateInvalidError{})
+	})
+
+	t.Run("Authenticates with the registered subject DN if the certificate chain was verified", func(t *testing.T) {
+		authenticatedClient, err := s.verifyClientCredentialsInternal(t.Context(), s.db, ClientAuthCredentials{
+			ClientID:                       subjectDNClient.ID,
+			ClientCertificate:              otherCert,
+			ClientCertificateChainVerified: true,
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, subjectDNClient.ID, authenticatedClient.ID)
+	})
+
+	t.Run("Rejects the registered subject DN if the certificate chain wasn't verified", func(t *testing.T) {
+		_, err := s.verifyClientCredentialsInternal(t.Context(), s.db, ClientAuthCredentials{
+			ClientID:          subjectDNClient.ID,
+			ClientCertificate: cert,
+		}, false)
+		require.ErrorIs(t, err, &common.OidcClientCertificateInvalidError{})
+	})
+
+	t.Run("Rejects another subject DN", func(t *testing.T) {
+		_, err := s.verifyClientCredentialsInternal(t.Context(), s.db, ClientAuthCredentials{
+			ClientID:                       subjectDNClient.ID,
+			ClientCertificate:              generateTestCertificate(t, "other-service"),
+			ClientCertificateChainVerified: true,
+		}, false)
+		require.ErrorIs(t, err, &common.OidcClientCertificateInvalidError{})
+	})
+
+	t.Run("Binds the access token to the certificate", func(t *testing.T) {
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:         GrantTypeClientCredentials,
+			ClientID:          client.ID,
+			ClientCertificate: cert,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer", tokens.TokenType)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, utils.CertificateThumbprint(cert), GetTokenConfirmation(token).CertificateThumbprint)
+
+		introspection, err := s.IntrospectToken(t.Context(), ClientAuthCredentials{ClientID: client.ID, ClientCertificate: cert}, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"x5t#S256": utils.CertificateThumbprint(cert)}, introspection.Confirmation)
+
+		require.NoError(t, VerifyAccessTokenCertificateBinding(token, cert))
+		require.ErrorIs(t, VerifyAccessTokenCertificateBinding(token, nil), &common.TokenInvalidError{})
+		require.ErrorIs(t, VerifyAccessTokenCertificateBinding(token, otherCert), &common.TokenInvalidError{})
+	})
+}
+
+func TestOidcService_Authorize_Prompt(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		webAuthnService:  &WebAuthnService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Prompt Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+		},
+	}, user.ID)
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	authorize := func(prompt string, maxAge *int, userID string, authTime time.Time, reauthenticationToken string) (*dto.AuthorizeOidcClientResponseDto, error) {
+		return s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:              client.ID,
+			Scope:                 "openid email",
+			CallbackURL:           "https://example.com/callback",
+			Prompt:                prompt,
+			MaxAge:                maxAge,
+			AuthTime:              authTime,
+			ReauthenticationToken: reauthenticationToken,
+		}, userID, "", "")
+	}
+
+	exchangeCode := func(t *testing.T, code string) jwt.Token {
+		t.Helper()
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		return idToken
+	}
+
+	t.Run("prompt=none returns login_required if the user isn't signed in", func(t *testing.T) {
+		response, err := authorize("none", nil, "", time.Time{}, "")
+		require.NoError(t, err)
+		assert.Equal(t, "login_required", response.Error)
+		assert.Equal(t, "https://example.com/callback", response.CallbackURL)
+		assert.Empty(t, response.Code)
+
+		_, err = authorize("", nil, "", time.Time{}, "")
+		require.ErrorIs(t, err, &common.NotSignedInError{})
+	})
+
+	t.Run("prompt=none returns consent_required if the user hasn't authorized the client", func(t *testing.T) {
+		response, err := authorize("none", nil, user.ID, time.Now(), "")
+		require.NoError(t, err)
+		assert.Equal(t, "consent_required", response.Error)
+		assert.Empty(t, response.Code)
+	})
+
+	t.Run("prompt=none can't be combined with other values", func(t *testing.T) {
+		_, err := authorize("none login", nil, user.ID, time.Now(), "")
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("ID token contains the authentication time", func(t *testing.T) {
+		authTime := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+		response, err := authorize("", nil, user.ID, authTime, "")
+		require.NoError(t, err)
+		require.NotEmpty(t, response.Code)
+
+		idToken := exchangeCode(t, response.Code)
+		var claim float64
+		require.NoError(t, idToken.Get(AuthTimeClaim, &claim))
+		assert.Equal(t, authTime.Unix(), int64(claim))
+
+		// Now that the client is authorized, prompt=none succeeds
+		response, err = authorize("none", nil, user.ID, authTime, "")
+		require.NoError(t, err)
+		assert.Empty(t, response.Error)
+		assert.NotEmpty(t, response.Code)
+	})
+
+	t.Run("prompt=login and max_age require reauthentication", func(t *testing.T) {
+		_, err := authorize("login", nil, user.ID, time.Now(), "")
+		require.ErrorIs(t, err, &common.ReauthenticationRequiredError{})
+
+		_, err = authorize("", utils.Ptr(60), user.ID, time.Now().Add(-2*time.Minute), "")
+		require.ErrorIs(t, err, &common.ReauthenticationRequiredError{})
+
+		response, err := authorize("none", utils.Ptr(60), user.ID, time.Now().Add(-2*time.Minute), "")
+		require.NoError(t, err)
+		assert.Equal(t, "login_required", response.Error)
+
+		response, err = authorize("", utils.Ptr(300), user.ID, time.Now().Add(-2*time.Minute), "")
+		require.NoError(t, err)
+		assert.NotEmpty(t, response.Code)
+	})
+
+	t.Run("Reauthentication updates the authentication time and methods", func(t *testing.T) {
+		reauthenticationToken := "reauthentication-token"
+		err := db.Create(&model.ReauthenticationToken{
+			Token:                 utils.CreateSha256Hash(reauthenticationToken),
+			ExpiresAt:             datatype.DateTime(time.Now().Add(time.Minute)),
+			AuthenticationMethods: "hwk user",
+			UserID:                user.ID,
+		}).Error
+		require.NoError(t, err)
+
+		response, err := authorize("login", nil, user.ID, time.Now().Add(-time.Hour), reauthenticationToken)
+		require.NoError(t, err)
+		require.NotEmpty(t, response.Code)
+
+		idToken := exchangeCode(t, response.Code)
+		var claim float64
+		require.NoError(t, idToken.Get(AuthTimeClaim, &claim))
+		assert.WithinDuration(t, time.Now(), time.Unix(int64(claim), 0), 5*time.Second)
+		assert.Equal(t, AuthenticationMethodsPasskey, GetAuthenticationMethods(idToken))
+	})
+
+	t.Run("prompt=consent requires the consent screen", func(t *testing.T) {
+		assert.True(t, IsConsentPromptRequested("login consent"))
+		assert.False(t, IsConsentPromptRequested("login"))
+	})
+}
+
+func TestOidcService_Authorize_Acr(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		webAuthnService:  &WebAuthnService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	createClient := func(t *testing.T, minimumAcr *string) (model.OidcClient, string) {
+		t.Helper()
+
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:         "ACR Client",
+				CallbackURLs: []string{"https://example.com/callback"},
+				MinimumAcr:   minimumAcr,
+			},
+		}, user.ID)
+		require.NoError(t, err)
+		clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+		return client, clientSecret
+	}
+
+	authorize := func(clientID string, acrValues string, methods []string) (*dto.AuthorizeOidcClientResponseDto, error) {
+		return s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:              clientID,
+			Scope:                 "openid offline_access",
+			CallbackURL:           "https://example.com/callback",
+			AcrValues:             acrValues,
+			AuthTime:              time.Now(),
+			AuthenticationMethods: methods,
+		}, user.ID, "", "")
+	}
+
+	t.Run("ID token contains the authentication methods of the session", func(t *testing.T) {
+		client, clientSecret := createClient(t, nil)
+
+		response, err := authorize(client.ID, "", AuthenticationMethodsOneTimeCode)
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		assert.Equal(t, AuthenticationMethodsOneTimeCode, GetAuthenticationMethods(idToken))
+		var acr string
+		require.NoError(t, idToken.Get(AuthenticationContextClassClaim, &acr))
+		assert.Equal(t, AcrOneTimeCode, acr)
+
+		// The authentication information is kept when the tokens are refreshed
+		refreshed, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: tokens.RefreshToken,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		idToken, err = s.jwtService.VerifyIdToken(refreshed.IdToken, false)
+		require.NoError(t, err)
+		assert.Equal(t, AuthenticationMethodsOneTimeCode, GetAuthenticationMethods(idToken))
+	})
+
+	t.Run("Client can require a minimum ACR", func(t *testing.T) {
+		client, _ := createClient(t, utils.Ptr(AcrPasskey))
+
+		_, err := authorize(client.ID, "", AuthenticationMethodsOneTimeCode)
+		require.ErrorIs(t, err, &common.OidcAcrNotSatisfiedError{})
+
+		response, err := authorize(client.ID, "", AuthenticationMethodsPasskey)
+		require.NoError(t, err)
+		assert.NotEmpty(t, response.Code)
+	})
+
+	t.Run("Requested ACR values must be satisfied", func(t *testing.T) {
+		client, _ := createClient(t, nil)
+
+		_, err := authorize(client.ID, AcrPasskey, AuthenticationMethodsOneTimeCode)
+		require.ErrorIs(t, err, &common.OidcAcrNotSatisfiedError{})
+
+		_, err = authorize(client.ID, AcrOneTimeCode+" "+AcrPasskey, AuthenticationMethodsOneTimeCode)
+		require.NoError(t, err)
+
+		_, err = authorize(client.ID, AcrOneTimeCode, AuthenticationMethodsPasskey)
+		require.NoError(t, err)
+
+		// Unsupported values are ignored
+		_, err = authorize(client.ID, "urn:example:unknown", nil)
+		require.NoError(t, err)
+	})
+}
+
+func TestOidcService_Authorize_ResponseMode(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		webAuthnService:  &WebAuthnService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Response Mode Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+		},
+	}, user.ID)
+	require.NoError(t, err)
+
+	authorize := func(responseMode string) (*dto.AuthorizeOidcClientResponseDto, error) {
+		return s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:     client.ID,
+			Scope:        "openid",
+			CallbackURL:  "https://example.com/callback",
+			State:        "test-state",
+			ResponseMode: responseMode,
+		}, user.ID, "", "")
+	}
+
+	// verifyResponse verifies the signed authorization response with the published keys
+	verifyResponse := func(t *testing.T, response string) jwt.Token {
+		t.Helper()
+
+		jwksJSON, err := mockJwtService.GetPublicJWKSAsJSON()
+		require.NoError(t, err)
+		jwks, err := jwk.Parse(jwksJSON)
+		require.NoError(t, err)
+
+		token, err := jwt.ParseString(response,
+			jwt.WithKeySet(jwks, jws.WithInferAlgorithmFromKey(true)),
+			jwt.WithValidate(true),
+			jwt.WithIssuer(common.EnvConfig.AppURL),
+			jwt.WithAudience(client.ID),
+		)
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("uses the query response mode by default", func(t *testing.T) {
+		response, err := authorize("")
+		require.NoError(t, err)
+		assert.Equal(t, "query", response.ResponseMode)
+		assert.NotEmpty(t, response.Code)
+		assert.Equal(t, "test-state", response.State)
+		assert.Empty(t, response.Response)
+	})
+
+	t.Run("returns the requested response mode", func(t *testing.T) {
+		for _, responseMode := range []string{"query", "fragment", "form_post"} {
+			response, err := authorize(responseMode)
+			require.NoError(t, err)
+			assert.Equal(t, responseMode, response.ResponseMode)
+			assert.NotEmpty(t, response.Code)
+			assert.Empty(t, response.Response)
+		}
+	})
+
+	t.Run("fails with an unsupported response mode", func(t *testing.T) {
+		_, err := authorize("web_message")
+		require.ErrorIs(t, err, &common.OidcUnsupportedResponseModeError{})
+	})
+
+	t.Run("signs the response with the JWT response modes", func(t *testing.T) {
+		for _, responseMode := range []string{"query.jwt", "form_post.jwt"} {
+			response, err := authorize(responseMode)
+			require.NoError(t, err)
+			assert.Equal(t, responseMode, response.ResponseMode)
+			assert.Equal(t, "https://example.com/callback", response.CallbackURL)
+			assert.Empty(t, response.Code)
+			assert.Empty(t, response.State)
+			require.NotEmpty(t, response.Response)
+
+			token := verifyResponse(t, response.Response)
+			var code, state string
+			require.NoError(t, token.Get("code", &code))
+			require.NoError(t, token.Get("state", &state))
+			assert.NotEmpty(t, code)
+			assert.Equal(t, "test-state", state)
+			_, ok := token.Expiration()
+			assert.True(t, ok)
+		}
+	})
+
+	t.Run("signs errors with the JWT response modes", func(t *testing.T) {
+		// The client was already authorized by the previous tests, so use another user
+		otherUser := model.User{
+			Base:     model.Base{ID: "other-user-id"},
+			Username: "otheruser",
+		}
+		require.NoError(t, db.Create(&otherUser).Error)
+
+		response, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:     client.ID,
+			Scope:        "openid",
+			CallbackURL:  "https://example.com/callback",
+			State:        "test-state",
+			Prompt:       "none",
+			ResponseMode: "query.jwt",
+		}, otherUser.ID, "", "")
+		require.NoError(t, err)
+		assert.Empty(t, response.Error)
+
+		token := verifyResponse(t, response.Response)
+		var errorCode string
+		require.NoError(t, token.Get("error", &errorCode))
+		assert.Equal(t, "consent_required", errorCode)
+		assert.False(t, token.Has("code"))
+	})
+
+	t.Run("validates the callback URL of form_post responses", func(t *testing.T) {
+		err := s.ValidateCallbackURL(t.Context(), client.ID, "https://example.com/callback")
+		require.NoError(t, err)
+
+		err = s.ValidateCallbackURL(t.Context(), client.ID, "https://attacker.example.com/callback")
+		require.ErrorIs(t, err, &common.OidcInvalidCallbackURLError{})
+	})
+}
+
+func TestOidcService_TokenExchange(t *testing.T) {
+	const federatedIssuer = "https://external-idp.com"
+
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	federatedKey, federatedJWKS := generateTestECDSAKey(t)
+	httpClient := &http.Client{
+		Transport: &testutils.MockRoundTripper{
+			Responses: map[string]*http.Response{
+				//nolint:bodyclose
+				federatedIssuer + "/.well-known/jwks.json": testutils.NewMockResponse(http.StatusOK, string(federatedJWKS)),
+			},
+		},
+	}
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		httpClient:       httpClient,
+	}
+	s.jwkCache, err = s.getJWKCache(t.Context())
+	require.NoError(t, err)
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	createClient := func(t *testing.T, id string, audiences []string) (model.OidcClient, string) {
+		t.Helper()
+
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                   "Service " + id,
+				CallbackURLs:           []string{"https://example.com/callback"},
+				TokenExchangeAudiences: audiences,
+				Credentials: dto.OidcClientCredentialsDto{
+					FederatedIdentities: []dto.OidcClientFederatedIdentityDto{
+						{Issuer: federatedIssuer, Subject: "workload-1"},
+						{Issuer: federatedIssuer, Subject: "workload-2", UserID: user.ID},
+						{Issuer: federatedIssuer, UserID: user.ID},
+					},
+				},
+			},
+			ID: id,
+		}, user.ID)
+		require.NoError(t, err)
+		clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+		return client, clientSecret
+	}
+
+	serviceA, serviceASecret := createClient(t, "service-a", []string{"service-b"})
+	serviceB, serviceBSecret := createClient(t, "service-b", []string{"https://api.example.com"})
+
+	exchange := func(client model.OidcClient, clientSecret string, input dto.OidcCreateTokensDto) (CreatedTokens, error) {
+		input.GrantType = GrantTypeTokenExchange
+		input.ClientID = client.ID
+		input.ClientSecret = clientSecret
+		if input.SubjectTokenType == "" {
+			input.SubjectTokenType = TokenTypeAccessToken
+		}
+		return s.CreateTokens(t.Context(), input)
+	}
+
+	// The user's token is obtained by service A with the authorization code flow
+	authorizeResponse, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+		ClientID:    serviceA.ID,
+		Scope:       "openid profile email",
+		CallbackURL: "https://example.com/callback",
+	}, user.ID, "", "")
+	require.NoError(t, err)
+	userTokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+		GrantType:    GrantTypeAuthorizationCode,
+		Code:         authorizeResponse.Code,
+		ClientID:     serviceA.ID,
+		ClientSecret: serviceASecret,
+	})
+	require.NoError(t, err)
+	userToken := userTokens.AccessToken
+
+	t.Run("Exchanges a user's access token for a token targeted at another audience", func(t *testing.T) {
+		tokens, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: userToken,
+			Audience:     serviceB.ID,
+			Scope:        "profile email",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, TokenTypeAccessToken, tokens.IssuedTokenType)
+		assert.Equal(t, "Bearer", tokens.TokenType)
+		assert.Empty(t, tokens.RefreshToken)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		sub, _ := token.Subject()
+		assert.Equal(t, user.ID, sub)
+		aud, _ := token.Audience()
+		assert.Equal(t, []string{serviceB.ID}, aud)
+		assert.Equal(t, "profile email", getStringClaim(token, ScopeClaim))
+		assert.False(t, token.Has(ActorClaim))
+	})
+
+	t.Run("Keeps the scopes of the subject token if none are requested", func(t *testing.T) {
+		tokens, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: userToken,
+			Audience:     serviceB.ID,
+		})
+		require.NoError(t, err)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "openid profile email", getStringClaim(token, ScopeClaim))
+	})
+
+	t.Run("Fails if scopes are requested for a subject token without scopes", func(t *testing.T) {
+		clientTokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeClientCredentials,
+			ClientID:     serviceA.ID,
+			ClientSecret: serviceASecret,
+		})
+		require.NoError(t, err)
+
+		_, err = exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: clientTokens.AccessToken,
+			Audience:     serviceB.ID,
+			Scope:        "profile",
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidScopeError{})
+
+		tokens, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: clientTokens.AccessToken,
+			Audience:     serviceB.ID,
+		})
+		require.NoError(t, err)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.False(t, token.Has(ScopeClaim))
+	})
+
+	t.Run("Requires proof of possession of the key the subject token is bound to", func(t *testing.T) {
+		cert := generateTestCertificate(t, "service-a")
+		otherCert := generateTestCertificate(t, "service-a")
+
+		boundTokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:         GrantTypeClientCredentials,
+			ClientID:          serviceA.ID,
+			ClientSecret:      serviceASecret,
+			ClientCertificate: cert,
+		})
+		require.NoError(t, err)
+
+		_, err = exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: boundTokens.AccessToken,
+			Audience:     serviceB.ID,
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+
+		_, err = exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken:      boundTokens.AccessToken,
+			Audience:          serviceB.ID,
+			ClientCertificate: otherCert,
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+
+		tokens, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken:      boundTokens.AccessToken,
+			Audience:          serviceB.ID,
+			ClientCertificate: cert,
+		})
+		require.NoError(t, err)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, utils.CertificateThumbprint(cert), GetTokenConfirmation(token).CertificateThumbprint)
+	})
+
+	t.Run("Records the chain of actors", func(t *testing.T) {
+		serviceAToken, err := s.jwtService.GenerateOAuthAccessToken("client-"+serviceA.ID, serviceA.ID, "", TokenConfirmation{}, AccessTokenDuration, "")
+		require.NoError(t, err)
+
+		tokens, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken:   userToken,
+			ActorToken:     serviceAToken,
+			ActorTokenType: TokenTypeAccessToken,
+			Audience:       serviceB.ID,
+			Scope:          "profile email",
+		})
+		require.NoError(t, err)
+
+		// Service B exchanges the token it received for a token targeted at the API, with less scopes
+		serviceBToken, err := s.jwtService.GenerateOAuthAccessToken("client-"+serviceB.ID, serviceB.ID, "", TokenConfirmation{}, AccessTokenDuration, "")
+		require.NoError(t, err)
+
+		_, err = exchange(serviceB, serviceBSecret, dto.OidcCreateTokensDto{
+			SubjectToken: tokens.AccessToken,
+			Audience:     "https://api.example.com",
+			Scope:        "profile admin",
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidScopeError{})
+
+		tokens, err = exchange(serviceB, serviceBSecret, dto.OidcCreateTokensDto{
+			SubjectToken:   tokens.AccessToken,
+			ActorToken:     serviceBToken,
+			ActorTokenType: TokenTypeAccessToken,
+			Resource:       "https://api.example.com",
+			Scope:          "profile",
+		})
+		require.NoError(t, err)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		sub, _ := token.Subject()
+		assert.Equal(t, user.ID, sub)
+		assert.Equal(t, "profile", getStringClaim(token, ScopeClaim))
+
+		var act map[string]any
+		require.NoError(t, token.Get(ActorClaim, &act))
+		assert.Equal(t, map[string]any{
+			"sub": "client-" + serviceB.ID,
+			"act": map[string]any{"sub": "client-" + serviceA.ID},
+		}, act)
+	})
+
+	t.Run("Fails with an audience the client is not allowed to request", func(t *testing.T) {
+		_, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: userToken,
+			Audience:     "https://api.example.com",
+		})
+		require.ErrorIs(t, err, &common.OidcTokenExchangeAudienceNotAllowedError{})
+
+		_, err = exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: userToken,
+		})
+		require.ErrorIs(t, err, &common.OidcTokenExchangeAudienceNotAllowedError{})
+	})
+
+	t.Run("Fails with a token issued to another client", func(t *testing.T) {
+		_, err := exchange(serviceB, serviceBSecret, dto.OidcCreateTokensDto{
+			SubjectToken: userToken,
+			Audience:     "https://api.example.com",
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+	})
+
+	t.Run("Fails with an invalid actor token", func(t *testing.T) {
+		_, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken:   userToken,
+			ActorToken:     "not-a-token",
+			ActorTokenType: TokenTypeAccessToken,
+			Audience:       serviceB.ID,
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidActorTokenError{})
+	})
+
+	t.Run("Fails with an unsupported requested token type", func(t *testing.T) {
+		_, err := exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken:       userToken,
+			Audience:           serviceB.ID,
+			RequestedTokenType: "urn:ietf:params:oauth:token-type:id_token",
+		})
+		require.ErrorIs(t, err, &common.OidcUnsupportedRequestedTokenTypeError{})
+	})
+
+	t.Run("Fails with a token of a disabled user", func(t *testing.T) {
+		disabledUser := model.User{
+			Base:     model.Base{ID: "disabled-user-id"},
+			Username: "disableduser",
+			Disabled: true,
+		}
+		require.NoError(t, db.Create(&disabledUser).Error)
+
+		disabledUserToken, err := s.jwtService.GenerateOAuthAccessToken(disabledUser.ID, serviceA.ID, "", TokenConfirmation{}, AccessTokenDuration, "")
+		require.NoError(t, err)
+
+		_, err = exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken: disabledUserToken,
+			Audience:     serviceB.ID,
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+	})
+
+	createFederatedToken := func(t *testing.T, subject string, jti string) string {
+		t.Helper()
+
+		builder := jwt.NewBuilder().
+			Issuer(federatedIssuer).
+			Audience([]string{common.EnvConfig.AppURL}).
+			Subject(subject).
+			IssuedAt(time.Now()).
+			Expiration(time.Now().Add(10 * time.Minute))
+		if jti != "" {
+			builder = builder.JwtID(jti)
+		}
+		federatedToken, err := builder.Build()
+		require.NoError(t, err)
+		signedToken, err := jwt.Sign(federatedToken, jwt.WithKey(jwa.ES256(), federatedKey))
+		require.NoError(t, err)
+		return string(signedToken)
+	}
+
+	exchangeFederatedToken := func(subjectToken string) (CreatedTokens, error) {
+		return exchange(serviceA, serviceASecret, dto.OidcCreateTokensDto{
+			SubjectToken:     subjectToken,
+			SubjectTokenType: TokenTypeJWT,
+			Audience:         serviceB.ID,
+		})
+	}
+
+	t.Run("Exchanges a token issued by a federated identity provider", func(t *testing.T) {
+		tokens, err := exchangeFederatedToken(createFederatedToken(t, "workload-1", uuid.New().String()))
+		require.NoError(t, err)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		sub, _ := token.Subject()
+		assert.Equal(t, FederatedSubjectPrefix+"workload-1", sub)
+	})
+
+	t.Run("Maps a federated token to the user of the identity with the matching subject", func(t *testing.T) {
+		tokens, err := exchangeFederatedToken(createFederatedToken(t, "workload-2", uuid.New().String()))
+		require.NoError(t, err)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		sub, _ := token.Subject()
+		assert.Equal(t, user.ID, sub)
+	})
+
+	t.Run("Rejects a federated token that only matches an identity mapped to a user without a subject", func(t *testing.T) {
+		_, err := exchangeFederatedToken(createFederatedToken(t, serviceA.ID, uuid.New().String()))
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+	})
+
+	t.Run("Rejects a federated token with an unknown subject", func(t *testing.T) {
+		_, err := exchangeFederatedToken(createFederatedToken(t, "workload-3", uuid.New().String()))
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+	})
+
+	t.Run("Rejects a federated token without an identifier", func(t *testing.T) {
+		_, err := exchangeFederatedToken(createFederatedToken(t, "workload-1", ""))
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+	})
+
+	t.Run("Rejects a replayed federated token", func(t *testing.T) {
+		subjectToken := createFederatedToken(t, "workload-1", uuid.New().String())
+		_, err := exchangeFederatedToken(subjectToken)
+		require.NoError(t, err)
+
+		_, err = exchangeFederatedToken(subjectToken)
+		require.ErrorIs(t, err, &common.OidcInvalidSubjectTokenError{})
+	})
+}
+
+func TestOidcService_JWTBearerGrant(t *testing.T) {
+	const (
+		federatedIssuer = "https://ci.example.com"
+		mainSubject     = "repo:org/app:ref:refs/heads/main"
+		devSubject      = "repo:org/app:ref:refs/heads/dev"
+	)
+
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	federatedKey, federatedJWKS := generateTestECDSAKey(t)
+	httpClient := &http.Client{
+		Transport: &testutils.MockRoundTripper{
+			Responses: map[string]*http.Response{
+				//nolint:bodyclose
+				federatedIssuer + "/.well-known/jwks.json": testutils.NewMockResponse(http.StatusOK, string(federatedJWKS)),
+			},
+		},
+	}
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		httpClient:       httpClient,
+	}
+	s.jwkCache, err = s.getJWKCache(t.Context())
+	require.NoError(t, err)
+
+	user := model.User{
+		Base:     model.Base{ID: "deploy-user-id"},
+		Username: "deploy",
+	}
+	require.NoError(t, db.Create(&user).Error)
+	disabledUser := model.User{
+		Base:     model.Base{ID: "disabled-user-id"},
+		Username: "disabled",
+		Disabled: true,
+	}
+	require.NoError(t, db.Create(&disabledUser).Error)
+
+	identities := []dto.OidcClientFederatedIdentityDto{
+		{
+			Issuer:  federatedIssuer,
+			Subject: mainSubject,
+			Claims:  map[string]string{"environment": "production"},
+			UserID:  user.ID,
+		},
+		{Issuer: federatedIssuer, Subject: devSubject},
+		{Issuer: federatedIssuer, Subject: "repo:org/disabled:ref:refs/heads/main", UserID: disabledUser.ID},
+	}
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "CI",
+			CallbackURLs: []string{"https://example.com/callback"},
+			IsPublic:     true,
+			Credentials:  dto.OidcClientCredentialsDto{FederatedIdentities: identities},
+		},
+	}, user.ID)
+	require.NoError(t, err)
+
+	confidentialClient, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Confidential",
+			CallbackURLs: []string{"https://example.com/callback"},
+			Credentials:  dto.OidcClientCredentialsDto{FederatedIdentities: identities},
+		},
+	}, user.ID)
+	require.NoError(t, err)
+
+	createAssertion := func(t *testing.T, subject string, claims map[string]any) string {
+		t.Helper()
+
+		builder := jwt.NewBuilder().
+			Issuer(federatedIssuer).
+			Audience([]string{common.EnvConfig.AppURL}).
+			Subject(subject).
+			JwtID(uuid.New().String()).
+			IssuedAt(time.Now()).
+			Expiration(time.Now().Add(10 * time.Minute))
+		for k, v := range claims {
+			builder = builder.Claim(k, v)
+		}
+		token, err := builder.Build()
+		require.NoError(t, err)
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), federatedKey))
+		require.NoError(t, err)
+		return string(signed)
+	}
+
+	createTokens := func(clientID string, assertion string) (CreatedTokens, error) {
+		return s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType: GrantTypeJWTBearer,
+			ClientID:  clientID,
+			Assertion: assertion,
+		})
+	}
+
+	getSubject := func(t *testing.T, tokens CreatedTokens) string {
+		t.Helper()
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		aud, _ := token.Audience()
+		assert.Equal(t, []string{client.ID}, aud)
+		sub, _ := token.Subject()
+		return sub
+	}
+
+	t.Run("Maps the assertion to the configured user", func(t *testing.T) {
+		tokens, err := createTokens(client.ID, createAssertion(t, mainSubject, map[string]any{"environment": "production"}))
+		require.NoError(t, err)
+		assert.Empty(t, tokens.IdToken)
+		assert.Empty(t, tokens.RefreshToken)
+		assert.Equal(t, user.ID, getSubject(t, tokens))
+	})
+
+	t.Run("Maps the assertion to the client's service account", func(t *testing.T) {
+		tokens, err := createTokens(client.ID, createAssertion(t, devSubject, nil))
+		require.NoError(t, err)
+		assert.Equal(t, "client-"+client.ID, getSubject(t, tokens))
+	})
+
+	t.Run("Fails if the claims don't match", func(t *testing.T) {
+		_, err := createTokens(client.ID, createAssertion(t, mainSubject, map[string]any{"environment": "staging"}))
+		require.ErrorIs(t, err, &common.OidcInvalidAssertionError{})
+
+		_, err = createTokens(client.ID, createAssertion(t, mainSubject, nil))
+		require.ErrorIs(t, err, &common.OidcInvalidAssertionError{})
+	})
+
+	t.Run("Fails if the subject doesn't match", func(t *testing.T) {
+		_, err := createTokens(client.ID, createAssertion(t, "repo:org/other:ref:refs/heads/main", nil))
+		require.ErrorIs(t, err, &common.OidcInvalidAssertionError{})
+	})
+
+	t.Run("Fails if the user is disabled", func(t *testing.T) {
+		_, err := createTokens(client.ID, createAssertion(t, "repo:org/disabled:ref:refs/heads/main", nil))
+		require.ErrorIs(t, err, &common.OidcInvalidAssertionError{})
+	})
+
+	t.Run("Fails without an assertion", func(t *testing.T) {
+		_, err := createTokens(client.ID, "")
+		require.ErrorIs(t, err, &common.OidcInvalidAssertionError{})
+	})
+
+	t.Run("Fails if the assertion is replayed", func(t *testing.T) {
+		assertion := createAssertion(t, devSubject, nil)
+		_, err := createTokens(client.ID, assertion)
+		require.NoError(t, err)
+
+		_, err = createTokens(client.ID, assertion)
+		require.ErrorIs(t, err, &common.OidcInvalidAssertionError{})
+	})
+
+	t.Run("Confidential clients must authenticate", func(t *testing.T) {
+		_, err := createTokens(confidentialClient.ID, createAssertion(t, devSubject, nil))
+		require.ErrorIs(t, err, &common.OidcMissingClientCredentialsError{})
+	})
+}
+
+func TestOidcService_TokenLifetimes(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		webAuthnService:  &WebAuthnService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	createTokens := func(t *testing.T, input dto.OidcClientUpdateDto) (model.OidcClient, string, CreatedTokens) {
+		t.Helper()
+
+		input.Name = "Lifetimes Client"
+		input.CallbackURLs = []string{"https://example.com/callback"}
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{OidcClientUpdateDto: input}, user.ID)
+		require.NoError(t, err)
+		clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+
+		response, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    client.ID,
+			Scope:       "openid email offline_access",
+			CallbackURL: "https://example.com/callback",
+			AuthTime:    time.Now(),
+		}, user.ID, "", "")
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		return client, clientSecret, tokens
+	}
+
+	getRefreshToken := func(t *testing.T, clientID string) model.OidcRefreshToken {
+		t.Helper()
+
+		var refreshToken model.OidcRefreshToken
+		require.NoError(t, db.First(&refreshToken, "client_id = ? AND rotated_at IS NULL", clientID).Error)
+		return refreshToken
+	}
+
+	assertExpiresIn := func(t *testing.T, expected time.Duration, expiration time.Time) {
+		t.Helper()
+		assert.WithinDuration(t, time.Now().Add(expected), expiration, 5*time.Second)
+	}
+
+	t.Run("Uses the default lifetimes", func(t *testing.T) {
+		client, _, tokens := createTokens(t, dto.OidcClientUpdateDto{})
+		assert.Equal(t, AccessTokenDuration, tokens.ExpiresIn)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		exp, _ := idToken.Expiration()
+		assertExpiresIn(t, IdTokenDuration, exp)
+
+		refreshToken := getRefreshToken(t, client.ID)
+		assertExpiresIn(t, RefreshTokenDuration, refreshToken.ExpiresAt.ToTime())
+		assert.Nil(t, refreshToken.AbsoluteExpiresAt)
+	})
+
+	t.Run("Uses the lifetimes configured for the client", func(t *testing.T) {
+		client, clientSecret, tokens := createTokens(t, dto.OidcClientUpdateDto{
+			AccessTokenLifetime:          utils.Ptr(300),
+			IdTokenLifetime:              utils.Ptr(600),
+			RefreshTokenIdleLifetime:     utils.Ptr(3600),
+			RefreshTokenAbsoluteLifetime: utils.Ptr(5400),
+		})
+		assert.Equal(t, 5*time.Minute, tokens.ExpiresIn)
+
+		accessToken, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		exp, _ := accessToken.Expiration()
+		assertExpiresIn(t, 5*time.Minute, exp)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		exp, _ = idToken.Expiration()
+		assertExpiresIn(t, 10*time.Minute, exp)
+
+		refreshToken := getRefreshToken(t, client.ID)
+		assertExpiresIn(t, time.Hour, refreshToken.ExpiresAt.ToTime())
+		require.NotNil(t, refreshToken.AbsoluteExpiresAt)
+		assertExpiresIn(t, 90*time.Minute, refreshToken.AbsoluteExpiresAt.ToTime())
+
+		// When the refresh token is rotated close to its absolute expiration, the new one expires at the same time
+		absoluteExpiresAt := datatype.DateTime(time.Now().Add(10 * time.Minute).Truncate(time.Second))
+		require.NoError(t, db.Model(&refreshToken).Update("absolute_expires_at", absoluteExpiresAt).Error)
+
+		tokens, err = s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: tokens.RefreshToken,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, tokens.ExpiresIn)
+
+		refreshToken = getRefreshToken(t, client.ID)
+		assertExpiresIn(t, 10*time.Minute, refreshToken.ExpiresAt.ToTime())
+		require.NotNil(t, refreshToken.AbsoluteExpiresAt)
+		assert.WithinDuration(t, absoluteExpiresAt.ToTime(), refreshToken.AbsoluteExpiresAt.ToTime(), time.Second)
+	})
+}
+
+func TestOidcService_RefreshTokenReuse(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration:              model.AppConfigVariable{Value: "60"}, // 60 minutes
+		RefreshTokenReuseGracePeriod: model.AppConfigVariable{Value: "10"}, // 10 seconds
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db, appConfigService: mockConfig},
+		webAuthnService:  &WebAuthnService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Reuse Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+		},
+	}, user.ID)
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	createTokens := func(t *testing.T) CreatedTokens {
+		t.Helper()
+
+		response, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    client.ID,
+			Scope:       "openid email offline_access",
+			CallbackURL: "https://example.com/callback",
+		}, user.ID, "", "")
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+		return tokens
+	}
+
+	refresh := func(refreshToken string) (CreatedTokens, error) {
+		return s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: refreshToken,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+	}
+
+	// Marks all rotated tokens as rotated before the grace period
+	expireGracePeriod := func(t *testing.T) {
+		t.Helper()
+		err := db.Model(&model.OidcRefreshToken{}).
+			Where("rotated_at IS NOT NULL").
+			Update("rotated_at", datatype.DateTime(time.Now().Add(-time.Minute))).
+			Error
+		require.NoError(t, err)
+	}
+
+	countRefreshTokens := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		require.NoError(t, db.Model(&model.OidcRefreshToken{}).Where("client_id = ?", client.ID).Count(&count).Error)
+		return count
+	}
+
+	countReuseAuditLogs := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		require.NoError(t, db.Model(&model.AuditLog{}).Where("event = ?", model.AuditLogEventRefreshTokenReuse).Count(&count).Error)
+		return count
+	}
+
+	t.Run("Rotated tokens stay in the same family", func(t *testing.T) {
+		tokens := createTokens(t)
+		rotated, err := refresh(tokens.RefreshToken)
+		require.NoError(t, err)
+
+		var refreshTokens []model.OidcRefreshToken
+		require.NoError(t, db.Order("created_at").Find(&refreshTokens, "client_id = ?", client.ID).Error)
+		require.Len(t, refreshTokens, 2)
+		assert.Equal(t, refreshTokens[0].FamilyID, refreshTokens[1].FamilyID)
+
+		// Clean up
+		err = s.RevokeToken(t.Context(), ClientAuthCredentials{ClientID: client.ID, ClientSecret: clientSecret}, rotated.RefreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), countRefreshTokens(t))
+	})
+
+	t.Run("Allows reuse within the grace period", func(t *testing.T) {
+		tokens := createTokens(t)
+		_, err := refresh(tokens.RefreshToken)
+		require.NoError(t, err)
+
+		// A concurrent request with the same token still succeeds
+		_, err = refresh(tokens.RefreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), countReuseAuditLogs(t))
+	})
+
+	t.Run("Revokes the token family on reuse after the grace period", func(t *testing.T) {
+		// Tokens from another family must not be affected
+		otherTokens := createTokens(t)
+
+		tokens := createTokens(t)
+		rotated, err := refresh(tokens.RefreshToken)
+		require.NoError(t, err)
+		expireGracePeriod(t)
+
+		_, err = refresh(tokens.RefreshToken)
+		require.ErrorIs(t, err, &common.OidcInvalidRefreshTokenError{})
+		assert.Equal(t, int64(1), countReuseAuditLogs(t))
+
+		// The token that was issued by the rotation was revoked too
+		_, err = refresh(rotated.RefreshToken)
+		require.ErrorIs(t, err, &common.OidcInvalidRefreshTokenError{})
+
+		_, err = refresh(otherTokens.RefreshToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("Rotated tokens are not active", func(t *testing.T) {
+		tokens := createTokens(t)
+		_, err := refresh(tokens.RefreshToken)
+		require.NoError(t, err)
+
+		introspection, err := s.IntrospectToken(t.Context(), ClientAuthCredentials{ClientID: client.ID, ClientSecret: clientSecret}, tokens.RefreshToken)
+		require.NoError(t, err)
+		assert.False(t, introspection.Active)
+	})
+}
+
+func TestOidcService_OfflineAccess(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		webAuthnService:  &WebAuthnService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	createClient := func(t *testing.T, refreshTokensDisabled bool) (model.OidcClient, string) {
+		t.Helper()
+
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                  "Offline Access Client",
+				CallbackURLs:          []string{"https://example.com/callback"},
+				RefreshTokensDisabled: refreshTokensDisabled,
+			},
+		}, user.ID)
+		require.NoError(t, err)
+		clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+		return client, clientSecret
+	}
+
+	createTokens := func(t *testing.T, clientID string, clientSecret string, scope string) CreatedTokens {
+		t.Helper()
+
+		response, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    clientID,
+			Scope:       scope,
+			CallbackURL: "https://example.com/callback",
+		}, user.ID, "", "")
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+		return tokens
+	}
+
+	countRefreshTokens := func(t *testing.T, clientID string) int64 {
+		t.Helper()
+		var count int64
+		require.NoError(t, db.Model(&model.OidcRefreshToken{}).Where("client_id = ?", clientID).Count(&count).Error)
+		return count
+	}
+
+	t.Run("Issues a refresh token only when offline_access is requested", func(t *testing.T) {
+		client, clientSecret := createClient(t, false)
+
+		tokens := createTokens(t, client.ID, clientSecret, "openid email")
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEmpty(t, tokens.IdToken)
+		assert.Empty(t, tokens.RefreshToken)
+		assert.Equal(t, int64(0), countRefreshTokens(t, client.ID))
+
+		tokens = createTokens(t, client.ID, clientSecret, "openid email offline_access")
+		assert.NotEmpty(t, tokens.RefreshToken)
+		assert.Equal(t, int64(1), countRefreshTokens(t, client.ID))
+
+		preview, err := s.GetClientPreview(t.Context(), client.ID, user.ID, []string{"openid", "email"})
+		require.NoError(t, err)
+		assert.False(t, preview.OfflineAccess)
+
+		preview, err = s.GetClientPreview(t.Context(), client.ID, user.ID, []string{"openid", "email", "offline_access"})
+		require.NoError(t, err)
+		assert.True(t, preview.OfflineAccess)
+	})
+
+	t.Run("Doesn't issue refresh tokens when disabled for the client", func(t *testing.T) {
+		client, clientSecret := createClient(t, true)
+
+		tokens := createTokens(t, client.ID, clientSecret, "openid email offline_access")
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.Empty(t, tokens.RefreshToken)
+		assert.Equal(t, int64(0), countRefreshTokens(t, client.ID))
+
+		preview, err := s.GetClientPreview(t.Context(), client.ID, user.ID, []string{"openid", "email", "offline_access"})
+		require.NoError(t, err)
+		assert.False(t, preview.OfflineAccess)
+	})
+
+	t.Run("Keeps issuing refresh tokens without offline_access to clients that opt in", func(t *testing.T) {
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                              "Legacy Client",
+				CallbackURLs:                      []string{"https://example.com/callback"},
+				RefreshTokensWithoutOfflineAccess: true,
+			},
+		}, user.ID)
+		require.NoError(t, err)
+		clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+
+		tokens := createTokens(t, client.ID, clientSecret, "openid email")
+		assert.NotEmpty(t, tokens.RefreshToken)
+		assert.Equal(t, int64(1), countRefreshTokens(t, client.ID))
+
+		preview, err := s.GetClientPreview(t.Context(), client.ID, user.ID, []string{"openid", "email"})
+		require.NoError(t, err)
+		assert.True(t, preview.OfflineAccess)
+	})
+
+	t.Run("Rejects refresh tokens issued before they were disabled", func(t *testing.T) {
+		client, clientSecret := createClient(t, false)
+		tokens := createTokens(t, client.ID, clientSecret, "openid email offline_access")
+		require.NotEmpty(t, tokens.RefreshToken)
+
+		require.NoError(t, db.Model(&client).Update("refresh_tokens_disabled", true).Error)
+
+		_, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: tokens.RefreshToken,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidRefreshTokenError{})
+	})
+}
+
+func TestOidcService_ClaimsParameter(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+		EmailsVerified:  model.AppConfigVariable{Value: "true"},
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:                 db,
+		jwtService:         mockJwtService,
+		appConfigService:   mockConfig,
+		auditLogService:    &AuditLogService{db: db},
+		webAuthnService:    &WebAuthnService{db: db},
+		customClaimService: NewCustomClaimService(db),
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Claims Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+		},
+	}, user.ID)
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	authorize := func(scope string, claims string) (*dto.AuthorizeOidcClientResponseDto, error) {
+		return s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    client.ID,
+			Scope:       scope,
+			Claims:      claims,
+			CallbackURL: "https://example.com/callback",
+		}, user.ID, "", "")
+	}
+
+	exchangeCode := func(t *testing.T, code string) CreatedTokens {
+		t.Helper()
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+		return tokens
+	}
+
+	t.Run("Includes the individually requested claims", func(t *testing.T) {
+		response, err := authorize("openid offline_access", `{"id_token":{"email":null,"unknown_claim":null},"userinfo":{"preferred_username":{"essential":true}}}`)
+		require.NoError(t, err)
+		tokens := exchangeCode(t, response.Code)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		var email string
+		require.NoError(t, idToken.Get("email", &email))
+		assert.Equal(t, "test@example.com", email)
+		assert.False(t, idToken.Has("email_verified"))
+		assert.False(t, idToken.Has("preferred_username"))
+		assert.False(t, idToken.Has("unknown_claim"))
+
+		userInfo, err := s.GetUserClaimsForClient(t.Context(), user.ID, client.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "testuser", userInfo["preferred_username"])
+		assert.NotContains(t, userInfo, "email")
+		assert.NotContains(t, userInfo, "given_name")
+
+		// The requested claims are kept when the tokens are refreshed
+		refreshed, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: tokens.RefreshToken,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+		idToken, err = s.jwtService.VerifyIdToken(refreshed.IdToken, false)
+		require.NoError(t, err)
+		assert.True(t, idToken.Has("email"))
+	})
+
+	t.Run("Only includes the claims of the scopes without the claims parameter", func(t *testing.T) {
+		response, err := authorize("openid", "")
+		require.NoError(t, err)
+		tokens := exchangeCode(t, response.Code)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		assert.False(t, idToken.Has("email"))
+
+		// The earlier consent isn't revoked by an authorization without the claims parameter
+		userInfo, err := s.GetUserClaimsForClient(t.Context(), user.ID, client.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "testuser", userInfo["preferred_username"])
+
+		hasAuthorized, err := s.HasAuthorizedClient(t.Context(), client.ID, user.ID, "openid", `{"id_token":{"email":null},"userinfo":{"preferred_username":null}}`)
+		require.NoError(t, err)
+		assert.True(t, hasAuthorized)
+	})
+
+	t.Run("Requires consent for claims the user hasn't consented to", func(t *testing.T) {
+		_, err := authorize("openid", `{"id_token":{"email":null}}`)
+		require.NoError(t, err)
+
+		hasAuthorized, err := s.HasAuthorizedClient(t.Context(), client.ID, user.ID, "openid", `{"id_token":{"email":null}}`)
+		require.NoError(t, err)
+		assert.True(t, hasAuthorized)
+
+		hasAuthorized, err = s.HasAuthorizedClient(t.Context(), client.ID, user.ID, "openid", "")
+		require.NoError(t, err)
+		assert.True(t, hasAuthorized)
+
+		hasAuthorized, err = s.HasAuthorizedClient(t.Context(), client.ID, user.ID, "openid", `{"userinfo":{"email":null,"groups":null}}`)
+		require.NoError(t, err)
+		assert.False(t, hasAuthorized)
+
+		// With prompt=none, the claims can't be released without consent
+		response, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    client.ID,
+			Scope:       "openid",
+			Claims:      `{"userinfo":{"groups":null}}`,
+			CallbackURL: "https://example.com/callback",
+			Prompt:      "none",
+		}, user.ID, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "consent_required", response.Error)
+		assert.Empty(t, response.Code)
+	})
+
+	t.Run("Rejects an invalid claims parameter", func(t *testing.T) {
+		_, err := authorize("openid", "not-json")
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+}
+
+func TestOidcService_CustomScopes(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	customClaimService := NewCustomClaimService(db)
+	scopeService := NewOidcScopeService(db)
+	s := &OidcService{
+		db:                 db,
+		jwtService:         mockJwtService,
+		appConfigService:   mockConfig,
+		auditLogService:    &AuditLogService{db: db},
+		webAuthnService:    &WebAuthnService{db: db},
+		customClaimService: customClaimService,
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+	_, err = customClaimService.UpdateCustomClaimsForUser(t.Context(), user.ID, []dto.CustomClaimCreateDto{
+		{Key: "team", Value: "platform"},
+		{Key: "cost_center", Value: "42"},
+		{Key: "nickname", Value: "tester"},
+	})
+	require.NoError(t, err)
+
+	createClient := func(t *testing.T) (model.OidcClient, string) {
+		t.Helper()
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:         "Scopes Client",
+				CallbackURLs: []string{"https://example.com/callback"},
+			},
+		}, user.ID)
+		require.NoError(t, err)
+		clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+		return client, clientSecret
+	}
+
+	authorize := func(clientID string, scope string) (*dto.AuthorizeOidcClientResponseDto, error) {
+		return s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    clientID,
+			Scope:       scope,
+			CallbackURL: "https://example.com/callback",
+		}, user.ID, "", "")
+	}
+
+	client, clientSecret := createClient(t)
+	otherClient, _ := createClient(t)
+
+	scope, err := scopeService.Create(t.Context(), dto.OidcScopeCreateDto{
+		Name:   "nextcloud",
+		Claims: []string{"email", "team"},
+	})
+	require.NoError(t, err)
+	_, err = scopeService.Create(t.Context(), dto.OidcScopeCreateDto{
+		Name:             "billing",
+		Claims:           []string{"cost_center"},
+		AllowedClientIDs: []string{otherClient.ID},
+	})
+	require.NoError(t, err)
+
+	t.Run("Releases the claims of the custom scope", func(t *testing.T) {
+		response, err := authorize(client.ID, "openid nextcloud")
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		var team string
+		require.NoError(t, idToken.Get("team", &team))
+		assert.Equal(t, "platform", team)
+		assert.True(t, idToken.Has("email"))
+		assert.False(t, idToken.Has("cost_center"))
+		assert.False(t, idToken.Has("given_name"))
+
+		userInfo, err := s.GetUserClaimsForClient(t.Context(), user.ID, client.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "platform", userInfo["team"])
+	})
+
+	t.Run("Only releases the custom claims without a custom scope with the profile scope", func(t *testing.T) {
+		response, err := authorize(client.ID, "openid profile")
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		assert.True(t, idToken.Has("nickname"))
+		assert.True(t, idToken.Has("given_name"))
+		assert.False(t, idToken.Has("team"))
+		assert.False(t, idToken.Has("cost_center"))
+	})
+
+	t.Run("Rejects custom scopes the client isn't allowed to request", func(t *testing.T) {
+		_, err := authorize(client.ID, "openid billing")
+		require.ErrorIs(t, err, &common.OidcInvalidScopeError{})
+
+		_, err = authorize(otherClient.ID, "openid billing")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rejects claims of custom scopes the client isn't allowed to request", func(t *testing.T) {
+		request := dto.AuthorizeOidcClientRequestDto{
+			ClientID:    client.ID,
+			Scope:       "openid",
+			Claims:      `{"userinfo":{"cost_center":null}}`,
+			CallbackURL: "https://example.com/callback",
+		}
+		_, err := s.Authorize(t.Context(), request, user.ID, "", "")
+		require.ErrorIs(t, err, &common.OidcInvalidScopeError{})
+
+		request.Claims = `{"userinfo":{"team":null,"nickname":null,"email":null}}`
+		_, err = s.Authorize(t.Context(), request, user.ID, "", "")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rejects custom scopes the client isn't allowed to request with the client credentials grant", func(t *testing.T) {
+		_, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeClientCredentials,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "billing",
+		})
+		require.ErrorIs(t, err, &common.OidcInvalidScopeError{})
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeClientCredentials,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "nextcloud",
+		})
+		require.NoError(t, err)
+
+		token, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "nextcloud", getStringClaim(token, ScopeClaim))
+	})
+
+	t.Run("Updates custom scopes", func(t *testing.T) {
+		updated, err := scopeService.Update(t.Context(), scope.ID, dto.OidcScopeCreateDto{
+			Name:             "nextcloud",
+			Description:      "Access to Nextcloud",
+			Claims:           []string{"team"},
+			AllowedClientIDs: []string{client.ID},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.StringList{"team"}, updated.Claims)
+
+		updated, err = scopeService.Get(t.Context(), scope.ID)
+		require.NoError(t, err)
+		require.Len(t, updated.AllowedClients, 1)
+		assert.Equal(t, client.ID, updated.AllowedClients[0].ID)
+
+		var scopeDto dto.OidcScopeDto
+		require.NoError(t, dto.MapStruct(updated, &scopeDto))
+		assert.Equal(t, []string{"team"}, scopeDto.Claims)
+		require.Len(t, scopeDto.AllowedClients, 1)
+		assert.Equal(t, client.ID, scopeDto.AllowedClients[0].ID)
+	})
+
+	t.Run("Rejects built-in and duplicate scope names", func(t *testing.T) {
+		_, err := scopeService.Create(t.Context(), dto.OidcScopeCreateDto{Name: "profile", Claims: []string{"team"}})
+		require.ErrorIs(t, err, &common.AlreadyInUseError{})
+
+		_, err = scopeService.Create(t.Context(), dto.OidcScopeCreateDto{Name: "nextcloud", Claims: []string{"team"}})
+		require.ErrorIs(t, err, &common.AlreadyInUseError{})
+	})
+}
+
+func TestOidcService_PairwiseSubject(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:                 db,
+		jwtService:         mockJwtService,
+		appConfigService:   mockConfig,
+		auditLogService:    &AuditLogService{db: db},
+		webAuthnService:    &WebAuthnService{db: db},
+		customClaimService: NewCustomClaimService(db),
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	createClient := func(t *testing.T, subjectType string, callbackURL string) (model.OidcClient, string) {
+		t.Helper()
+		client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:         "Pairwise Client",
+				CallbackURLs: []string{callbackURL},
+				SubjectType:  subjectType,
+			},
+		}, user.ID)
+		require.NoError(t, err)
+		clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+		require.NoError(t, err)
+		return client, clientSecret
+	}
+
+	// Returns the subject of the ID token and of the userinfo endpoint for the client
+	getSubjects := func(t *testing.T, client model.OidcClient, clientSecret string, callbackURL string) (idTokenSub string, userInfoSub any, tokens CreatedTokens) {
+		t.Helper()
+		response, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    client.ID,
+			Scope:       "openid email offline_access",
+			CallbackURL: callbackURL,
+		}, user.ID, "", "")
+		require.NoError(t, err)
+
+		tokens, err = s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		idTokenSub, _ = idToken.Subject()
+
+		userInfo, err := s.GetUserClaimsForClient(t.Context(), user.ID, client.ID)
+		require.NoError(t, err)
+		return idTokenSub, userInfo["sub"], tokens
+	}
+
+	publicClient, publicSecret := createClient(t, "", "https://public.example.com/callback")
+	pairwiseClient, pairwiseSecret := createClient(t, model.OidcSubjectTypePairwise, "https://app.example.com/callback")
+	sameSectorClient, sameSectorSecret := createClient(t, model.OidcSubjectTypePairwise, "https://app.example.com/other-callback")
+	otherSectorClient, otherSectorSecret := createClient(t, model.OidcSubjectTypePairwise, "https://other.example.com/callback")
+
+	t.Run("Public clients receive the user ID", func(t *testing.T) {
+		assert.Equal(t, model.OidcSubjectTypePublic, publicClient.SubjectType)
+
+		idTokenSub, userInfoSub, _ := getSubjects(t, publicClient, publicSecret, "https://public.example.com/callback")
+		assert.Equal(t, user.ID, idTokenSub)
+		assert.Equal(t, user.ID, userInfoSub)
+	})
+
+	t.Run("Pairwise clients receive a subject that depends on their sector", func(t *testing.T) {
+		idTokenSub, userInfoSub, tokens := getSubjects(t, pairwiseClient, pairwiseSecret, "https://app.example.com/callback")
+		assert.NotEqual(t, user.ID, idTokenSub)
+		assert.Equal(t, idTokenSub, userInfoSub)
+
+		sameSectorSub, _, _ := getSubjects(t, sameSectorClient, sameSectorSecret, "https://app.example.com/other-callback")
+		assert.Equal(t, idTokenSub, sameSectorSub)
+
+		otherSectorSub, _, _ := getSubjects(t, otherSectorClient, otherSectorSecret, "https://other.example.com/callback")
+		assert.NotEqual(t, idTokenSub, otherSectorSub)
+
+		// Refreshed ID tokens and introspection use the same subject
+		refreshed, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: tokens.RefreshToken,
+			ClientID:     pairwiseClient.ID,
+			ClientSecret: pairwiseSecret,
+		})
+		require.NoError(t, err)
+		idToken, err := s.jwtService.VerifyIdToken(refreshed.IdToken, false)
+		require.NoError(t, err)
+		refreshedSub, _ := idToken.Subject()
+		assert.Equal(t, idTokenSub, refreshedSub)
+
+		creds := ClientAuthCredentials{ClientID: pairwiseClient.ID, ClientSecret: pairwiseSecret}
+		introspection, err := s.IntrospectToken(t.Context(), creds, refreshed.AccessToken)
+		require.NoError(t, err)
+		assert.True(t, introspection.Active)
+		assert.Equal(t, idTokenSub, introspection.Subject)
+
+		introspection, err = s.IntrospectToken(t.Context(), creds, refreshed.RefreshToken)
+		require.NoError(t, err)
+		assert.True(t, introspection.Active)
+		assert.Equal(t, idTokenSub, introspection.Subject)
+	})
+
+	t.Run("Access and refresh tokens of pairwise clients contain the pairwise subject", func(t *testing.T) {
+		idTokenSub, _, tokens := getSubjects(t, pairwiseClient, pairwiseSecret, "https://app.example.com/callback")
+
+		accessToken, err := s.jwtService.VerifyOAuthAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+		accessTokenSub, _ := accessToken.Subject()
+		assert.Equal(t, idTokenSub, accessTokenSub)
+
+		refreshTokenSub, _, _, err := s.jwtService.VerifyOAuthRefreshToken(tokens.RefreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, idTokenSub, refreshTokenSub)
+
+		// The subject is mapped back to the user for the userinfo endpoint
+		userInfo, _, err := s.GetUserInfo(t.Context(), accessTokenSub, pairwiseClient.ID)
+		require.NoError(t, err)
+		assert.Equal(t, idTokenSub, userInfo["sub"])
+		assert.Equal(t, user.Email, userInfo["email"])
+
+		// And for revocation of the refresh token
+		creds := ClientAuthCredentials{ClientID: pairwiseClient.ID, ClientSecret: pairwiseSecret}
+		err = s.RevokeToken(t.Context(), creds, tokens.RefreshToken)
+		require.NoError(t, err)
+
+		_, err = s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeRefreshToken,
+			RefreshToken: tokens.RefreshToken,
+			ClientID:     pairwiseClient.ID,
+			ClientSecret: pairwiseSecret,
+		})
+		var refreshErr *common.OidcInvalidRefreshTokenError
+		require.ErrorAs(t, err, &refreshErr)
+	})
+
+	t.Run("Rejects callback URLs of different hosts without a sector identifier URI", func(t *testing.T) {
+		_, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:         "Pairwise Client",
+				CallbackURLs: []string{"https://app.example.com/callback", "https://other.example.com/callback"},
+				SubjectType:  model.OidcSubjectTypePairwise,
+			},
+		}, user.ID)
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("Rejects sector identifier URIs that don't use https", func(t *testing.T) {
+		_, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                "Pairwise Client",
+				CallbackURLs:        []string{"https://app.example.com/callback"},
+				SubjectType:         model.OidcSubjectTypePairwise,
+				SectorIdentifierURI: utils.Ptr("http://example.com/redirect_uris.json"),
+			},
+		}, user.ID)
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+}
+
+func TestOidcService_TokenEncryption(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:                 db,
+		jwtService:         mockJwtService,
+		appConfigService:   mockConfig,
+		auditLogService:    &AuditLogService{db: db},
+		webAuthnService:    &WebAuthnService{db: db},
+		customClaimService: NewCustomClaimService(db),
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	// Create the encryption key of the client and publish it inline
+	rawClientKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	clientKey, err := jwk.Import(rawClientKey)
+	require.NoError(t, err)
+	clientPublicKey, err := clientKey.PublicKey()
+	require.NoError(t, err)
+	require.NoError(t, clientPublicKey.Set(jwk.KeyUsageKey, jwk.ForEncryption))
+	jwks := jwk.NewSet()
+	require.NoError(t, jwks.AddKey(clientPublicKey))
+	jwksJSON, err := json.Marshal(jwks)
+	require.NoError(t, err)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:                         "Encryption Client",
+			CallbackURLs:                 []string{"https://example.com/callback"},
+			Jwks:                         utils.Ptr(string(jwksJSON)),
+			IdTokenEncryptedResponseAlg:  utils.Ptr("RSA-OAEP-256"),
+			UserinfoEncryptedResponseAlg: utils.Ptr("RSA-OAEP"),
+			UserinfoEncryptedResponseEnc: utils.Ptr("A256GCM"),
+		},
+	}, user.ID)
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	t.Run("Encrypts ID tokens and userinfo responses", func(t *testing.T) {
+		response, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    client.ID,
+			Scope:       "openid email",
+			CallbackURL: "https://example.com/callback",
+		}, user.ID, "", "")
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         response.Code,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		msg, err := jwe.Parse([]byte(tokens.IdToken))
+		require.NoError(t, err)
+		enc, _ := msg.ProtectedHeaders().ContentEncryption()
+		assert.Equal(t, jwa.A128CBC_HS256(), enc)
+
+		decrypted, err := jwe.Decrypt([]byte(tokens.IdToken), jwe.WithKey(jwa.RSA_OAEP_256(), clientKey))
+		require.NoError(t, err)
+		idToken, err := s.jwtService.VerifyIdToken(string(decrypted), false)
+		require.NoError(t, err)
+		subject, _ := idToken.Subject()
+		assert.Equal(t, user.ID, subject)
+
+		// Without a signing algorithm, the userinfo response contains the claims as plain JSON
+		_, userInfoToken, err := s.GetUserInfo(t.Context(), user.ID, client.ID)
+		require.NoError(t, err)
+		msg, err = jwe.Parse([]byte(userInfoToken))
+		require.NoError(t, err)
+		_, ok := msg.ProtectedHeaders().ContentType()
+		assert.False(t, ok, "The 'cty' header should not be set for plain claims")
+		decrypted, err = jwe.Decrypt([]byte(userInfoToken), jwe.WithKey(jwa.RSA_OAEP(), clientKey))
+		require.NoError(t, err)
+		var userInfo map[string]any
+		require.NoError(t, json.Unmarshal(decrypted, &userInfo))
+		assert.Equal(t, "test@example.com", userInfo["email"])
+	})
+
+	t.Run("Signs encrypted userinfo responses if the client registered a signing algorithm", func(t *testing.T) {
+		signingClient, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                         "Signed Encryption Client",
+				CallbackURLs:                 []string{"https://example.com/callback"},
+				Jwks:                         utils.Ptr(string(jwksJSON)),
+				UserinfoSignedResponseAlg:    utils.Ptr("RS256"),
+				UserinfoEncryptedResponseAlg: utils.Ptr("RSA-OAEP"),
+			},
+		}, user.ID)
+		require.NoError(t, err)
+		_, err = s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    signingClient.ID,
+			Scope:       "openid email",
+			CallbackURL: "https://example.com/callback",
+		}, user.ID, "", "")
+		require.NoError(t, err)
+
+		_, userInfoToken, err := s.GetUserInfo(t.Context(), user.ID, signingClient.ID)
+		require.NoError(t, err)
+		decrypted, err := jwe.Decrypt([]byte(userInfoToken), jwe.WithKey(jwa.RSA_OAEP(), clientKey))
+		require.NoError(t, err)
+		publicKey, err := mockJwtService.GetPublicJWK()
+		require.NoError(t, err)
+		alg, err := mockJwtService.GetKeyAlg()
+		require.NoError(t, err)
+		userInfo, err := jwt.Parse(decrypted, jwt.WithKey(alg, publicKey), jwt.WithAudience(signingClient.ID))
+		require.NoError(t, err)
+		var email string
+		require.NoError(t, userInfo.Get("email", &email))
+		assert.Equal(t, "test@example.com", email)
+	})
+
+	t.Run("Requires keys to encrypt tokens", func(t *testing.T) {
+		_, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                        "Encryption Client",
+				IdTokenEncryptedResponseAlg: utils.Ptr("RSA-OAEP-256"),
+			},
+		}, user.ID)
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+}
+
+func TestOidcService_SignedUserInfo(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:                 db,
+		jwtService:         mockJwtService,
+		appConfigService:   mockConfig,
+		auditLogService:    &AuditLogService{db: db},
+		webAuthnService:    &WebAuthnService{db: db},
+		customClaimService: NewCustomClaimService(db),
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	alg, err := mockJwtService.GetKeyAlg()
+	require.NoError(t, err)
+	publicKey, err := mockJwtService.GetPublicJWK()
+	require.NoError(t, err)
+
+	createClient := func(userinfoSignedResponseAlg *string) (model.OidcClient, error) {
+		return s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:                      "Signed Userinfo Client",
+				CallbackURLs:              []string{"https://example.com/callback"},
+				UserinfoSignedResponseAlg: userinfoSignedResponseAlg,
+			},
+		}, user.ID)
+	}
+
+	authorize := func(t *testing.T, clientID string) {
+		t.Helper()
+		_, err := s.Authorize(t.Context(), dto.AuthorizeOidcClientRequestDto{
+			ClientID:    clientID,
+			Scope:       "openid email",
+			CallbackURL: "https://example.com/callback",
+		}, user.ID, "", "")
+		require.NoError(t, err)
+	}
+
+	t.Run("Returns a signed JWT to clients that registered a signing algorithm", func(t *testing.T) {
+		client, err := createClient(utils.Ptr(alg.String()))
+		require.NoError(t, err)
+		authorize(t, client.ID)
+
+		claims, userInfoToken, err := s.GetUserInfo(t.Context(), user.ID, client.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, userInfoToken)
+
+		token, err := jwt.Parse([]byte(userInfoToken),
+			jwt.WithKey(alg, publicKey),
+			jwt.WithIssuer(common.EnvConfig.AppURL),
+			jwt.WithAudience(client.ID),
+		)
+		require.NoError(t, err)
+		subject, _ := token.Subject()
+		assert.Equal(t, user.ID, subject)
+		var email string
+		require.NoError(t, token.Get("email", &email))
+		assert.Equal(t, *user.Email, email)
+		assert.Contains(t, claims, "email")
+	})
+
+	t.Run("Returns plain claims to other clients", func(t *testing.T) {
+		client, err := createClient(nil)
+		require.NoError(t, err)
+		authorize(t, client.ID)
+
+		claims, userInfoToken, err := s.GetUserInfo(t.Context(), user.ID, client.ID)
+		require.NoError(t, err)
+		assert.Empty(t, userInfoToken)
+		assert.Equal(t, user.ID, claims["sub"])
+	})
+
+	t.Run("Rejects algorithms of other keys", func(t *testing.T) {
+		otherAlg := "PS512"
+		if alg.String() == otherAlg {
+			otherAlg = "ES512"
+		}
+
+		_, err := createClient(&otherAlg)
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+}
+
+func TestOidcService_BackchannelAuthentication(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+		webAuthnService:  &WebAuthnService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:                             "CIBA Client",
+			CallbackURLs:                     []string{"https://example.com/callback"},
+			BackchannelAuthenticationEnabled: true,
+		},
+	}, user.ID)
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	startAuthentication := func(t *testing.T, loginHint string) *dto.OidcBackchannelAuthenticationResponseDto {
+		t.Helper()
+
+		response, err := s.CreateBackchannelAuthentication(t.Context(), dto.OidcBackchannelAuthenticationRequestDto{
+			ClientID:       client.ID,
+			ClientSecret:   clientSecret,
+			Scope:          "openid email",
+			LoginHint:      loginHint,
+			BindingMessage: "Checkout 42",
+		})
+		require.NoError(t, err)
+		return response
+	}
+
+	pollTokens := func(authReqID string) (CreatedTokens, error) {
+		return s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeCIBA,
+			AuthReqID:    authReqID,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+	}
+
+	// pendingRequestID returns the ID of the pending request the user sees in their session
+	pendingRequestID := func(t *testing.T) string {
+		t.Helper()
+
+		authRequests, err := s.ListPendingBackchannelAuthentications(t.Context(), user.ID)
+		require.NoError(t, err)
+		require.Len(t, authRequests, 1)
+		assert.Equal(t, "Checkout 42", authRequests[0].BindingMessage)
+		assert.Equal(t, client.Name, authRequests[0].Client.Name)
+		return authRequests[0].ID
+	}
+
+	t.Run("issues tokens once the user approves the request", func(t *testing.T) {
+		response := startAuthentication(t, "testuser")
+		assert.NotEmpty(t, response.AuthReqID)
+		assert.Equal(t, int(BackchannelAuthenticationRequestDuration.Seconds()), response.ExpiresIn)
+		assert.Equal(t, 5, response.Interval)
+
+		_, err := pollTokens(response.AuthReqID)
+		require.ErrorIs(t, err, &common.OidcAuthorizationPendingError{})
+
+		authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+		err = s.ApproveBackchannelAuthentication(t.Context(), pendingRequestID(t), user.ID, "", AuthenticationInfo{Time: authTime, Methods: []string{"hwk"}}, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+
+		tokens, err := pollTokens(response.AuthReqID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		subject, _ := idToken.Subject()
+		assert.Equal(t, user.ID, subject)
+		var claim float64
+		require.NoError(t, idToken.Get(AuthTimeClaim, &claim))
+		assert.Equal(t, authTime.Unix(), int64(claim))
+
+		// The approval is recorded in the audit log
+		var auditLog model.AuditLog
+		require.NoError(t, db.First(&auditLog, "event = ?", model.AuditLogEventNewBackchannelAuthorization).Error)
+		assert.Equal(t, user.ID, auditLog.UserID)
+		assert.Equal(t, "Checkout 42", auditLog.Data["bindingMessage"])
+
+		// The request can only be used once
+		_, err = pollTokens(response.AuthReqID)
+		require.ErrorIs(t, err, &common.OidcInvalidAuthReqIDError{})
+	})
+
+	t.Run("returns access_denied when the user denies the request", func(t *testing.T) {
+		response := startAuthentication(t, "test@example.com")
+
+		err := s.DenyBackchannelAuthentication(t.Context(), pendingRequestID(t), user.ID)
+		require.NoError(t, err)
+
+		_, err = pollTokens(response.AuthReqID)
+		require.ErrorIs(t, err, &common.OidcBackchannelAuthenticationDeniedError{})
+
+		// The request can't be approved anymore
+		authRequests, err := s.ListPendingBackchannelAuthentications(t.Context(), user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, authRequests)
+	})
+
+	t.Run("returns expired_token when the request has expired", func(t *testing.T) {
+		response := startAuthentication(t, "testuser")
+
+		err := db.
+			Model(&model.OidcBackchannelAuthenticationRequest{}).
+			Where("auth_req_id = ?", response.AuthReqID).
+			Update("expires_at", datatype.DateTime(time.Now().Add(-time.Minute))).
+			Error
+		require.NoError(t, err)
+
+		_, err = pollTokens(response.AuthReqID)
+		require.ErrorIs(t, err, &common.OidcAuthReqIDExpiredError{})
+	})
+
+	t.Run("fails for unknown users and without the openid scope", func(t *testing.T) {
+		_, err := s.CreateBackchannelAuthentication(t.Context(), dto.OidcBackchannelAuthenticationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "openid",
+			LoginHint:    "unknown",
+		})
+		require.ErrorIs(t, err, &common.OidcUnknownUserError{})
+
+		_, err = s.CreateBackchannelAuthentication(t.Context(), dto.OidcBackchannelAuthenticationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "email",
+			LoginHint:    "testuser",
+		})
+		var validationErr *common.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("requires client authentication", func(t *testing.T) {
+		_, err := s.CreateBackchannelAuthentication(t.Context(), dto.OidcBackchannelAuthenticationRequestDto{
+			ClientID:  client.ID,
+			Scope:     "openid",
+			LoginHint: "testuser",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("requires the client to opt in", func(t *testing.T) {
+		otherClient, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+			OidcClientUpdateDto: dto.OidcClientUpdateDto{
+				Name:         "Other Client",
+				CallbackURLs: []string{"https://example.com/callback"},
+			},
+		}, user.ID)
+		require.NoError(t, err)
+		otherClientSecret, err := s.CreateClientSecret(t.Context(), otherClient.ID)
+		require.NoError(t, err)
+
+		_, err = s.CreateBackchannelAuthentication(t.Context(), dto.OidcBackchannelAuthenticationRequestDto{
+			ClientID:     otherClient.ID,
+			ClientSecret: otherClientSecret,
+			Scope:        "openid",
+			LoginHint:    "testuser",
+		})
+		require.ErrorIs(t, err, &common.OidcBackchannelAuthenticationNotAllowedError{})
+	})
+
+	t.Run("requires the minimum authentication context class of the client on approval", func(t *testing.T) {
+		err := db.Model(&model.OidcClient{}).Where("id = ?", client.ID).Update("minimum_acr", AcrPasskey).Error
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Model(&model.OidcClient{}).Where("id = ?", client.ID).Update("minimum_acr", nil)
+		})
+
+		startAuthentication(t, "testuser")
+		authRequestID := pendingRequestID(t)
+
+		err = s.ApproveBackchannelAuthentication(t.Context(), authRequestID, user.ID, "", AuthenticationInfo{Time: time.Now(), Methods: AuthenticationMethodsOneTimeCode}, "127.0.0.1", "test-agent")
+		require.ErrorIs(t, err, &common.OidcAcrNotSatisfiedError{})
+
+		err = s.ApproveBackchannelAuthentication(t.Context(), authRequestID, user.ID, "", AuthenticationInfo{Time: time.Now(), Methods: AuthenticationMethodsPasskey}, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+	})
+
+	t.Run("keeps the claims the user consented to on approval", func(t *testing.T) {
+		consentedClaims := &model.OidcClaimsRequest{
+			Userinfo: map[string]*model.OidcClaimRequest{"preferred_username": nil},
+		}
+		err := db.Model(&model.UserAuthorizedOidcClient{}).
+			Where("user_id = ? AND client_id = ?", user.ID, client.ID).
+			Update("claims", consentedClaims).
+			Error
+		require.NoError(t, err)
+
+		startAuthentication(t, "testuser")
+		err = s.ApproveBackchannelAuthentication(t.Context(), pendingRequestID(t), user.ID, "", AuthenticationInfo{Time: time.Now(), Methods: AuthenticationMethodsPasskey}, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+
+		var authorizedClient model.UserAuthorizedOidcClient
+		err = db.First(&authorizedClient, "user_id = ? AND client_id = ?", user.ID, client.ID).Error
+		require.NoError(t, err)
+		assert.Equal(t, []string{"preferred_username"}, authorizedClient.Claims.ClaimNames())
+	})
+}
+
+func TestOidcService_DeviceCode(t *testing.T) {
+	db := testutils.NewDatabaseForTest(t)
+
+	mockConfig := NewTestAppConfigService(&model.AppConfig{
+		SessionDuration: model.AppConfigVariable{Value: "60"}, // 60 minutes
+	})
+	mockJwtService, err := NewJwtService(db, mockConfig)
+	require.NoError(t, err)
+
+	s := &OidcService{
+		db:               db,
+		jwtService:       mockJwtService,
+		appConfigService: mockConfig,
+		auditLogService:  &AuditLogService{db: db},
+	}
+
+	user := model.User{
+		Base:     model.Base{ID: "test-user-id"},
+		Username: "testuser",
+		Email:    utils.Ptr("test@example.com"),
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	minimumAcr := AcrPasskey
+	client, err := s.CreateClient(t.Context(), dto.OidcClientCreateDto{
+		OidcClientUpdateDto: dto.OidcClientUpdateDto{
+			Name:         "Device Client",
+			CallbackURLs: []string{"https://example.com/callback"},
+			MinimumAcr:   &minimumAcr,
+		},
+	}, user.ID)
+	require.NoError(t, err)
+	clientSecret, err := s.CreateClientSecret(t.Context(), client.ID)
+	require.NoError(t, err)
+
+	t.Run("requires the minimum authentication context class of the client and keeps the authentication in the tokens", func(t *testing.T) {
+		deviceAuth, err := s.CreateDeviceAuthorization(t.Context(), dto.OidcDeviceAuthorizationRequestDto{
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+			Scope:        "openid",
+		})
+		require.NoError(t, err)
+
+		err = s.VerifyDeviceCode(t.Context(), deviceAuth.UserCode, user.ID, AuthenticationInfo{Time: time.Now(), Methods: AuthenticationMethodsOneTimeCode}, "127.0.0.1", "test-agent")
+		require.ErrorIs(t, err, &common.OidcAcrNotSatisfiedError{})
+
+		authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+		err = s.VerifyDeviceCode(t.Context(), deviceAuth.UserCode, user.ID, AuthenticationInfo{Time: authTime, Methods: AuthenticationMethodsPasskey}, "127.0.0.1", "test-agent")
+		require.NoError(t, err)
+
+		tokens, err := s.CreateTokens(t.Context(), dto.OidcCreateTokensDto{
+			GrantType:    GrantTypeDeviceCode,
+			DeviceCode:   deviceAuth.DeviceCode,
+			ClientID:     client.ID,
+			ClientSecret: clientSecret,
+		})
+		require.NoError(t, err)
+
+		idToken, err := s.jwtService.VerifyIdToken(tokens.IdToken, false)
+		require.NoError(t, err)
+		var claim float64
+		require.NoError(t, idToken.Get(AuthTimeClaim, &claim))
+		assert.Equal(t, authTime.Unix(), int64(claim))
+		var acr string
+		require.NoError(t, idToken.Get("acr", &acr))
+		assert.Equal(t, AcrPasskey, acr)
+	})
+}
@@ -33,9 +33,10 @@ type UserService struct {
 	emailService       *EmailService
 	appConfigService   *AppConfigService
 	customClaimService *CustomClaimService
+	oidcService        *OidcService
 }
 
-func NewUserService(db *gorm.DB, jwtService *JwtService, auditLogService *AuditLogService, emailService *EmailService, appConfigService *AppConfigService, customClaimService *CustomClaimService) *UserService {
+func NewUserService(db *gorm.DB, jwtService *JwtService, auditLogService *AuditLogService, emailService *EmailService, appConfigService *AppConfigService, customClaimService *CustomClaimService, oidcService *OidcService) *UserService {
 	return &UserService{
 		db:                 db,
 		jwtService:         jwtService,
@@ -43,6 +44,7 @@ func NewUserService(db *gorm.DB, jwtService *JwtService, auditLogService *AuditL
 		emailService:       emailService,
 		appConfigService:   appConfigService,
 		customClaimService: customClaimService,
+		oidcService:        oidcService,
 	}
 }
 
@@ -187,9 +189,23 @@ func (s *UserService) UpdateProfilePicture(userID string, file io.Reader) error
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, userID string, allowLdapDelete bool) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	var logoutClients []model.OidcClient
+	err := s.db.Transaction(func(tx *gorm.DB) (err error) {
+		// The authorized clients are deleted together with the user, so we need to load them first
+		logoutClients, err = s.oidcService.ListBackchannelLogoutClients(ctx, userID, tx)
+		if err != nil {
+			return err
+		}
+
 		return s.deleteUserInternal(ctx, userID, allowLdapDelete, tx)
 	})
+	if err != nil {
+		return err
+	}
+
+	// The user doesn't exist anymore, so no audit log entries can be created for them
+	s.oidcService.SendBackchannelLogoutToClients(ctx, userID, logoutClients, "", "", false)
+	return nil
 }
 
 func (s *UserService) deleteUserInternal(ctx context.Context, userID string, allowLdapDelete bool, tx *gorm.DB) error {
@@ -329,6 +345,18 @@ func (s *UserService) UpdateUser(ctx context.Context, userID string, updatedUser
 		tx.Rollback()
 	}()
 
+	var wasDisabled bool
+	err := tx.
+		WithContext(ctx).
+		Model(&model.User{}).
+		Select("disabled").
+		Where("id = ?", userID).
+		Scan(&wasDisabled).
+		Error
+	if err != nil {
+		return model.User{}, err
+	}
+
 	user, err := s.updateUserInternal(ctx, userID, updatedUser, updateOwnUser, isLdapSync, tx)
 	if err != nil {
 		return model.User{}, err
@@ -339,6 +367,14 @@ func (s *UserService) UpdateUser(ctx context.Context, userID string, updatedUser
 		return model.User{}, err
 	}
 
+	// Log the user out of all clients if they have just been disabled
+	if user.Disabled && !wasDisabled {
+		err = s.oidcService.SendBackchannelLogout(ctx, user.ID, "", "")
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to send back-channel logout", slog.String("user", user.ID), slog.Any("error", err))
+		}
+	}
+
 	return user, nil
 }
 
@@ -528,7 +564,7 @@ func (s *UserService) ExchangeOneTimeAccessToken(ctx context.Context, token stri
 		}
 		return model.User{}, "", err
 	}
-	accessToken, err := s.jwtService.GenerateAccessToken(oneTimeAccessToken.User)
+	accessToken, err := s.jwtService.GenerateAccessToken(oneTimeAccessToken.User, AuthenticationMethodsOneTimeCode)
 	if err != nil {
 		return model.User{}, "", err
 	}
@@ -627,7 +663,8 @@ func (s *UserService) SignUpInitialAdmin(ctx context.Context, signUpData dto.Sig
 		return model.User{}, "", err
 	}
 
-	token, err := s.jwtService.GenerateAccessToken(user)
+	// The user has just signed up and didn't authenticate with any method yet
+	token, err := s.jwtService.GenerateAccessToken(user, nil)
 	if err != nil {
 		return model.User{}, "", err
 	}
@@ -763,7 +800,8 @@ func (s *UserService) SignUp(ctx context.Context, signupData dto.SignUpDto, ipAd
 		return model.User{}, "", err
 	}
 
-	accessToken, err := s.jwtService.GenerateAccessToken(user)
+	// The user has just signed up and didn't authenticate with any method yet
+	accessToken, err := s.jwtService.GenerateAccessToken(user, nil)
 	if err != nil {
 		return model.User{}, "", err
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-webauthn/webauthn/protocol"
@@ -258,7 +259,7 @@ func (s *WebAuthnService) VerifyLogin(ctx context.Context, sessionID string, cre
 		return model.User{}, "", &common.UserDisabledError{}
 	}
 
-	token, err := s.jwtService.GenerateAccessToken(*user)
+	token, err := s.jwtService.GenerateAccessToken(*user, AuthenticationMethodsPasskey)
 	if err != nil {
 		return model.User{}, "", err
 	}
@@ -368,7 +369,7 @@ func (s *WebAuthnService) CreateReauthenticationTokenWithAccessToken(ctx context
 		return "", fmt.Errorf("failed to load user: %w", err)
 	}
 
-	reauthToken, err := s.createReauthenticationToken(ctx, tx, user.ID)
+	reauthToken, err := s.createReauthenticationToken(ctx, tx, user.ID, GetAuthenticationMethods(token))
 	if err != nil {
 		return "", err
 	}
@@ -422,7 +423,7 @@ func (s *WebAuthnService) CreateReauthenticationTokenWithWebauthn(ctx context.Co
 	}
 
 	// Create reauthentication token
-	token, err := s.createReauthenticationToken(ctx, tx, user.ID)
+	token, err := s.createReauthenticationToken(ctx, tx, user.ID, AuthenticationMethodsPasskey)
 	if err != nil {
 		return "", err
 	}
@@ -435,31 +436,34 @@ func (s *WebAuthnService) CreateReauthenticationTokenWithWebauthn(ctx context.Co
 	return token, nil
 }
 
-func (s *WebAuthnService) ConsumeReauthenticationToken(ctx context.Context, tx *gorm.DB, token string, userID string) error {
+// ConsumeReauthenticationToken deletes the reauthentication token and returns the methods the user reauthenticated with
+func (s *WebAuthnService) ConsumeReauthenticationToken(ctx context.Context, tx *gorm.DB, token string, userID string) ([]string, error) {
 	hashedToken := utils.CreateSha256Hash(token)
+	var reauthToken model.ReauthenticationToken
 	result := tx.WithContext(ctx).
 		Clauses(clause.Returning{}).
-		Delete(&model.ReauthenticationToken{}, "token = ? AND user_id = ? AND expires_at > ?", hashedToken, userID, datatype.DateTime(time.Now()))
+		Delete(&reauthToken, "token = ? AND user_id = ? AND expires_at > ?", hashedToken, userID, datatype.DateTime(time.Now()))
 
 	if result.Error != nil {
-		return result.Error
+		return nil, result.Error
 	}
 	if result.RowsAffected == 0 {
-		return &common.ReauthenticationRequiredError{}
+		return nil, &common.ReauthenticationRequiredError{}
 	}
-	return nil
+	return strings.Fields(reauthToken.AuthenticationMethods), nil
 }
 
-func (s *WebAuthnService) createReauthenticationToken(ctx context.Context, tx *gorm.DB, userID string) (string, error) {
+func (s *WebAuthnService) createReauthenticationToken(ctx context.Context, tx *gorm.DB, userID string, authenticationMethods []string) (string, error) {
 	token, err := utils.GenerateRandomAlphanumericString(32)
 	if err != nil {
 		return "", err
 	}
 
 	reauthToken := model.ReauthenticationToken{
-		Token:     utils.CreateSha256Hash(token),
-		ExpiresAt: datatype.DateTime(time.Now().Add(3 * time.Minute)),
-		UserID:    userID,
+		Token:                 utils.CreateSha256Hash(token),
+		ExpiresAt:             datatype.DateTime(time.Now().Add(3 * time.Minute)),
+		AuthenticationMethods: strings.Join(authenticationMethods, " "),
+		UserID:                userID,
 	}
 
 	err = tx.WithContext(ctx).Create(&reauthToken).Error
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+)
+
+// ClientCertificate returns the TLS client certificate of the request, or nil if the client didn't present one.
+// If Pocket ID is behind a trusted proxy that terminates TLS, the certificate is read from the header configured with CLIENT_CERT_HEADER.
+// chainVerified is true if the certificate chain was verified against the CAs configured with CLIENT_CA_CERTS, or by the trusted proxy.
+func ClientCertificate(r *http.Request) (cert *x509.Certificate, chainVerified bool, err error) {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates[0], verifyClientCertificateChain(r.TLS.PeerCertificates), nil
+	}
+
+	if !common.EnvConfig.TrustProxy || common.EnvConfig.ClientCertHeader == "" {
+		return nil, false, nil
+	}
+
+	value := r.Header.Get(common.EnvConfig.ClientCertHeader)
+	if value == "" {
+		return nil, false, nil
+	}
+
+	cert, err = ParseClientCertificateHeader(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The trusted proxy only forwards certificates that it verified
+	return cert, true, nil
+}
+
+// verifyClientCertificateChain verifies the certificates presented by the client against the CAs configured with CLIENT_CA_CERTS
+func verifyClientCertificateChain(chain []*x509.Certificate) bool {
+	if common.EnvConfig.ClientCACerts == "" {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(common.EnvConfig.ClientCACerts)) {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+// ParseClientCertificateHeader parses a client certificate forwarded by a proxy.
+// Both URL-encoded PEM (as sent by nginx) and base64-encoded DER (as sent by Traefik) are supported.
+func ParseClientCertificateHeader(value string) (*x509.Certificate, error) {
+	unescaped, err := url.PathUnescape(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unescape certificate: %w", err)
+	}
+
+	var der []byte
+	if strings.HasPrefix(unescaped, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(unescaped))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil, errors.New("failed to decode PEM certificate")
+		}
+		der = block.Bytes
+	} else {
+		// Proxies may forward the whole chain, separated by commas: the client certificate is the first one
+		first, _, _ := strings.Cut(unescaped, ",")
+		der, err = base64.StdEncoding.DecodeString(first)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 certificate: %w", err)
+		}
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// CertificateThumbprint returns the base64url-encoded SHA-256 thumbprint of the certificate, as used in the "x5t#S256" confirmation method
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientCertificateHeader(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test-client"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	tests := []struct {
+		name        string
+		value       string
+		expectError bool
+	}{
+		{
+			name:  "URL-encoded PEM",
+			value: url.PathEscape(string(pemCert)),
+		},
+		{
+			name:  "Base64-encoded DER",
+			value: base64.StdEncoding.EncodeToString(der),
+		},
+		{
+			name:  "Base64-encoded DER chain",
+			value: base64.StdEncoding.EncodeToString(der) + "," + base64.StdEncoding.EncodeToString(der),
+		},
+		{
+			name:        "Invalid value",
+			value:       "not-a-certificate",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := ParseClientCertificateHeader(tt.value)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "test-client", cert.Subject.CommonName)
+			assert.Len(t, CertificateThumbprint(cert), 43)
+		})
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	originalConfig := common.EnvConfig
+	t.Cleanup(func() { common.EnvConfig = originalConfig })
+
+	createCertificate := func(t *testing.T, commonName string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+		t.Helper()
+
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: commonName},
+			NotBefore:             time.Now().Add(-time.Minute),
+			NotAfter:              time.Now().Add(time.Hour),
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			IsCA:                  isCA,
+			BasicConstraintsValid: true,
+		}
+		if isCA {
+			template.KeyUsage = x509.KeyUsageCertSign
+		}
+		if parent == nil {
+			parent, parentKey = template, privateKey
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &privateKey.PublicKey, parentKey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+
+		return cert, privateKey
+	}
+
+	caCert, caKey := createCertificate(t, "test-ca", true, nil, nil)
+	signedCert, _ := createCertificate(t, "test-client", false, caCert, caKey)
+	selfSignedCert, _ := createCertificate(t, "test-client", false, nil, nil)
+
+	common.EnvConfig.ClientCACerts = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw}))
+
+	requestWithTLS := func(cert *x509.Certificate) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/oidc/token", nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		return r
+	}
+
+	t.Run("Verifies the chain of a certificate issued by a client CA", func(t *testing.T) {
+		cert, chainVerified, err := ClientCertificate(requestWithTLS(signedCert))
+		require.NoError(t, err)
+		assert.Equal(t, signedCert, cert)
+		assert.True(t, chainVerified)
+	})
+
+	t.Run("Doesn't verify the chain of a self-signed certificate", func(t *testing.T) {
+		cert, chainVerified, err := ClientCertificate(requestWithTLS(selfSignedCert))
+		require.NoError(t, err)
+		assert.Equal(t, selfSignedCert, cert)
+		assert.False(t, chainVerified)
+	})
+
+	t.Run("Doesn't verify the chain without client CAs", func(t *testing.T) {
+		common.EnvConfig.ClientCACerts = ""
+		t.Cleanup(func() { common.EnvConfig.ClientCACerts = originalConfig.ClientCACerts })
+
+		_, chainVerified, err := ClientCertificate(requestWithTLS(signedCert))
+		require.NoError(t, err)
+		assert.False(t, chainVerified)
+	})
+
+	t.Run("Trusts certificates forwarded by the trusted proxy", func(t *testing.T) {
+		common.EnvConfig.TrustProxy = true
+		common.EnvConfig.ClientCertHeader = "X-Client-Cert"
+
+		r := httptest.NewRequest(http.MethodPost, "/api/oidc/token", nil)
+		r.Header.Set("X-Client-Cert", base64.StdEncoding.EncodeToString(selfSignedCert.Raw))
+
+		cert, chainVerified, err := ClientCertificate(r)
+		require.NoError(t, err)
+		assert.Equal(t, selfSignedCert.Raw, cert.Raw)
+		assert.True(t, chainVerified)
+	})
+
+	t.Run("Ignores the header without a trusted proxy", func(t *testing.T) {
+		common.EnvConfig.TrustProxy = false
+		common.EnvConfig.ClientCertHeader = "X-Client-Cert"
+
+		r := httptest.NewRequest(http.MethodPost, "/api/oidc/token", nil)
+		r.Header.Set("X-Client-Cert", base64.StdEncoding.EncodeToString(selfSignedCert.Raw))
+
+		cert, chainVerified, err := ClientCertificate(r)
+		require.NoError(t, err)
+		assert.Nil(t, cert)
+		assert.False(t, chainVerified)
+	})
+}
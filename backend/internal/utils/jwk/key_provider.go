@@ -19,6 +19,10 @@ type KeyProvider interface {
 	Init(opts KeyProviderOpts) error
 	LoadKey() (jwk.Key, error)
 	SaveKey(key jwk.Key) error
+	// LoadKeyRing returns the keys that are kept besides the active one, such as retired keys
+	// If there's no keyring, it returns an empty set
+	LoadKeyRing() (jwk.Set, error)
+	SaveKeyRing(keys jwk.Set) error
 }
 
 func GetKeyProvider(db *gorm.DB, envConfig *common.EnvConfigSchema, instanceID string) (keyProvider KeyProvider, err error) {
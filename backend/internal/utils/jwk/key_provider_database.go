@@ -15,7 +15,10 @@ import (
 	cryptoutils "github.com/pocket-id/pocket-id/backend/internal/utils/crypto"
 )
 
-const PrivateKeyDBKey = "jwt_private_key.json"
+const (
+	PrivateKeyDBKey = "jwt_private_key.json"
+	KeyRingDBKey    = "jwt_keyring.json"
+)
 
 type KeyProviderDatabase struct {
 	db  *gorm.DB
@@ -113,5 +116,78 @@ func (f *KeyProviderDatabase) SaveKey(key jwk.Key) error {
 	return nil
 }
 
+func (f *KeyProviderDatabase) LoadKeyRing() (jwk.Set, error) {
+	row := model.KV{
+		Key: KeyRingDBKey,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := f.db.WithContext(ctx).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (row.Value == nil || *row.Value == "")) {
+		// No keyring exists yet
+		return jwk.NewSet(), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve keyring from the database: %w", err)
+	}
+
+	// Decode from base64
+	enc, err := base64.StdEncoding.DecodeString(*row.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted keyring: not a valid base64-encoded value: %w", err)
+	}
+
+	// Decrypt the data
+	data, err := cryptoutils.Decrypt(f.kek, enc, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keyring: %w", err)
+	}
+
+	// Parse the keys
+	keys, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted keyring: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (f *KeyProviderDatabase) SaveKeyRing(keys jwk.Set) error {
+	// Encode the keys to JSON
+	data, err := EncodeKeySetBytes(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode keyring to JSON: %w", err)
+	}
+
+	// Encrypt the keyring then encode to Base64
+	enc, err := cryptoutils.Encrypt(f.kek, data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt keyring: %w", err)
+	}
+	encB64 := base64.StdEncoding.EncodeToString(enc)
+
+	// Save to database
+	row := model.KV{
+		Key:   KeyRingDBKey,
+		Value: &encB64,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = f.db.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		}).
+		Create(&row).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to store keyring in database: %w", err)
+	}
+
+	return nil
+}
+
 // Compile-time interface check
 var _ KeyProvider = (*KeyProviderDatabase)(nil)
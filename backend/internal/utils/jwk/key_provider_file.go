@@ -21,6 +21,13 @@ const (
 	// PrivateKeyFileEncrypted is the path in the data/keys folder where the encrypted key is stored
 	// This is a encrypted JSON file containing a key encoded as JWK
 	PrivateKeyFileEncrypted = "jwt_private_key.json.enc"
+
+	// KeyRingFile is the path in the data/keys folder where the keyring is stored
+	// This is a JSON file containing the keys that are kept besides the active one, encoded as JWKS
+	KeyRingFile = "jwt_keyring.json"
+
+	// KeyRingFileEncrypted is the path in the data/keys folder where the encrypted keyring is stored
+	KeyRingFileEncrypted = "jwt_keyring.json.enc"
 )
 
 type KeyProviderFile struct {
@@ -49,6 +56,102 @@ func (f *KeyProviderFile) SaveKey(key jwk.Key) error {
 	return f.saveKey(key)
 }
 
+func (f *KeyProviderFile) LoadKeyRing() (jwk.Set, error) {
+	data, err := f.loadKeyRingData()
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		// No keyring exists yet
+		return jwk.NewSet(), nil
+	}
+
+	keys, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keyring file: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (f *KeyProviderFile) SaveKeyRing(keys jwk.Set) error {
+	err := os.MkdirAll(f.envConfig.KeysPath, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create directory '%s' for keyring file: %w", f.envConfig.KeysPath, err)
+	}
+
+	data, err := EncodeKeySetBytes(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode keyring to JSON: %w", err)
+	}
+
+	if len(f.kek) == 0 {
+		keyRingPath := filepath.Join(f.envConfig.KeysPath, KeyRingFile)
+		err = os.WriteFile(keyRingPath, data, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to write keyring file at path '%s': %w", keyRingPath, err)
+		}
+		return nil
+	}
+
+	// Encrypt the keyring then encode to Base64
+	enc, err := cryptoutils.Encrypt(f.kek, data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt keyring: %w", err)
+	}
+	encB64 := make([]byte, base64.StdEncoding.EncodedLen(len(enc)))
+	base64.StdEncoding.Encode(encB64, enc)
+
+	encKeyRingPath := filepath.Join(f.envConfig.KeysPath, KeyRingFileEncrypted)
+	err = os.WriteFile(encKeyRingPath, encB64, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write encrypted keyring file at path '%s': %w", encKeyRingPath, err)
+	}
+
+	// Remove the un-encrypted keyring if present, for example if an encryption key was added after the keyring was created
+	err = os.Remove(filepath.Join(f.envConfig.KeysPath, KeyRingFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove un-encrypted keyring file: %w", err)
+	}
+
+	return nil
+}
+
+// loadKeyRingData returns the JSON-encoded keyring, decrypting it if needed
+// It returns nil if there's no keyring file
+func (f *KeyProviderFile) loadKeyRingData() ([]byte, error) {
+	if len(f.kek) > 0 {
+		encKeyRingPath := filepath.Join(f.envConfig.KeysPath, KeyRingFileEncrypted)
+		encB64, err := os.ReadFile(encKeyRingPath)
+		if err == nil {
+			enc := make([]byte, base64.StdEncoding.DecodedLen(len(encB64)))
+			n, err := base64.StdEncoding.Decode(enc, encB64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read encrypted keyring file at path '%s': not a valid base64-encoded file: %w", encKeyRingPath, err)
+			}
+
+			data, err := cryptoutils.Decrypt(f.kek, enc[:n], nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt keyring file at path '%s': %w", encKeyRingPath, err)
+			}
+			return data, nil
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read encrypted keyring file at path '%s': %w", encKeyRingPath, err)
+		}
+
+		// Fall back to an un-encrypted keyring, which is encrypted the next time the keyring is saved
+	}
+
+	keyRingPath := filepath.Join(f.envConfig.KeysPath, KeyRingFile)
+	data, err := os.ReadFile(keyRingPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read keyring file at path '%s': %w", keyRingPath, err)
+	}
+	return data, nil
+}
+
 func (f *KeyProviderFile) loadKey() (jwk.Key, error) {
 	var key jwk.Key
 
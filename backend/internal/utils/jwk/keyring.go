@@ -0,0 +1,318 @@
+package jwk
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+)
+
+const (
+	// KeyStatusParam is the private JWK parameter that contains the status of a key in the keyring
+	KeyStatusParam = "pocket_id_status"
+
+	// KeyExpiresAtParam is the private JWK parameter that contains the time (in RFC 3339 format) after which a retired key is removed from the keyring
+	KeyExpiresAtParam = "pocket_id_expires_at"
+
+	// KeyActiveFromParam is the private JWK parameter that contains the time (in RFC 3339 format) from which a key is used for signing
+	// For the next key this is in the future, while for the active key it's the time it became active
+	KeyActiveFromParam = "pocket_id_active_from"
+
+	// KeyStatusRetired is the status of keys that have been replaced by a newer key
+	// Retired keys are not used for signing anymore, but they are still published so tokens signed with them can be verified until they expire
+	KeyStatusRetired = "retired"
+
+	// KeyStatusNext is the status of a key that will replace the active key
+	// The next key is published before it becomes active, so clients that cache the JWKS already know it when it's used for signing
+	KeyStatusNext = "next"
+
+	// KeyStatusAdditional is the status of keys used to sign tokens for clients that require a different algorithm than the one of the active key
+	// For each algorithm, the additional key that became active most recently is used for signing
+	KeyStatusAdditional = "additional"
+)
+
+// keyRingParams contains the private parameters that are used to manage keys in the keyring, and which must not be published
+var keyRingParams = []string{KeyStatusParam, KeyExpiresAtParam, KeyActiveFromParam}
+
+// SetNextKey marks a key as the next key, which becomes active at activeFrom
+func SetNextKey(key jwk.Key, activeFrom time.Time) error {
+	err := key.Set(KeyStatusParam, KeyStatusNext)
+	if err != nil {
+		return fmt.Errorf("failed to set key status: %w", err)
+	}
+	return SetKeyActiveFrom(key, activeFrom)
+}
+
+// SetKeyActiveFrom sets the time from which a key is used for signing
+func SetKeyActiveFrom(key jwk.Key, activeFrom time.Time) error {
+	err := key.Set(KeyActiveFromParam, activeFrom.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to set key activation time: %w", err)
+	}
+	return nil
+}
+
+// GetKeyActiveFrom returns the time from which a key is used for signing
+// The returned value is the zero time if it's unknown, for example for keys that were created before this was tracked
+func GetKeyActiveFrom(key jwk.Key) time.Time {
+	return getTimeParam(key, KeyActiveFromParam)
+}
+
+// GetNextKey returns the next key in the keyring, if any
+func GetNextKey(keys jwk.Set) jwk.Key {
+	for i := range keys.Len() {
+		key, ok := keys.Key(i)
+		if ok && GetKeyStatus(key) == KeyStatusNext {
+			return key
+		}
+	}
+	return nil
+}
+
+// GenerateAdditionalKey generates an additional key for the given algorithm, which is used for signing from activeFrom
+func GenerateAdditionalKey(alg string, activeFrom time.Time) (jwk.Key, error) {
+	var crv string
+	if alg == jwa.EdDSA().String() {
+		crv = jwa.Ed25519().String()
+	}
+	key, err := GenerateKey(alg, crv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	err = key.Set(KeyStatusParam, KeyStatusAdditional)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set key status: %w", err)
+	}
+	err = SetKeyActiveFrom(key, activeFrom)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAdditionalKeys returns the additional keys in the keyring for the given algorithm, including the ones that aren't active yet
+func GetAdditionalKeys(keys jwk.Set, alg string) []jwk.Key {
+	var res []jwk.Key
+	for i := range keys.Len() {
+		key, ok := keys.Key(i)
+		if !ok || GetKeyStatus(key) != KeyStatusAdditional {
+			continue
+		}
+		keyAlg, ok := key.Algorithm()
+		if ok && keyAlg.String() == alg {
+			res = append(res, key)
+		}
+	}
+	return res
+}
+
+// CurrentAdditionalKey returns the key among the additional keys for an algorithm that is used for signing at now
+// That's the key that became active most recently, while keys that become active in the future are only published
+func CurrentAdditionalKey(keys []jwk.Key, now time.Time) jwk.Key {
+	var current jwk.Key
+	var currentActiveFrom time.Time
+	for _, key := range keys {
+		activeFrom := GetKeyActiveFrom(key)
+		if activeFrom.After(now) {
+			continue
+		}
+		// Keys are added to the keyring in order, so the newer key wins if they have the same activation time
+		if current == nil || !activeFrom.Before(currentActiveFrom) {
+			current = key
+			currentActiveFrom = activeFrom
+		}
+	}
+	return current
+}
+
+// RetireReplacedAdditionalKeys retires the additional keys that were replaced by a newer key for the same algorithm, so they're kept in the keyring until now+retainFor
+// It returns the keys that were retired
+func RetireReplacedAdditionalKeys(keys jwk.Set, now time.Time, retainFor time.Duration) ([]jwk.Key, error) {
+	var retired []jwk.Key
+	for _, alg := range additionalKeyAlgs(keys) {
+		algKeys := GetAdditionalKeys(keys, alg)
+		current := CurrentAdditionalKey(algKeys, now)
+		for _, key := range algKeys {
+			if key == current || GetKeyActiveFrom(key).After(now) {
+				continue
+			}
+			err := RetireKey(key, now.Add(retainFor))
+			if err != nil {
+				return nil, err
+			}
+			retired = append(retired, key)
+		}
+	}
+	return retired, nil
+}
+
+// RotateAdditionalKeys adds a new additional key for every algorithm that has additional keys in the keyring, which is used for signing from activeFrom
+// It returns the keys that were added
+func RotateAdditionalKeys(keys jwk.Set, activeFrom time.Time) ([]jwk.Key, error) {
+	var added []jwk.Key
+	for _, alg := range additionalKeyAlgs(keys) {
+		key, err := GenerateAdditionalKey(alg, activeFrom)
+		if err != nil {
+			return nil, err
+		}
+		err = keys.AddKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add key to the keyring: %w", err)
+		}
+		added = append(added, key)
+	}
+	return added, nil
+}
+
+// additionalKeyAlgs returns the algorithms of the additional keys in the keyring
+func additionalKeyAlgs(keys jwk.Set) []string {
+	var algs []string
+	for i := range keys.Len() {
+		key, ok := keys.Key(i)
+		if !ok || GetKeyStatus(key) != KeyStatusAdditional {
+			continue
+		}
+		alg, ok := key.Algorithm()
+		if ok && !slices.Contains(algs, alg.String()) {
+			algs = append(algs, alg.String())
+		}
+	}
+	return algs
+}
+
+// RetireKey marks a key as retired, so it's kept in the keyring until expiresAt
+func RetireKey(key jwk.Key, expiresAt time.Time) error {
+	err := key.Set(KeyStatusParam, KeyStatusRetired)
+	if err != nil {
+		return fmt.Errorf("failed to set key status: %w", err)
+	}
+	err = key.Set(KeyExpiresAtParam, expiresAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to set key expiration: %w", err)
+	}
+	return nil
+}
+
+// GetKeyStatus returns the status of a key in the keyring, or an empty string if the key has no status
+func GetKeyStatus(key jwk.Key) string {
+	var status string
+	_ = key.Get(KeyStatusParam, &status)
+	return status
+}
+
+// GetKeyExpiration returns the time after which a key is removed from the keyring
+// The returned value is the zero time if the key doesn't expire
+func GetKeyExpiration(key jwk.Key) time.Time {
+	return getTimeParam(key, KeyExpiresAtParam)
+}
+
+func getTimeParam(key jwk.Key, param string) time.Time {
+	var valueStr string
+	err := key.Get(param, &valueStr)
+	if err != nil || valueStr == "" {
+		return time.Time{}
+	}
+
+	value, err := time.Parse(time.RFC3339, valueStr)
+	if err != nil {
+		return time.Time{}
+	}
+	return value
+}
+
+// RemoveExpiredKeys returns a new set that contains only the keys in the keyring that haven't expired yet
+func RemoveExpiredKeys(keys jwk.Set, now time.Time) (jwk.Set, error) {
+	res := jwk.NewSet()
+	for i := range keys.Len() {
+		key, ok := keys.Key(i)
+		if !ok {
+			continue
+		}
+
+		expiresAt := GetKeyExpiration(key)
+		if !expiresAt.IsZero() && !now.Before(expiresAt) {
+			continue
+		}
+
+		err := res.AddKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add key to the keyring: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// PublicKeyForJWKS returns the public part of a key, without the parameters used to manage the keyring, so it can be published in a JWKS
+func PublicKeyForJWKS(key jwk.Key) (jwk.Key, error) {
+	pubKey, err := key.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key: %w", err)
+	}
+	for _, param := range keyRingParams {
+		_ = pubKey.Remove(param)
+	}
+	EnsureAlgInKey(pubKey, "", "")
+	return pubKey, nil
+}
+
+// EncodeKeySetBytes encodes a jwk.Set to a byte slice.
+func EncodeKeySetBytes(keys jwk.Set) ([]byte, error) {
+	return json.Marshal(keys)
+}
+
+// RotateKey replaces the active key stored by the provider with newKey
+// The previous key is moved to the keyring, where it remains available to verify the tokens it signed for the duration of retainFor
+// Keys in the keyring that have expired are removed, and so is newKey if it was the next key
+func RotateKey(provider KeyProvider, newKey jwk.Key, retainFor time.Duration) error {
+	now := time.Now()
+
+	keyRing, err := provider.LoadKeyRing()
+	if err != nil {
+		return fmt.Errorf("failed to load keyring: %w", err)
+	}
+	keyRing, err = RemoveExpiredKeys(keyRing, now)
+	if err != nil {
+		return err
+	}
+
+	newKeyID, _ := newKey.KeyID()
+	if existing, ok := keyRing.LookupKeyID(newKeyID); ok {
+		_ = keyRing.RemoveKey(existing)
+	}
+	_ = newKey.Remove(KeyStatusParam)
+	err = SetKeyActiveFrom(newKey, now)
+	if err != nil {
+		return err
+	}
+
+	oldKey, err := provider.LoadKey()
+	if err != nil {
+		return fmt.Errorf("failed to load current key: %w", err)
+	}
+	if oldKey != nil && retainFor > 0 {
+		err = RetireKey(oldKey, now.Add(retainFor))
+		if err != nil {
+			return err
+		}
+		err = keyRing.AddKey(oldKey)
+		if err != nil {
+			return fmt.Errorf("failed to add key to the keyring: %w", err)
+		}
+	}
+
+	// Save the keyring before the new key, so the previous key is never lost
+	err = provider.SaveKeyRing(keyRing)
+	if err != nil {
+		return fmt.Errorf("failed to save keyring: %w", err)
+	}
+	err = provider.SaveKey(newKey)
+	if err != nil {
+		return fmt.Errorf("failed to save new key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package jwk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocket-id/pocket-id/backend/internal/common"
+	testutils "github.com/pocket-id/pocket-id/backend/internal/utils/testing"
+)
+
+func TestRemoveExpiredKeys(t *testing.T) {
+	now := time.Now()
+
+	expiredKey, err := GenerateKey("ES256", "")
+	require.NoError(t, err)
+	require.NoError(t, RetireKey(expiredKey, now.Add(-time.Minute)))
+
+	retiredKey, err := GenerateKey("ES256", "")
+	require.NoError(t, err)
+	require.NoError(t, RetireKey(retiredKey, now.Add(time.Hour)))
+
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(expiredKey))
+	require.NoError(t, keys.AddKey(retiredKey))
+
+	res, err := RemoveExpiredKeys(keys, now)
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Len())
+
+	key, _ := res.Key(0)
+	kid, _ := key.KeyID()
+	expectedKid, _ := retiredKey.KeyID()
+	assert.Equal(t, expectedKid, kid)
+	assert.Equal(t, KeyStatusRetired, GetKeyStatus(key))
+	assert.WithinDuration(t, now.Add(time.Hour), GetKeyExpiration(key), time.Second)
+}
+
+func TestPublicKeyForJWKS(t *testing.T) {
+	key, err := GenerateKey("ES256", "")
+	require.NoError(t, err)
+	require.NoError(t, RetireKey(key, time.Now().Add(time.Hour)))
+
+	pubKey, err := PublicKeyForJWKS(key)
+	require.NoError(t, err)
+
+	isPrivate, err := jwk.IsPrivateKey(pubKey)
+	require.NoError(t, err)
+	assert.False(t, isPrivate)
+	assert.False(t, pubKey.Has(KeyStatusParam))
+	assert.False(t, pubKey.Has(KeyExpiresAtParam))
+
+	// The original key must not be changed
+	assert.Equal(t, KeyStatusRetired, GetKeyStatus(key))
+}
+
+func TestRotateKey(t *testing.T) {
+	providers := map[string]func(t *testing.T) KeyProvider{
+		"file": func(t *testing.T) KeyProvider {
+			provider := &KeyProviderFile{}
+			err := provider.Init(KeyProviderOpts{
+				EnvConfig: &common.EnvConfigSchema{KeysPath: t.TempDir()},
+			})
+			require.NoError(t, err)
+			return provider
+		},
+		"file with kek": func(t *testing.T) KeyProvider {
+			provider := &KeyProviderFile{}
+			err := provider.Init(KeyProviderOpts{
+				EnvConfig: &common.EnvConfigSchema{KeysPath: t.TempDir()},
+				Kek:       makeKEK(t),
+			})
+			require.NoError(t, err)
+			return provider
+		},
+		"database": func(t *testing.T) KeyProvider {
+			provider := &KeyProviderDatabase{}
+			err := provider.Init(KeyProviderOpts{
+				DB:  testutils.NewDatabaseForTest(t),
+				Kek: generateTestKEK(t),
+			})
+			require.NoError(t, err)
+			return provider
+		},
+	}
+
+	for name, newProvider := range providers {
+		t.Run(name, func(t *testing.T) {
+			provider := newProvider(t)
+
+			// No keyring at first
+			keyRing, err := provider.LoadKeyRing()
+			require.NoError(t, err)
+			assert.Equal(t, 0, keyRing.Len())
+
+			firstKey, err := GenerateKey("ES256", "")
+			require.NoError(t, err)
+			require.NoError(t, provider.SaveKey(firstKey))
+
+			// Rotate the key twice: the first key is retired and then expires, the second key stays in the keyring
+			secondKey, err := GenerateKey("ES256", "")
+			require.NoError(t, err)
+			require.NoError(t, RotateKey(provider, secondKey, time.Nanosecond))
+
+			thirdKey, err := GenerateKey("ES256", "")
+			require.NoError(t, err)
+			require.NoError(t, RotateKey(provider, thirdKey, time.Hour))
+
+			activeKey, err := provider.LoadKey()
+			require.NoError(t, err)
+			activeKid, _ := activeKey.KeyID()
+			thirdKid, _ := thirdKey.KeyID()
+			assert.Equal(t, thirdKid, activeKid)
+
+			keyRing, err = provider.LoadKeyRing()
+			require.NoError(t, err)
+			require.Equal(t, 1, keyRing.Len())
+
+			retiredKey, _ := keyRing.Key(0)
+			retiredKid, _ := retiredKey.KeyID()
+			secondKid, _ := secondKey.KeyID()
+			assert.Equal(t, secondKid, retiredKid)
+			assert.Equal(t, KeyStatusRetired, GetKeyStatus(retiredKey))
+			assert.WithinDuration(t, time.Now().Add(time.Hour), GetKeyExpiration(retiredKey), time.Minute)
+
+			isPrivate, err := jwk.IsPrivateKey(retiredKey)
+			require.NoError(t, err)
+			assert.True(t, isPrivate, "Retired keys must be stored with their private part")
+		})
+	}
+}
+
+func TestRotateAdditionalKeys(t *testing.T) {
+	now := time.Now()
+	keyRing := jwk.NewSet()
+
+	firstKey, err := GenerateAdditionalKey("ES384", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, keyRing.AddKey(firstKey))
+	otherAlgKey, err := GenerateAdditionalKey("EdDSA", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, keyRing.AddKey(otherAlgKey))
+
+	// The replacements are published, but the current keys are used until they become active
+	added, err := RotateAdditionalKeys(keyRing, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, added, 2)
+	assert.Len(t, GetAdditionalKeys(keyRing, "ES384"), 2)
+	assert.Equal(t, firstKey, CurrentAdditionalKey(GetAdditionalKeys(keyRing, "ES384"), now))
+
+	retired, err := RetireReplacedAdditionalKeys(keyRing, now, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, retired)
+
+	// Once the replacements are active, the previous keys are retired
+	later := now.Add(2 * time.Hour)
+	newKey := CurrentAdditionalKey(GetAdditionalKeys(keyRing, "ES384"), later)
+	assert.NotEqual(t, firstKey, newKey)
+
+	retired, err = RetireReplacedAdditionalKeys(keyRing, later, time.Hour)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []jwk.Key{firstKey, otherAlgKey}, retired)
+	assert.Equal(t, KeyStatusRetired, GetKeyStatus(firstKey))
+	assert.WithinDuration(t, later.Add(time.Hour), GetKeyExpiration(firstKey), time.Second)
+	assert.Equal(t, []jwk.Key{newKey}, GetAdditionalKeys(keyRing, "ES384"))
+}
+
+func TestKeyProviderFile_SaveKeyRing(t *testing.T) {
+	key, err := GenerateKey("ES256", "")
+	require.NoError(t, err)
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(key))
+
+	t.Run("encrypts the keyring and removes the un-encrypted one", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		// Save the keyring without encryption first
+		provider := &KeyProviderFile{}
+		require.NoError(t, provider.Init(KeyProviderOpts{
+			EnvConfig: &common.EnvConfigSchema{KeysPath: tempDir},
+		}))
+		require.NoError(t, provider.SaveKeyRing(keys))
+		require.FileExists(t, filepath.Join(tempDir, KeyRingFile))
+
+		// Load it with a kek, which falls back to the un-encrypted file
+		provider = &KeyProviderFile{}
+		require.NoError(t, provider.Init(KeyProviderOpts{
+			EnvConfig: &common.EnvConfigSchema{KeysPath: tempDir},
+			Kek:       makeKEK(t),
+		}))
+		loaded, err := provider.LoadKeyRing()
+		require.NoError(t, err)
+		require.Equal(t, 1, loaded.Len())
+
+		// Saving with the kek replaces the un-encrypted file
+		require.NoError(t, provider.SaveKeyRing(loaded))
+		require.NoFileExists(t, filepath.Join(tempDir, KeyRingFile))
+
+		data, err := os.ReadFile(filepath.Join(tempDir, KeyRingFileEncrypted))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "keys")
+
+		loaded, err = provider.LoadKeyRing()
+		require.NoError(t, err)
+		require.Equal(t, 1, loaded.Len())
+	})
+}
@@ -0,0 +1,11 @@
+ALTER TABLE oidc_clients ADD COLUMN requires_pushed_authorization_requests BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE oidc_pushed_authorization_requests
+(
+    id          UUID        NOT NULL PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL,
+    request_uri TEXT        NOT NULL UNIQUE,
+    parameters  JSONB       NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    client_id   TEXT        NOT NULL REFERENCES oidc_clients ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE oidc_clients DROP COLUMN registration_access_token;
+DROP TABLE oidc_initial_access_tokens;
@@ -0,0 +1,13 @@
+CREATE TABLE oidc_initial_access_tokens (
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    token VARCHAR(255) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    usage_limit INTEGER NOT NULL DEFAULT 1,
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    created_by_id UUID REFERENCES users ON DELETE SET NULL
+);
+
+CREATE INDEX idx_oidc_initial_access_tokens_expires_at ON oidc_initial_access_tokens(expires_at);
+
+ALTER TABLE oidc_clients ADD COLUMN registration_access_token TEXT NULL;
@@ -0,0 +1 @@
+ALTER TABLE oidc_clients ADD COLUMN backchannel_logout_url TEXT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE oidc_clients DROP COLUMN frontchannel_logout_session_required;
+ALTER TABLE oidc_clients DROP COLUMN frontchannel_logout_url;
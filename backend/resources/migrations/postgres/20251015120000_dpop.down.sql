@@ -0,0 +1,3 @@
+DROP TABLE oidc_dpop_proofs;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN dpop_jkt;
+ALTER TABLE oidc_clients DROP COLUMN requires_dpop;
@@ -0,0 +1,11 @@
+ALTER TABLE oidc_clients ADD COLUMN requires_dpop BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE oidc_refresh_tokens ADD COLUMN dpop_jkt TEXT NULL;
+
+CREATE TABLE oidc_dpop_proofs (
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    jwt_id_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_oidc_dpop_proofs_expires_at ON oidc_dpop_proofs(expires_at);
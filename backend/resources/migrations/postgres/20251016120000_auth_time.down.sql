@@ -0,0 +1,2 @@
+ALTER TABLE oidc_refresh_tokens DROP COLUMN auth_time;
+ALTER TABLE oidc_authorization_codes DROP COLUMN auth_time;
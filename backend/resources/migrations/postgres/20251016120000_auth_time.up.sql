@@ -0,0 +1,2 @@
+ALTER TABLE oidc_authorization_codes ADD COLUMN auth_time TIMESTAMPTZ NULL;
+ALTER TABLE oidc_refresh_tokens ADD COLUMN auth_time TIMESTAMPTZ NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE oidc_refresh_tokens DROP COLUMN authentication_methods;
+ALTER TABLE oidc_authorization_codes DROP COLUMN authentication_methods;
+ALTER TABLE oidc_clients DROP COLUMN minimum_acr;
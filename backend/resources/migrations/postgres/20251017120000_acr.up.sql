@@ -0,0 +1,3 @@
+ALTER TABLE oidc_clients ADD COLUMN minimum_acr TEXT NULL;
+ALTER TABLE oidc_authorization_codes ADD COLUMN authentication_methods TEXT NOT NULL DEFAULT '';
+ALTER TABLE oidc_refresh_tokens ADD COLUMN authentication_methods TEXT NOT NULL DEFAULT '';
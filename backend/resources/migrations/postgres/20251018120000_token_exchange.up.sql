@@ -0,0 +1 @@
+ALTER TABLE oidc_clients ADD COLUMN token_exchange_audiences JSONB;
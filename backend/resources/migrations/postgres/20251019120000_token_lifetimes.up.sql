@@ -0,0 +1,5 @@
+ALTER TABLE oidc_clients ADD COLUMN access_token_lifetime INTEGER NULL;
+ALTER TABLE oidc_clients ADD COLUMN id_token_lifetime INTEGER NULL;
+ALTER TABLE oidc_clients ADD COLUMN refresh_token_idle_lifetime INTEGER NULL;
+ALTER TABLE oidc_clients ADD COLUMN refresh_token_absolute_lifetime INTEGER NULL;
+ALTER TABLE oidc_refresh_tokens ADD COLUMN absolute_expires_at TIMESTAMPTZ NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_oidc_refresh_tokens_family_id;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN rotated_at;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN family_id;
@@ -0,0 +1,4 @@
+ALTER TABLE oidc_refresh_tokens ADD COLUMN family_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE oidc_refresh_tokens ADD COLUMN rotated_at TIMESTAMPTZ NULL;
+UPDATE oidc_refresh_tokens SET family_id = id;
+CREATE INDEX idx_oidc_refresh_tokens_family_id ON oidc_refresh_tokens(family_id);
@@ -0,0 +1 @@
+ALTER TABLE oidc_clients ADD COLUMN refresh_tokens_disabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+ALTER TABLE user_authorized_oidc_clients DROP COLUMN claims;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN claims;
+ALTER TABLE oidc_authorization_codes DROP COLUMN claims;
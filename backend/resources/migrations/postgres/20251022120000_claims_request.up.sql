@@ -0,0 +1,3 @@
+ALTER TABLE oidc_authorization_codes ADD COLUMN claims JSONB NULL;
+ALTER TABLE oidc_refresh_tokens ADD COLUMN claims JSONB NULL;
+ALTER TABLE user_authorized_oidc_clients ADD COLUMN claims JSONB NULL;
@@ -0,0 +1,2 @@
+DROP TABLE oidc_scopes_allowed_clients;
+DROP TABLE oidc_scopes;
@@ -0,0 +1,15 @@
+CREATE TABLE oidc_scopes
+(
+    id          UUID        NOT NULL PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL,
+    name        TEXT        NOT NULL UNIQUE,
+    description TEXT        NOT NULL DEFAULT '',
+    claims      JSONB       NOT NULL
+);
+
+CREATE TABLE oidc_scopes_allowed_clients
+(
+    oidc_scope_id  UUID NOT NULL REFERENCES oidc_scopes ON DELETE CASCADE,
+    oidc_client_id TEXT NOT NULL REFERENCES oidc_clients ON DELETE CASCADE,
+    PRIMARY KEY (oidc_scope_id, oidc_client_id)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE oidc_clients ADD COLUMN jwks TEXT;
+ALTER TABLE oidc_clients ADD COLUMN id_token_encrypted_response_alg TEXT;
+ALTER TABLE oidc_clients ADD COLUMN id_token_encrypted_response_enc TEXT;
+ALTER TABLE oidc_clients ADD COLUMN userinfo_encrypted_response_alg TEXT;
+ALTER TABLE oidc_clients ADD COLUMN userinfo_encrypted_response_enc TEXT;
@@ -0,0 +1,17 @@
+CREATE TABLE oidc_backchannel_authentication_requests
+(
+    id                     UUID        NOT NULL PRIMARY KEY,
+    created_at             TIMESTAMPTZ NOT NULL,
+    auth_req_id            TEXT        NOT NULL UNIQUE,
+    scope                  TEXT        NOT NULL,
+    binding_message        TEXT        NOT NULL DEFAULT '',
+    expires_at             TIMESTAMPTZ NOT NULL,
+    is_authorized          BOOLEAN     NOT NULL DEFAULT FALSE,
+    is_denied              BOOLEAN     NOT NULL DEFAULT FALSE,
+    auth_time              TIMESTAMPTZ,
+    authentication_methods TEXT        NOT NULL DEFAULT '',
+    user_id                UUID        NOT NULL REFERENCES users ON DELETE CASCADE,
+    client_id              TEXT        NOT NULL REFERENCES oidc_clients ON DELETE CASCADE
+);
+
+CREATE INDEX idx_oidc_backchannel_authentication_requests_user_id ON oidc_backchannel_authentication_requests(user_id);
@@ -0,0 +1,8 @@
+CREATE TABLE oidc_used_assertions (
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    jwt_id_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_oidc_used_assertions_expires_at ON oidc_used_assertions(expires_at);
@@ -0,0 +1,6 @@
+CREATE TABLE oidc_pairwise_subjects (
+    id UUID NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    subject VARCHAR(64) NOT NULL UNIQUE,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE oidc_clients DROP COLUMN backchannel_authentication_enabled;
@@ -0,0 +1 @@
+ALTER TABLE oidc_clients ADD COLUMN backchannel_authentication_enabled BOOLEAN NOT NULL DEFAULT FALSE;
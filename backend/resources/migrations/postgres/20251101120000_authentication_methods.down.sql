@@ -0,0 +1,3 @@
+ALTER TABLE reauthentication_tokens DROP COLUMN authentication_methods;
+ALTER TABLE oidc_device_codes DROP COLUMN authentication_methods;
+ALTER TABLE oidc_device_codes DROP COLUMN auth_time;
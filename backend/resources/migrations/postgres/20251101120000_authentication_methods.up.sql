@@ -0,0 +1,3 @@
+ALTER TABLE oidc_device_codes ADD COLUMN auth_time TIMESTAMPTZ NULL;
+ALTER TABLE oidc_device_codes ADD COLUMN authentication_methods TEXT NOT NULL DEFAULT '';
+ALTER TABLE reauthentication_tokens ADD COLUMN authentication_methods TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE oidc_clients DROP COLUMN refresh_tokens_without_offline_access;
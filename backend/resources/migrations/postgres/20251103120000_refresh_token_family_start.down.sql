@@ -0,0 +1 @@
+-- No-op because the backfilled absolute expiration is still valid
@@ -0,0 +1,15 @@
+-- Refresh tokens issued before the absolute lifetime was configured don't have an absolute expiration, so it's computed from the start of their family
+UPDATE oidc_refresh_tokens
+SET absolute_expires_at = (
+    SELECT MIN(f.created_at)
+    FROM oidc_refresh_tokens f
+    WHERE f.family_id = oidc_refresh_tokens.family_id
+) + (
+    SELECT c.refresh_token_absolute_lifetime
+    FROM oidc_clients c
+    WHERE c.id = oidc_refresh_tokens.client_id
+) * INTERVAL '1 second'
+WHERE absolute_expires_at IS NULL
+  AND client_id IN (
+    SELECT id FROM oidc_clients WHERE refresh_token_absolute_lifetime > 0
+  );
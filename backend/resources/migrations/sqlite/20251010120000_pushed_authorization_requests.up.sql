@@ -0,0 +1,15 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN requires_pushed_authorization_requests BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE oidc_pushed_authorization_requests
+(
+    id          TEXT     NOT NULL PRIMARY KEY,
+    created_at  DATETIME NOT NULL,
+    request_uri TEXT     NOT NULL UNIQUE,
+    parameters  TEXT     NOT NULL,
+    expires_at  DATETIME NOT NULL,
+    client_id   TEXT     NOT NULL REFERENCES oidc_clients ON DELETE CASCADE
+);
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN requires_signed_request_object;
+ALTER TABLE oidc_clients DROP COLUMN jwks_url;
+COMMIT;
+PRAGMA foreign_keys=ON;
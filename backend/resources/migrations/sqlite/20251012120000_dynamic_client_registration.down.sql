@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN registration_access_token;
+DROP TABLE oidc_initial_access_tokens;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,17 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+CREATE TABLE oidc_initial_access_tokens (
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at DATETIME NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    usage_limit INTEGER NOT NULL DEFAULT 1,
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    created_by_id TEXT REFERENCES users ON DELETE SET NULL
+);
+
+CREATE INDEX idx_oidc_initial_access_tokens_expires_at ON oidc_initial_access_tokens(expires_at);
+
+ALTER TABLE oidc_clients ADD COLUMN registration_access_token TEXT NULL;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN backchannel_logout_url;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN backchannel_logout_url TEXT NULL;
+COMMIT;
+PRAGMA foreign_keys=ON;
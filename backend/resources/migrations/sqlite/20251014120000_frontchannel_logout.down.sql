@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN frontchannel_logout_session_required;
+ALTER TABLE oidc_clients DROP COLUMN frontchannel_logout_url;
+COMMIT;
+PRAGMA foreign_keys=ON;
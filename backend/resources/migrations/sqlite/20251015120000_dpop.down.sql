@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+DROP TABLE oidc_dpop_proofs;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN dpop_jkt;
+ALTER TABLE oidc_clients DROP COLUMN requires_dpop;
+COMMIT;
+PRAGMA foreign_keys=ON;
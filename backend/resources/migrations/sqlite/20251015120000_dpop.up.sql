@@ -0,0 +1,15 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN requires_dpop BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE oidc_refresh_tokens ADD COLUMN dpop_jkt TEXT NULL;
+
+CREATE TABLE oidc_dpop_proofs (
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at DATETIME NOT NULL,
+    jwt_id_hash TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_oidc_dpop_proofs_expires_at ON oidc_dpop_proofs(expires_at);
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN auth_time;
+ALTER TABLE oidc_authorization_codes DROP COLUMN auth_time;
+COMMIT;
+PRAGMA foreign_keys=ON;
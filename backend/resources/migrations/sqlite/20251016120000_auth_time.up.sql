@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_authorization_codes ADD COLUMN auth_time DATETIME NULL;
+ALTER TABLE oidc_refresh_tokens ADD COLUMN auth_time DATETIME NULL;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN authentication_methods;
+ALTER TABLE oidc_authorization_codes DROP COLUMN authentication_methods;
+ALTER TABLE oidc_clients DROP COLUMN minimum_acr;
+COMMIT;
+PRAGMA foreign_keys=ON;
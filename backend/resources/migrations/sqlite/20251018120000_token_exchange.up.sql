@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN token_exchange_audiences BLOB;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,9 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN absolute_expires_at;
+ALTER TABLE oidc_clients DROP COLUMN refresh_token_absolute_lifetime;
+ALTER TABLE oidc_clients DROP COLUMN refresh_token_idle_lifetime;
+ALTER TABLE oidc_clients DROP COLUMN id_token_lifetime;
+ALTER TABLE oidc_clients DROP COLUMN access_token_lifetime;
+COMMIT;
+PRAGMA foreign_keys=ON;
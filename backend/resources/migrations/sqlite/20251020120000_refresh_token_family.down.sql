@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+DROP INDEX IF EXISTS idx_oidc_refresh_tokens_family_id;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN rotated_at;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN family_id;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN refresh_tokens_disabled;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE user_authorized_oidc_clients DROP COLUMN claims;
+ALTER TABLE oidc_refresh_tokens DROP COLUMN claims;
+ALTER TABLE oidc_authorization_codes DROP COLUMN claims;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_authorization_codes ADD COLUMN claims BLOB NULL;
+ALTER TABLE oidc_refresh_tokens ADD COLUMN claims BLOB NULL;
+ALTER TABLE user_authorized_oidc_clients ADD COLUMN claims BLOB NULL;
+COMMIT;
+PRAGMA foreign_keys=ON;
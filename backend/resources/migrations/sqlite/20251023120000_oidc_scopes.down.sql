@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+DROP TABLE oidc_scopes_allowed_clients;
+DROP TABLE oidc_scopes;
+COMMIT;
+PRAGMA foreign_keys=ON;
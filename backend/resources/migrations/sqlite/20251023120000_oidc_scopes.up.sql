@@ -0,0 +1,21 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+CREATE TABLE oidc_scopes
+(
+    id          TEXT     NOT NULL PRIMARY KEY,
+    created_at  DATETIME NOT NULL,
+    name        TEXT     NOT NULL UNIQUE,
+    description TEXT     NOT NULL DEFAULT '',
+    claims      BLOB     NOT NULL
+);
+
+CREATE TABLE oidc_scopes_allowed_clients
+(
+    oidc_scope_id  TEXT NOT NULL,
+    oidc_client_id TEXT NOT NULL,
+    PRIMARY KEY (oidc_scope_id, oidc_client_id),
+    FOREIGN KEY (oidc_scope_id) REFERENCES oidc_scopes (id) ON DELETE CASCADE,
+    FOREIGN KEY (oidc_client_id) REFERENCES oidc_clients (id) ON DELETE CASCADE
+);
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN sector_identifier_uri;
+ALTER TABLE oidc_clients DROP COLUMN subject_type;
+COMMIT;
+PRAGMA foreign_keys=ON;
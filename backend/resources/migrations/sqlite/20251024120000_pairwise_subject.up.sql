@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN subject_type TEXT NOT NULL DEFAULT 'public';
+ALTER TABLE oidc_clients ADD COLUMN sector_identifier_uri TEXT;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,9 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN jwks TEXT;
+ALTER TABLE oidc_clients ADD COLUMN id_token_encrypted_response_alg TEXT;
+ALTER TABLE oidc_clients ADD COLUMN id_token_encrypted_response_enc TEXT;
+ALTER TABLE oidc_clients ADD COLUMN userinfo_encrypted_response_alg TEXT;
+ALTER TABLE oidc_clients ADD COLUMN userinfo_encrypted_response_enc TEXT;
+COMMIT;
+PRAGMA foreign_keys=ON;
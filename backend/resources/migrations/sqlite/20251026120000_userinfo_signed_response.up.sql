@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN userinfo_signed_response_alg TEXT;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN id_token_signed_response_alg;
+COMMIT;
+PRAGMA foreign_keys=ON;
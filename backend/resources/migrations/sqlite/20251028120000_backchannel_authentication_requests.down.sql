@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+DROP TABLE IF EXISTS oidc_backchannel_authentication_requests;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,21 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+CREATE TABLE oidc_backchannel_authentication_requests
+(
+    id                     TEXT     NOT NULL PRIMARY KEY,
+    created_at             DATETIME NOT NULL,
+    auth_req_id            TEXT     NOT NULL UNIQUE,
+    scope                  TEXT     NOT NULL,
+    binding_message        TEXT     NOT NULL DEFAULT '',
+    expires_at             DATETIME NOT NULL,
+    is_authorized          BOOLEAN  NOT NULL DEFAULT FALSE,
+    is_denied              BOOLEAN  NOT NULL DEFAULT FALSE,
+    auth_time              DATETIME,
+    authentication_methods TEXT     NOT NULL DEFAULT '',
+    user_id                TEXT     NOT NULL REFERENCES users ON DELETE CASCADE,
+    client_id              TEXT     NOT NULL REFERENCES oidc_clients ON DELETE CASCADE
+);
+
+CREATE INDEX idx_oidc_backchannel_authentication_requests_user_id ON oidc_backchannel_authentication_requests(user_id);
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+DROP TABLE oidc_used_assertions;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,12 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+CREATE TABLE oidc_used_assertions (
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at DATETIME NOT NULL,
+    jwt_id_hash TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_oidc_used_assertions_expires_at ON oidc_used_assertions(expires_at);
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+DROP TABLE oidc_pairwise_subjects;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients DROP COLUMN backchannel_authentication_enabled;
+COMMIT;
+PRAGMA foreign_keys=ON;
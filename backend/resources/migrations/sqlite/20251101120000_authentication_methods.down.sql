@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE reauthentication_tokens DROP COLUMN authentication_methods;
+ALTER TABLE oidc_device_codes DROP COLUMN authentication_methods;
+ALTER TABLE oidc_device_codes DROP COLUMN auth_time;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_device_codes ADD COLUMN auth_time DATETIME NULL;
+ALTER TABLE oidc_device_codes ADD COLUMN authentication_methods TEXT NOT NULL DEFAULT '';
+ALTER TABLE reauthentication_tokens ADD COLUMN authentication_methods TEXT NOT NULL DEFAULT '';
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+ALTER TABLE oidc_clients ADD COLUMN refresh_tokens_without_offline_access BOOLEAN NOT NULL DEFAULT FALSE;
+-- Existing clients keep receiving refresh tokens without requesting the offline_access scope
+UPDATE oidc_clients SET refresh_tokens_without_offline_access = TRUE;
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -0,0 +1 @@
+-- No-op because the removed initial access tokens can't be restored
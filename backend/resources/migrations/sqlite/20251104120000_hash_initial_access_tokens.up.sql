@@ -0,0 +1,6 @@
+PRAGMA foreign_keys=OFF;
+BEGIN;
+-- Initial access tokens are now stored hashed; the existing ones are short-lived, so they are removed instead of being hashed
+DELETE FROM oidc_initial_access_tokens;
+COMMIT;
+PRAGMA foreign_keys=ON;
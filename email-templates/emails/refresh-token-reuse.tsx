@@ -0,0 +1,54 @@
+import { Text } from "@react-email/components";
+import { BaseTemplate } from "../components/base-template";
+import CardHeader from "../components/card-header";
+import { sharedPreviewProps, sharedTemplateProps } from "../props";
+
+interface RefreshTokenReuseData {
+  clientName: string;
+  dateTime: string;
+}
+
+interface RefreshTokenReuseEmailProps {
+  logoURL: string;
+  appName: string;
+  data: RefreshTokenReuseData;
+}
+
+export const RefreshTokenReuseEmail = ({
+  logoURL,
+  appName,
+  data,
+}: RefreshTokenReuseEmailProps) => (
+  <BaseTemplate logoURL={logoURL} appName={appName}>
+    <CardHeader title="Session Revoked" warning />
+    <Text>
+      On <strong>{data.dateTime}</strong>, a token that was already used to
+      refresh your session with <strong>{data.clientName}</strong> was
+      presented again. This can mean that the token was stolen, so all sessions
+      of {data.clientName} that were derived from it have been signed out.
+    </Text>
+
+    <Text>
+      If you don't recognize this activity, we recommend that you review the
+      applications that have access to your {appName} account.
+    </Text>
+  </BaseTemplate>
+);
+
+export default RefreshTokenReuseEmail;
+
+RefreshTokenReuseEmail.TemplateProps = {
+  ...sharedTemplateProps,
+  data: {
+    clientName: "{{.Data.ClientName}}",
+    dateTime: '{{.Data.DateTime.Format "January 2, 2006 at 3:04 PM MST"}}',
+  },
+};
+
+RefreshTokenReuseEmail.PreviewProps = {
+  ...sharedPreviewProps,
+  data: {
+    clientName: "Nextcloud",
+    dateTime: "2024-01-01 12:00 PM UTC",
+  },
+};
@@ -1,6 +1,10 @@
 import type {
 	AccessibleOidcClient,
+	AuthorizationRequiredRequest,
+	AuthorizationRequiredResponse,
+	AuthorizeRequest,
 	AuthorizeResponse,
+	BackchannelAuthenticationRequest,
 	OidcClient,
 	OidcClientCreate,
 	OidcClientMetaData,
@@ -14,35 +18,16 @@ import { cachedOidcClientLogo } from '$lib/utils/cached-image-util';
 import APIService from './api-service';
 
 class OidcService extends APIService {
-	async authorize(
-		clientId: string,
-		scope: string,
-		callbackURL: string,
-		nonce?: string,
-		codeChallenge?: string,
-		codeChallengeMethod?: string,
-		reauthenticationToken?: string
-	) {
-		const res = await this.api.post('/oidc/authorize', {
-			scope,
-			nonce,
-			callbackURL,
-			clientId,
-			codeChallenge,
-			codeChallengeMethod,
-			reauthenticationToken
-		});
+	async authorize(request: AuthorizeRequest) {
+		const res = await this.api.post('/oidc/authorize', request);
 
 		return res.data as AuthorizeResponse;
 	}
 
-	async isAuthorizationRequired(clientId: string, scope: string) {
-		const res = await this.api.post('/oidc/authorization-required', {
-			scope,
-			clientId
-		});
+	async isAuthorizationRequired(request: AuthorizationRequiredRequest) {
+		const res = await this.api.post('/oidc/authorization-required', request);
 
-		return res.data.authorizationRequired as boolean;
+		return res.data as AuthorizationRequiredResponse;
 	}
 
 	async listClients(options?: SearchPaginationSortRequest) {
@@ -129,6 +114,21 @@ class OidcService extends APIService {
 	async revokeOwnAuthorizedClient(clientId: string) {
 		await this.api.delete(`/oidc/users/me/authorized-clients/${clientId}`);
 	}
+
+	async listPendingBackchannelAuthentications() {
+		const res = await this.api.get('/oidc/backchannel-authentications');
+		return res.data as BackchannelAuthenticationRequest[];
+	}
+
+	async approveBackchannelAuthentication(id: string, reauthenticationToken?: string) {
+		await this.api.post(`/oidc/backchannel-authentications/${id}/approve`, {
+			reauthenticationToken
+		});
+	}
+
+	async denyBackchannelAuthentication(id: string) {
+		await this.api.post(`/oidc/backchannel-authentications/${id}/deny`);
+	}
 }
 
 export default OidcService;
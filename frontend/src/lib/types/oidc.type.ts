@@ -25,6 +25,8 @@ export type OidcClient = OidcClientMetaData & {
 	isPublic: boolean;
 	pkceEnabled: boolean;
 	requiresReauthentication: boolean;
+	refreshTokensWithoutOfflineAccess: boolean;
+	backchannelAuthenticationEnabled: boolean;
 	credentials?: OidcClientCredentials;
 	launchURL?: string;
 };
@@ -56,10 +58,56 @@ export type OidcDeviceCodeInfo = {
 	client: OidcClientMetaData;
 };
 
+export type AuthorizeRequest = {
+	clientId: string;
+	scope?: string;
+	callbackURL?: string;
+	nonce?: string;
+	state?: string;
+	codeChallenge?: string;
+	codeChallengeMethod?: string;
+	claims?: string;
+	responseMode?: string;
+	prompt?: string;
+	maxAge?: number;
+	acrValues?: string;
+	requestUri?: string;
+	request?: string;
+	reauthenticationToken?: string;
+};
+
+export type AuthorizationRequiredRequest = {
+	clientId: string;
+	scope?: string;
+	claims?: string;
+	prompt?: string;
+	requestUri?: string;
+	request?: string;
+};
+
+export type AuthorizationRequiredResponse = {
+	authorizationRequired: boolean;
+	scope: string;
+	claims?: string;
+	prompt?: string;
+};
+
 export type AuthorizeResponse = {
-	code: string;
+	code?: string;
 	callbackURL: string;
 	issuer: string;
+	state?: string;
+	error?: string;
+	responseMode: string;
+	response?: string;
+};
+
+export type BackchannelAuthenticationRequest = {
+	id: string;
+	client: OidcClientMetaData;
+	scope: string;
+	bindingMessage: string;
+	expiresAt: string;
 };
 
 export type AccessibleOidcClient = OidcClientMetaData & {
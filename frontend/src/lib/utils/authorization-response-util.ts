@@ -0,0 +1,54 @@
+import type { AuthorizeResponse } from '$lib/types/oidc.type';
+
+// Returns true if the response is posted to the client with an auto-submitted form
+export function isFormPostResponseMode(responseMode?: string | null) {
+	return responseMode === 'form_post' || responseMode === 'form_post.jwt';
+}
+
+// Returns the parameters of the authorization response
+// With the JWT response modes, they are all contained in the signed "response" parameter
+function getResponseParams(response: AuthorizeResponse) {
+	if (response.response) {
+		return { response: response.response };
+	}
+
+	const params: Record<string, string> = {};
+	if (response.code) params.code = response.code;
+	if (response.error) params.error = response.error;
+	if (response.state) params.state = response.state;
+	params.iss = response.issuer;
+	return params;
+}
+
+// Returns the authorization response to the client's callback URL, using the response mode of the request
+export function sendAuthorizationResponse(response: AuthorizeResponse, clientId: string) {
+	const params = getResponseParams(response);
+
+	if (isFormPostResponseMode(response.responseMode)) {
+		// The backend renders the page that posts the response, so that only the callback URL's origin is allowed as form target
+		const form = document.createElement('form');
+		form.method = 'POST';
+		form.action = '/api/oidc/authorization-response';
+		const formParams = { ...params, client_id: clientId, redirect_uri: response.callbackURL };
+		for (const [name, value] of Object.entries(formParams)) {
+			const input = document.createElement('input');
+			input.type = 'hidden';
+			input.name = name;
+			input.value = value;
+			form.appendChild(input);
+		}
+		document.body.appendChild(form);
+		form.submit();
+		return;
+	}
+
+	const redirectURL = new URL(response.callbackURL);
+	if (response.responseMode === 'fragment') {
+		redirectURL.hash = new URLSearchParams(params).toString();
+	} else {
+		for (const [name, value] of Object.entries(params)) {
+			redirectURL.searchParams.append(name, value);
+		}
+	}
+	window.location.href = redirectURL.toString();
+}
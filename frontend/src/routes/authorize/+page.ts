@@ -8,10 +8,17 @@ export const load: PageLoad = async ({ url }) => {
 	const client = await oidcService.getClientMetaData(clientId!);
 
 	return {
-		scope: url.searchParams.get('scope')!,
+		scope: url.searchParams.get('scope') || undefined,
 		nonce: url.searchParams.get('nonce') || undefined,
-		authorizeState: url.searchParams.get('state')!,
-		callbackURL: url.searchParams.get('redirect_uri')!,
+		claims: url.searchParams.get('claims') || undefined,
+		responseMode: url.searchParams.get('response_mode') || undefined,
+		authorizeState: url.searchParams.get('state') || undefined,
+		callbackURL: url.searchParams.get('redirect_uri') || undefined,
+		requestUri: url.searchParams.get('request_uri') || undefined,
+		request: url.searchParams.get('request') || undefined,
+		prompt: url.searchParams.get('prompt') || undefined,
+		maxAge: url.searchParams.has('max_age') ? Number(url.searchParams.get('max_age')) : undefined,
+		acrValues: url.searchParams.get('acr_values') || undefined,
 		client,
 		codeChallenge: url.searchParams.get('code_challenge')!,
 		codeChallengeMethod: url.searchParams.get('code_challenge_method')!
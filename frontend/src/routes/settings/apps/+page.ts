@@ -16,7 +16,10 @@ export const load: PageLoad = async () => {
 		}
 	};
 
-	const clients = await oidcService.listOwnAccessibleClients(appRequestOptions);
+	const [clients, backchannelAuthenticationRequests] = await Promise.all([
+		oidcService.listOwnAccessibleClients(appRequestOptions),
+		oidcService.listPendingBackchannelAuthentications()
+	]);
 
-	return { clients, appRequestOptions };
+	return { clients, backchannelAuthenticationRequests, appRequestOptions };
 };